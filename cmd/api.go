@@ -7,17 +7,17 @@ import (
 	"os/signal"
 	"syscall"
 
-	"github.com/joho/godotenv"
 	"github.com/psds-microservice/api-gateway/internal/application"
 	"github.com/psds-microservice/api-gateway/internal/config"
+	"github.com/psds-microservice/api-gateway/internal/logging"
 	"github.com/spf13/cobra"
-	"go.uber.org/zap"
 )
 
 var (
-	apiDebug    bool
-	apiConfig   string
-	apiGrpcPort string
+	apiDebug      bool
+	apiConfig     string
+	apiGrpcPort   string
+	apiSinglePort bool
 )
 
 var apiCmd = &cobra.Command{
@@ -27,32 +27,34 @@ var apiCmd = &cobra.Command{
 }
 
 func init() {
-	apiCmd.Flags().BoolVar(&apiDebug, "debug", false, "Debug logging")
+	apiCmd.Flags().BoolVar(&apiDebug, "debug", false, "Debug logging (LOG_LEVEL=debug, LOG_FORMAT=console)")
 	apiCmd.Flags().StringVar(&apiConfig, "config", "", "Config path (ignored, config from .env only)")
 	apiCmd.Flags().StringVar(&apiGrpcPort, "grpc-port", "9090", "gRPC port")
+	apiCmd.Flags().BoolVar(&apiSinglePort, "single-port", false, "Serve gRPC on the HTTP port (SINGLE_PORT_ENABLED=true)")
 }
 
 func runAPI(cmd *cobra.Command, args []string) error {
-	_ = godotenv.Load()
+	_ = config.LoadDotEnv(".env")
 
-	var logger *zap.Logger
-	var err error
+	cfg := config.Load()
+	if apiGrpcPort != "" {
+		cfg.GRPCPort = apiGrpcPort
+	}
+	if apiSinglePort {
+		cfg.SinglePort = true
+	}
 	if apiDebug {
-		logger, err = zap.NewDevelopment()
-	} else {
-		logger, err = zap.NewProduction()
+		cfg.Logging.Level = "debug"
+		cfg.Logging.Format = config.LogFormatConsole
 	}
+
+	logger, levels, err := logging.New(cfg)
 	if err != nil {
 		return fmt.Errorf("logger: %w", err)
 	}
 	defer logger.Sync()
 
-	cfg := config.Load()
-	if apiGrpcPort != "" {
-		cfg.GRPCPort = apiGrpcPort
-	}
-
-	app, err := application.NewAPI(cfg, logger)
+	app, err := application.NewAPI(cfg, logger, levels)
 	if err != nil {
 		return fmt.Errorf("application: %w", err)
 	}
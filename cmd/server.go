@@ -7,17 +7,17 @@ import (
 	"os/signal"
 	"syscall"
 
-	"github.com/joho/godotenv"
 	"github.com/psds-microservice/api-gateway/internal/application"
 	"github.com/psds-microservice/api-gateway/internal/config"
+	"github.com/psds-microservice/api-gateway/internal/logging"
 	"github.com/spf13/cobra"
-	"go.uber.org/zap"
 )
 
 var (
-	serverDebug    bool
-	serverConfig   string
-	serverGrpcPort string
+	serverDebug      bool
+	serverConfig     string
+	serverGrpcPort   string
+	serverSinglePort bool
 )
 
 var serverCmd = &cobra.Command{
@@ -27,32 +27,34 @@ var serverCmd = &cobra.Command{
 }
 
 func init() {
-	serverCmd.Flags().BoolVar(&serverDebug, "debug", false, "Debug logging")
+	serverCmd.Flags().BoolVar(&serverDebug, "debug", false, "Debug logging (LOG_LEVEL=debug, LOG_FORMAT=console)")
 	serverCmd.Flags().StringVar(&serverConfig, "config", "", "Config path (ignored, config from .env only)")
 	serverCmd.Flags().StringVar(&serverGrpcPort, "grpc-port", "9090", "gRPC port")
+	serverCmd.Flags().BoolVar(&serverSinglePort, "single-port", false, "Serve gRPC on the HTTP port (SINGLE_PORT_ENABLED=true)")
 }
 
 func runServer(cmd *cobra.Command, args []string) error {
-	_ = godotenv.Load()
+	_ = config.LoadDotEnv(".env")
 
-	var logger *zap.Logger
-	var err error
+	cfg := config.Load()
+	if serverGrpcPort != "" {
+		cfg.GRPCPort = serverGrpcPort
+	}
+	if serverSinglePort {
+		cfg.SinglePort = true
+	}
 	if serverDebug {
-		logger, err = zap.NewDevelopment()
-	} else {
-		logger, err = zap.NewProduction()
+		cfg.Logging.Level = "debug"
+		cfg.Logging.Format = config.LogFormatConsole
 	}
+
+	logger, levels, err := logging.New(cfg)
 	if err != nil {
 		return fmt.Errorf("logger: %w", err)
 	}
 	defer logger.Sync()
 
-	cfg := config.Load()
-	if serverGrpcPort != "" {
-		cfg.GRPCPort = serverGrpcPort
-	}
-
-	app, err := application.NewAPI(cfg, logger)
+	app, err := application.NewAPI(cfg, logger, levels)
 	if err != nil {
 		return fmt.Errorf("application: %w", err)
 	}
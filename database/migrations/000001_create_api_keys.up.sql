@@ -0,0 +1,15 @@
+-- API-ключи устройств и машинных клиентов. Хранится только SHA-256 ключа.
+CREATE TABLE IF NOT EXISTS api_keys (
+    id           VARCHAR(32)  PRIMARY KEY,
+    key_hash     BYTEA        NOT NULL,
+    client_id    VARCHAR(255) NOT NULL,
+    name         VARCHAR(255) NOT NULL DEFAULT '',
+    scopes       TEXT[]       NOT NULL DEFAULT '{}',
+    created_at   TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    expires_at   TIMESTAMPTZ,
+    revoked_at   TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    rotated_from VARCHAR(32)  REFERENCES api_keys (id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_client_id ON api_keys (client_id);
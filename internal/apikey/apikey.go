@@ -0,0 +1,76 @@
+// Package apikey — API-ключи устройств и машинных клиентов: выдача, ротация, отзыв и проверка.
+// Ключ привязан к client_id и scopes; в хранилище лежит только его SHA-256.
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Ошибки API-ключей.
+var (
+	// ErrInvalidKey — ключ неизвестен, отозван, истёк или не совпал (транспорт отвечает 401).
+	ErrInvalidKey = errors.New("invalid api key")
+	// ErrNotFound — записи ключа с таким id нет.
+	ErrNotFound = errors.New("api key not found")
+	// ErrInactive — ключ уже отозван или истёк (ротировать нечего).
+	ErrInactive = errors.New("api key is revoked or expired")
+)
+
+// prefix — префикс ключа, по которому его легко узнать в логах и сканерах секретов.
+const prefix = "psk_"
+
+// Key — запись API-ключа (без секрета).
+type Key struct {
+	ID          string     `json:"id"`
+	ClientID    string     `json:"client_id"`
+	Name        string     `json:"name,omitempty"`
+	Scopes      []string   `json:"scopes"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	RotatedFrom string     `json:"rotated_from,omitempty"`
+}
+
+// Active — ключ не отозван и не истёк на момент now.
+func (k *Key) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// generate создаёт ключ "psk_<id>_<secret>": id — 16 hex-символов (идентификатор записи),
+// secret — 32 случайных байта в base64url.
+func generate() (id, plaintext string, err error) {
+	buf := make([]byte, 8+32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	id = hex.EncodeToString(buf[:8])
+	return id, prefix + id + "_" + base64.RawURLEncoding.EncodeToString(buf[8:]), nil
+}
+
+// parse возвращает id записи из ключа.
+func parse(plaintext string) (string, bool) {
+	rest, ok := strings.CutPrefix(plaintext, prefix)
+	if !ok {
+		return "", false
+	}
+	id, secret, ok := strings.Cut(rest, "_")
+	if !ok || len(id) != 16 || secret == "" {
+		return "", false
+	}
+	if _, err := hex.DecodeString(id); err != nil {
+		return "", false
+	}
+	return id, true
+}
+
+func hash(plaintext string) []byte {
+	sum := sha256.Sum256([]byte(plaintext))
+	return sum[:]
+}
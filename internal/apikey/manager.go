@@ -0,0 +1,200 @@
+package apikey
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/psds-microservice/api-gateway/internal/auth"
+	apperrors "github.com/psds-microservice/api-gateway/internal/errors"
+	"github.com/psds-microservice/api-gateway/pkg/constants"
+)
+
+// IssueRequest — параметры нового ключа.
+type IssueRequest struct {
+	ClientID string
+	Name     string
+	Scopes   []string
+	TTL      time.Duration // 0 — бессрочный
+}
+
+// Manager выдаёт, ротирует, отзывает и проверяет ключи. Проверенные записи кэшируются
+// на cacheTTL: отзыв на этом экземпляре действует сразу, на остальных — в пределах cacheTTL.
+type Manager struct {
+	store    Store
+	cacheTTL time.Duration
+	grace    time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cachedKey
+}
+
+type cachedKey struct {
+	key     *Key
+	hash    []byte
+	fetched time.Time
+}
+
+// NewManager создаёт Manager. grace — сколько старый ключ продолжает работать после ротации.
+func NewManager(store Store, cacheTTL, grace time.Duration) *Manager {
+	return &Manager{store: store, cacheTTL: cacheTTL, grace: grace, cache: make(map[string]cachedKey)}
+}
+
+// Issue создаёт ключ. Открытый ключ возвращается только здесь — в хранилище лежит его хэш.
+func (m *Manager) Issue(ctx context.Context, req IssueRequest) (string, *Key, error) {
+	if err := validate(req); err != nil {
+		return "", nil, err
+	}
+	id, plaintext, err := generate()
+	if err != nil {
+		return "", nil, err
+	}
+	k := newKey(id, req)
+	if err := m.store.Insert(ctx, k, hash(plaintext)); err != nil {
+		return "", nil, err
+	}
+	return plaintext, k, nil
+}
+
+// List возвращает ключи клиента (clientID == "" — все).
+func (m *Manager) List(ctx context.Context, clientID string) ([]*Key, error) {
+	return m.store.List(ctx, clientID)
+}
+
+// Rotate выдаёт новый ключ с теми же client_id, именем и scopes; старый перестаёт
+// действовать через grace (grace < 0 — Manager.grace по умолчанию, 0 — сразу).
+func (m *Manager) Rotate(ctx context.Context, id string, grace time.Duration) (string, *Key, error) {
+	old, _, err := m.store.Get(ctx, id)
+	if err != nil {
+		return "", nil, err
+	}
+	if !old.Active(time.Now()) {
+		return "", nil, ErrInactive
+	}
+	if grace < 0 {
+		grace = m.grace
+	}
+	newID, plaintext, err := generate()
+	if err != nil {
+		return "", nil, err
+	}
+	// Новый ключ наследует и срок действия старого.
+	k := newKey(newID, IssueRequest{ClientID: old.ClientID, Name: old.Name, Scopes: old.Scopes})
+	k.ExpiresAt = old.ExpiresAt
+	k.RotatedFrom = old.ID
+	if err := m.store.Rotate(ctx, old.ID, time.Now().Add(grace), k, hash(plaintext)); err != nil {
+		return "", nil, err
+	}
+	m.evict(old.ID)
+	return plaintext, k, nil
+}
+
+// Revoke отзывает ключ.
+func (m *Manager) Revoke(ctx context.Context, id string) error {
+	if err := m.store.Revoke(ctx, id, time.Now()); err != nil {
+		return err
+	}
+	m.evict(id)
+	return nil
+}
+
+// Verify проверяет ключ и возвращает identity: subject — client_id ключа, роль — client,
+// разрешения ограничены scopes ключа. Ошибка хранилища возвращается как есть (не ErrInvalidKey).
+func (m *Manager) Verify(ctx context.Context, plaintext string) (*auth.Identity, error) {
+	id, ok := parse(plaintext)
+	if !ok {
+		return nil, fmt.Errorf("%w: malformed key", ErrInvalidKey)
+	}
+	k, keyHash, err := m.lookup(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("%w: unknown key", ErrInvalidKey)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(keyHash, hash(plaintext)) != 1 {
+		return nil, fmt.Errorf("%w: unknown key", ErrInvalidKey)
+	}
+	if !k.Active(time.Now()) {
+		return nil, fmt.Errorf("%w: key is revoked or expired", ErrInvalidKey)
+	}
+	return &auth.Identity{
+		Subject: k.ClientID,
+		Roles:   []string{constants.RoleClient},
+		Scopes:  k.Scopes,
+		KeyID:   k.ID,
+	}, nil
+}
+
+// lookup читает запись из кэша или хранилища. last_used_at обновляется при чтении из хранилища,
+// то есть не чаще раза в cacheTTL на ключ.
+func (m *Manager) lookup(ctx context.Context, id string) (*Key, []byte, error) {
+	now := time.Now()
+	m.mu.Lock()
+	c, ok := m.cache[id]
+	m.mu.Unlock()
+	if ok && now.Sub(c.fetched) < m.cacheTTL {
+		return c.key, c.hash, nil
+	}
+	k, keyHash, err := m.store.Get(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if k.Active(now) {
+		_ = m.store.Touch(ctx, id, now)
+	}
+	if m.cacheTTL > 0 {
+		m.mu.Lock()
+		for cid, e := range m.cache {
+			if now.Sub(e.fetched) >= m.cacheTTL {
+				delete(m.cache, cid)
+			}
+		}
+		m.cache[id] = cachedKey{key: k, hash: keyHash, fetched: now}
+		m.mu.Unlock()
+	}
+	return k, keyHash, nil
+}
+
+func (m *Manager) evict(id string) {
+	m.mu.Lock()
+	delete(m.cache, id)
+	m.mu.Unlock()
+}
+
+func newKey(id string, req IssueRequest) *Key {
+	k := &Key{
+		ID:        id,
+		ClientID:  req.ClientID,
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+	if req.TTL > 0 {
+		exp := k.CreatedAt.Add(req.TTL)
+		k.ExpiresAt = &exp
+	}
+	return k
+}
+
+func validate(req IssueRequest) error {
+	if strings.TrimSpace(req.ClientID) == "" {
+		return fmt.Errorf("%w: client_id is required", apperrors.ErrInvalidRequest)
+	}
+	if len(req.Scopes) == 0 {
+		return fmt.Errorf("%w: at least one scope is required", apperrors.ErrInvalidRequest)
+	}
+	for _, s := range req.Scopes {
+		if s == "" || strings.ContainsAny(s, " ,") {
+			return fmt.Errorf("%w: invalid scope %q", apperrors.ErrInvalidRequest, s)
+		}
+	}
+	if req.TTL < 0 {
+		return fmt.Errorf("%w: ttl must be >= 0", apperrors.ErrInvalidRequest)
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package apikey
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Store — хранилище API-ключей.
+type Store interface {
+	Insert(ctx context.Context, k *Key, keyHash []byte) error
+	// Get возвращает запись и хэш ключа; ErrNotFound, если записи нет.
+	Get(ctx context.Context, id string) (*Key, []byte, error)
+	// List возвращает ключи клиента (clientID == "" — все), новые первыми.
+	List(ctx context.Context, clientID string) ([]*Key, error)
+	Revoke(ctx context.Context, id string, at time.Time) error
+	// Rotate атомарно сохраняет новый ключ и ограничивает срок старого до oldExpiresAt.
+	Rotate(ctx context.Context, oldID string, oldExpiresAt time.Time, k *Key, keyHash []byte) error
+	Touch(ctx context.Context, id string, at time.Time) error
+}
+
+// PostgresStore — Store в таблице api_keys (database/migrations).
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore создаёт Store поверх PostgreSQL.
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+const keyColumns = `id, client_id, name, scopes, created_at, expires_at, revoked_at, last_used_at, rotated_from`
+
+func (s *PostgresStore) Insert(ctx context.Context, k *Key, keyHash []byte) error {
+	return insert(ctx, s.db, k, keyHash)
+}
+
+func (s *PostgresStore) Get(ctx context.Context, id string) (*Key, []byte, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+keyColumns+`, key_hash FROM api_keys WHERE id = $1`, id)
+	var keyHash []byte
+	k, err := scanKey(row, &keyHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return k, keyHash, nil
+}
+
+func (s *PostgresStore) List(ctx context.Context, clientID string) ([]*Key, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+keyColumns+` FROM api_keys WHERE $1 = '' OR client_id = $1 ORDER BY created_at DESC`, clientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	keys := []*Key{}
+	for rows.Next() {
+		k, err := scanKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+func (s *PostgresStore) Revoke(ctx context.Context, id string, at time.Time) error {
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE api_keys SET revoked_at = COALESCE(revoked_at, $2) WHERE id = $1`, id, at)
+	if err != nil {
+		return err
+	}
+	return affected(res)
+}
+
+func (s *PostgresStore) Rotate(ctx context.Context, oldID string, oldExpiresAt time.Time, k *Key, keyHash []byte) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	// Срок старого ключа только сокращается: уже истекающий раньше ключ не продлевается.
+	res, err := tx.ExecContext(ctx,
+		`UPDATE api_keys SET expires_at = LEAST(COALESCE(expires_at, $2), $2) WHERE id = $1 AND revoked_at IS NULL`,
+		oldID, oldExpiresAt)
+	if err != nil {
+		return err
+	}
+	if err := affected(res); err != nil {
+		return err
+	}
+	if err := insert(ctx, tx, k, keyHash); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *PostgresStore) Touch(ctx context.Context, id string, at time.Time) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE api_keys SET last_used_at = $2 WHERE id = $1`, id, at)
+	return err
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func insert(ctx context.Context, db execer, k *Key, keyHash []byte) error {
+	_, err := db.ExecContext(ctx,
+		`INSERT INTO api_keys (id, key_hash, client_id, name, scopes, created_at, expires_at, rotated_from)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''))`,
+		k.ID, keyHash, k.ClientID, k.Name, pq.Array(k.Scopes), k.CreatedAt, k.ExpiresAt, k.RotatedFrom)
+	return err
+}
+
+func affected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanKey(row scanner, extra ...any) (*Key, error) {
+	var (
+		k           Key
+		rotatedFrom sql.NullString
+	)
+	dest := append([]any{
+		&k.ID, &k.ClientID, &k.Name, pq.Array(&k.Scopes), &k.CreatedAt,
+		&k.ExpiresAt, &k.RevokedAt, &k.LastUsedAt, &rotatedFrom,
+	}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	k.RotatedFrom = rotatedFrom.String
+	return &k, nil
+}
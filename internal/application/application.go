@@ -3,94 +3,178 @@ package application
 import (
 	"context"
 	"fmt"
-	"log"
 	"net"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/psds-microservice/api-gateway/internal/config"
+	"github.com/psds-microservice/api-gateway/internal/logging"
+	"github.com/psds-microservice/api-gateway/internal/tracing"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 )
 
 // API — приложение dual HTTP + gRPC (по PROJECT_PROMPT: net/http + grpc-gateway).
 type API struct {
-	cfg     *config.Config
-	httpSrv *http.Server
-	grpcSrv *grpc.Server
-	lis     net.Listener
+	cfg      *config.Config
+	httpSrv  *http.Server
+	grpcSrv  *grpc.Server
+	lis      net.Listener // nil — gRPC на порту HTTP (SINGLE_PORT_ENABLED)
+	router   *Router
+	reloader *routeReloader
+	logger   *zap.Logger
+	// shutdownTracing отправляет накопленные спаны при остановке.
+	shutdownTracing func(context.Context) error
 }
 
-// NewAPI создаёт приложение. Конфиг только из .env (Load).
-func NewAPI(cfg *config.Config, logger *zap.Logger) (*API, error) {
-	handler, grpcSrv, _, _, err := NewRouter(cfg, logger)
+// NewAPI создаёт приложение. Конфиг только из .env (Load); levels — уровни логгера logger,
+// изменяемые через admin API.
+func NewAPI(cfg *config.Config, logger *zap.Logger, levels *logging.Levels) (*API, error) {
+	shutdownTracing, err := tracing.Init(context.Background(), cfg, logger.Named("tracing"))
 	if err != nil {
 		return nil, err
 	}
+	router, err := NewRouter(cfg, logger, levels)
+	if err != nil {
+		_ = shutdownTracing(context.Background())
+		return nil, err
+	}
 
 	httpAddr := fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
+	// ReadTimeout/WriteTimeout не задаются на уровне сервера: таймауты выставляются на запрос —
+	// по маршруту прокси (timeout_ms, idle_timeout_sec) или HTTP_*_TIMEOUT_SEC для остальных путей.
 	httpSrv := &http.Server{
 		Addr:              httpAddr,
-		Handler:           handler,
+		Handler:           router.Handler,
 		ReadHeaderTimeout: 5 * time.Second,
-		ReadTimeout:       15 * time.Second,
-		WriteTimeout:      30 * time.Second,
 		IdleTimeout:       60 * time.Second,
 	}
-
-	grpcPort := cfg.GRPCPort
-	if grpcPort == "" {
-		grpcPort = "9090"
+	if cfg.TLS.HTTP {
+		httpSrv.TLSConfig = router.TLS.ServerConfig("h2", "http/1.1")
 	}
-	grpcAddr := ":" + grpcPort
-	lis, err := net.Listen("tcp", grpcAddr)
-	if err != nil {
-		return nil, fmt.Errorf("grpc listen %s: %w", grpcAddr, err)
+
+	var lis net.Listener
+	if cfg.SinglePort {
+		httpSrv.Handler = grpcOrHTTP(router.GRPC, router.Handler)
+		httpSrv.Protocols = new(http.Protocols)
+		httpSrv.Protocols.SetHTTP1(true)
+		httpSrv.Protocols.SetHTTP2(true)
+		// Без TLS gRPC-клиенты подключаются по h2c (HTTP/2 with prior knowledge).
+		httpSrv.Protocols.SetUnencryptedHTTP2(!cfg.TLS.HTTP)
+	} else {
+		grpcPort := cfg.GRPCPort
+		if grpcPort == "" {
+			grpcPort = "9090"
+		}
+		grpcAddr := ":" + grpcPort
+		if lis, err = net.Listen("tcp", grpcAddr); err != nil {
+			router.Close()
+			_ = shutdownTracing(context.Background())
+			return nil, fmt.Errorf("grpc listen %s: %w", grpcAddr, err)
+		}
 	}
 
 	return &API{
-		cfg:     cfg,
-		httpSrv: httpSrv,
-		grpcSrv: grpcSrv,
-		lis:     lis,
+		cfg:      cfg,
+		httpSrv:  httpSrv,
+		grpcSrv:  router.GRPC,
+		lis:      lis,
+		router:   router,
+		reloader: newRouteReloader(cfg, router, logger.Named("proxy")),
+		logger:   logger,
+
+		shutdownTracing: shutdownTracing,
 	}, nil
 }
 
+// grpcOrHTTP направляет gRPC-запросы (HTTP/2, content-type application/grpc) в grpcSrv мимо
+// HTTP middleware (у gRPC свои interceptor-ы), остальные — в next. gRPC-Web не поддерживается.
+func grpcOrHTTP(grpcSrv *grpc.Server, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && isGRPCContentType(r.Header.Get("Content-Type")) {
+			grpcSrv.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isGRPCContentType — "application/grpc" с необязательным "+proto" / "+json" или параметрами.
+func isGRPCContentType(ct string) bool {
+	rest, ok := strings.CutPrefix(ct, "application/grpc")
+	return ok && (rest == "" || rest[0] == '+' || rest[0] == ';')
+}
+
 // Run запускает HTTP и gRPC серверы, блокируется до отмены ctx.
 func (a *API) Run(ctx context.Context) error {
 	httpAddr := a.httpSrv.Addr
-	grpcAddr := a.lis.Addr().String()
+	grpcAddr := httpAddr
+	if a.lis != nil {
+		grpcAddr = a.lis.Addr().String()
+	}
 	host := a.cfg.Host
 	if host == "0.0.0.0" {
 		host = "localhost"
 	}
-	httpBase := fmt.Sprintf("http://%s:%d", host, a.cfg.Port)
-	log.Printf("HTTP server listening on %s", httpAddr)
-	log.Printf("  Swagger UI:    %s/swagger", httpBase)
-	log.Printf("  Swagger spec:  %s/openapi.json", httpBase)
-	log.Printf("  Health:        %s/health", httpBase)
-	log.Printf("  Ready:         %s/ready", httpBase)
-	log.Printf("  API v1:        %s/api/v1/", httpBase)
-	log.Printf("gRPC server listening on %s", grpcAddr)
-	log.Printf("  gRPC endpoint: %s (reflection enabled)", grpcAddr)
+	scheme := "http"
+	if a.httpSrv.TLSConfig != nil {
+		scheme = "https"
+	}
+	httpBase := fmt.Sprintf("%s://%s:%d", scheme, host, a.cfg.Port)
+	a.logger.Info("HTTP server listening",
+		zap.String("addr", httpAddr),
+		zap.Bool("tls", a.cfg.TLS.HTTP),
+		zap.String("swagger_ui", httpBase+"/swagger"),
+		zap.String("swagger_spec", httpBase+"/openapi.json"),
+		zap.String("health", httpBase+"/health"),
+		zap.String("ready", httpBase+"/ready"),
+		zap.String("api_v1", httpBase+"/api/v1/"))
+	a.logger.Info("gRPC server listening (reflection enabled)",
+		zap.String("addr", grpcAddr),
+		zap.Bool("tls", a.cfg.GRPCTLS()),
+		zap.Bool("single_port", a.lis == nil))
 
 	go func() {
-		if err := a.httpSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Printf("http: %v", err)
+		var err error
+		if a.httpSrv.TLSConfig != nil {
+			// Сертификат задаёт TLSConfig (перечитывается при изменении файлов).
+			err = a.httpSrv.ListenAndServeTLS("", "")
+		} else {
+			err = a.httpSrv.ListenAndServe()
 		}
-	}()
-	go func() {
-		if err := a.grpcSrv.Serve(a.lis); err != nil {
-			log.Printf("grpc: %v", err)
+		if err != nil && err != http.ErrServerClosed {
+			a.logger.Error("HTTP server failed", zap.Error(err))
 		}
 	}()
+	if a.lis != nil {
+		go func() {
+			if err := a.grpcSrv.Serve(a.lis); err != nil {
+				a.logger.Error("gRPC server failed", zap.Error(err))
+			}
+		}()
+	}
+	go a.reloader.Run(ctx)
+	go a.router.Health.Run(ctx)
 
 	<-ctx.Done()
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
+	// Upgraded-соединения http.Server.Shutdown не отслеживает: закрываем их сами.
+	a.router.WebSockets.Shutdown(shutdownCtx)
 	if err := a.httpSrv.Shutdown(shutdownCtx); err != nil {
-		log.Printf("http shutdown: %v", err)
+		a.logger.Error("HTTP server shutdown failed", zap.Error(err))
+	}
+	if a.lis != nil {
+		a.grpcSrv.GracefulStop()
+	} else {
+		// Стримы на общем порту дожидается httpSrv.Shutdown; GracefulStop для
+		// grpc.Server.ServeHTTP не поддерживается — оставшиеся после таймаута закрываются.
+		a.grpcSrv.Stop()
+	}
+	a.router.Close()
+	if err := a.shutdownTracing(shutdownCtx); err != nil {
+		a.logger.Error("Tracing shutdown failed", zap.Error(err))
 	}
-	a.grpcSrv.GracefulStop()
 	return nil
 }
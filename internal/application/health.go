@@ -0,0 +1,70 @@
+package application
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"time"
+
+	"github.com/psds-microservice/api-gateway/internal/config"
+	"github.com/psds-microservice/api-gateway/internal/grpc_client"
+	"github.com/psds-microservice/api-gateway/internal/health"
+	"github.com/psds-microservice/api-gateway/internal/proxy"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Имена зависимостей в /ready (кроме upstream-ов прокси, которые называются по имени upstream-а).
+const (
+	depUserServiceGRPC = "user-service-grpc"
+	depPostgres        = "postgres"
+	depRedis           = "redis"
+)
+
+// newHealthChecker собирает проверки для /ready: user-service (gRPC HealthCheck), Postgres (db == nil —
+// без проверки), Redis и upstream-ы текущей таблицы прокси. Возвращает функции закрытия созданных подключений.
+func newHealthChecker(cfg *config.Config, db *sql.DB, userClient grpc_client.UserServiceClient, proxyRouter *proxy.Router, logger *zap.Logger) (*health.Checker, []func() error) {
+	required := func(name string) bool { return slices.Contains(cfg.Health.Required, name) }
+
+	var closers []func() error
+	probes := []health.Probe{{
+		Name:     depUserServiceGRPC,
+		Required: required(depUserServiceGRPC) || required(config.UpstreamUserService),
+		Check:    userClient.HealthCheck,
+	}}
+	if db != nil {
+		probes = append(probes, health.Probe{Name: depPostgres, Required: required(depPostgres), Check: db.PingContext})
+	} else {
+		logger.Warn("Postgres health probe disabled")
+	}
+
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     cfg.RedisAddr(),
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+		// проба не должна ретраить: её таймаут задаёт Checker
+		MaxRetries: -1,
+	})
+	closers = append(closers, rdb.Close)
+	probes = append(probes, health.Probe{
+		Name:     depRedis,
+		Required: required(depRedis),
+		Check:    func(ctx context.Context) error { return rdb.Ping(ctx).Err() },
+	})
+
+	upstreams := func() []health.Probe {
+		ps := proxyRouter.HealthProbes()
+		for i := range ps {
+			ps[i].Required = ps[i].Required || required(ps[i].Name)
+		}
+		return ps
+	}
+	checker := health.NewChecker(
+		time.Duration(cfg.Health.IntervalSec)*time.Second,
+		time.Duration(cfg.Health.TimeoutSec)*time.Second,
+		logger,
+		health.Static(probes...),
+		upstreams,
+	)
+	return checker, closers
+}
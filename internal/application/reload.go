@@ -0,0 +1,198 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/psds-microservice/api-gateway/internal/cache"
+	"github.com/psds-microservice/api-gateway/internal/config"
+	"github.com/psds-microservice/api-gateway/internal/corspolicy"
+	"github.com/psds-microservice/api-gateway/internal/proxy"
+	"github.com/psds-microservice/api-gateway/internal/ratelimit"
+	"github.com/psds-microservice/api-gateway/internal/rbac"
+	"go.uber.org/zap"
+)
+
+// routeReloader перечитывает таблицу маршрутов прокси, политики RBAC и CORS и лимиты запросов по SIGHUP
+// и при изменении .env / GATEWAY_CONFIG_FILE (опрос mtime). Новая таблица подменяется атомарно;
+// активные запросы, gRPC-стримы и WebSocket-соединения дорабатывают на старой.
+type routeReloader struct {
+	router   *proxy.Router
+	authz    *rbac.Authorizer
+	cors     *corspolicy.Holder
+	limiter  *ratelimit.Limiter // nil — лимиты выключены
+	cache    *cache.Cache
+	sockets  *proxy.WebSockets
+	logger   *zap.Logger
+	interval time.Duration
+	envFile  string
+
+	mu       sync.Mutex
+	gwFile   string
+	modTimes map[string]time.Time
+}
+
+func newRouteReloader(cfg *config.Config, app *Router, logger *zap.Logger) *routeReloader {
+	r := &routeReloader{
+		router:   app.Proxy,
+		authz:    app.Authz,
+		cors:     app.CORS,
+		limiter:  app.Limiter,
+		cache:    app.Cache,
+		sockets:  app.WebSockets,
+		logger:   logger,
+		interval: time.Duration(cfg.GatewayReloadIntervalSec) * time.Second,
+		envFile:  ".env",
+		gwFile:   cfg.GatewayFile,
+	}
+	r.modTimes = r.snapshot()
+	return r
+}
+
+// Run обрабатывает SIGHUP и опрашивает файлы до отмены ctx.
+func (r *routeReloader) Run(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var tick <-chan time.Time
+	if r.interval > 0 {
+		t := time.NewTicker(r.interval)
+		defer t.Stop()
+		tick = t.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			r.logger.Info("SIGHUP received, reloading proxy routes")
+			r.reload()
+		case <-tick:
+			if r.changed() {
+				r.logger.Info("Gateway config changed on disk, reloading proxy routes")
+				r.reload()
+			}
+		}
+	}
+}
+
+// load перечитывает .env и таблицу маршрутов. При ошибке текущая таблица остаётся в силе.
+func (r *routeReloader) load() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Окружение процесса важнее .env, как и при старте (config.LoadDotEnv).
+	changed, shadowed, err := config.ReloadDotEnv(r.envFile)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%s: %w", r.envFile, err)
+	}
+	r.logEnv(changed, shadowed)
+	cfg := config.Load()
+	// Запоминаем mtime до валидации, чтобы ошибочный файл не перечитывался на каждом тике.
+	r.gwFile = cfg.GatewayFile
+	r.modTimes = r.snapshot()
+	gw, err := config.LoadGateway(cfg, reservedPaths...)
+	if err != nil {
+		return err
+	}
+	table, err := proxy.NewTable(gw, r.cache, r.sockets, r.logger)
+	if err != nil {
+		return fmt.Errorf("proxy routes: %w", err)
+	}
+	old := r.router.Swap(table)
+	old.Close()
+	r.authz.Swap(rbac.New(gw.RBAC))
+	r.cors.Swap(corspolicy.New(gw.CORS, gw.Routes))
+	if r.limiter != nil {
+		r.limiter.Swap(ratelimit.New(gw.RateLimit, gw.Routes))
+	}
+	r.logger.Info("Proxy routes reloaded", zap.Int("routes", len(table.Routes())))
+	return nil
+}
+
+// reloadableEnv — переменные, которые применяются перечитыванием; остальные требуют перезапуска.
+// Элемент с "_" на конце — префикс, с "_" в начале — суффикс переменных upstream-ов (<NAME>_URL и т.п.).
+var reloadableEnv = []string{
+	"GATEWAY_CONFIG_FILE", "USER_SERVICE_HOST", "USER_SERVICE_HTTP_PORT",
+	"_URL", "_LB_POLICY", "_LB_HASH_ON", "_HEALTH_PATH",
+	"_TLS_CA_FILE", "_TLS_CERT_FILE", "_TLS_KEY_FILE", "_TLS_SERVER_NAME",
+	"CIRCUIT_BREAKER_", "PROXY_RETRY_", "RETRY_BUDGET_", "PROXY_TIMEOUT_MS", "PROXY_IDLE_TIMEOUT_SEC",
+	"RBAC_DEFAULT", "RATE_LIMIT_RPS", "RATE_LIMIT_BURST", "RATE_LIMIT_KEY", "WS_", "CORS_",
+}
+
+func reloadable(key string) bool {
+	for _, p := range reloadableEnv {
+		switch {
+		case strings.HasPrefix(p, "_") && strings.HasSuffix(key, p),
+			strings.HasSuffix(p, "_") && strings.HasPrefix(key, p),
+			key == p:
+			return true
+		}
+	}
+	return false
+}
+
+// logEnv пишет в лог изменённые в .env переменные (только имена: значения могут быть секретами).
+func (r *routeReloader) logEnv(changed, shadowed []string) {
+	var applied, ignored []string
+	for _, k := range changed {
+		if reloadable(k) {
+			applied = append(applied, k)
+		} else {
+			ignored = append(ignored, k)
+		}
+	}
+	if len(applied) > 0 {
+		r.logger.Info("Settings changed in .env", zap.Strings("keys", applied))
+	}
+	if len(ignored) > 0 {
+		r.logger.Warn("Settings changed in .env require a restart, ignored", zap.Strings("keys", ignored))
+	}
+	if len(shadowed) > 0 {
+		r.logger.Warn("Settings in .env are overridden by the process environment, ignored", zap.Strings("keys", shadowed))
+	}
+}
+
+func (r *routeReloader) reload() {
+	if err := r.load(); err != nil {
+		r.logger.Error("Proxy routes reload failed, keeping current routes", zap.Error(err))
+	}
+}
+
+func (r *routeReloader) changed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.snapshot()
+	if len(now) != len(r.modTimes) {
+		return true
+	}
+	for f, t := range now {
+		if !r.modTimes[f].Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// snapshot возвращает mtime отслеживаемых файлов (отсутствующие файлы не попадают в карту).
+func (r *routeReloader) snapshot() map[string]time.Time {
+	out := make(map[string]time.Time, 2)
+	for _, f := range []string{r.envFile, r.gwFile} {
+		if f == "" {
+			continue
+		}
+		if st, err := os.Stat(f); err == nil {
+			out[f] = st.ModTime()
+		}
+	}
+	return out
+}
@@ -1,15 +1,11 @@
 package application
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"log"
 	"net/http"
-	"net/http/httputil"
-	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -17,75 +13,231 @@ import (
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/psds-microservice/api-gateway/api"
+	"github.com/psds-microservice/api-gateway/internal/accesslog"
+	"github.com/psds-microservice/api-gateway/internal/apikey"
+	"github.com/psds-microservice/api-gateway/internal/auth"
+	"github.com/psds-microservice/api-gateway/internal/cache"
+	"github.com/psds-microservice/api-gateway/internal/certs"
+	"github.com/psds-microservice/api-gateway/internal/clientip"
+	"github.com/psds-microservice/api-gateway/internal/coalesce"
 	"github.com/psds-microservice/api-gateway/internal/config"
 	"github.com/psds-microservice/api-gateway/internal/controller"
+	"github.com/psds-microservice/api-gateway/internal/corspolicy"
+	"github.com/psds-microservice/api-gateway/internal/database"
 	"github.com/psds-microservice/api-gateway/internal/grpc_client"
 	"github.com/psds-microservice/api-gateway/internal/grpc_server"
 	"github.com/psds-microservice/api-gateway/internal/handler"
+	"github.com/psds-microservice/api-gateway/internal/health"
+	"github.com/psds-microservice/api-gateway/internal/logging"
+	"github.com/psds-microservice/api-gateway/internal/metrics"
+	"github.com/psds-microservice/api-gateway/internal/middleware"
+	"github.com/psds-microservice/api-gateway/internal/proxy"
+	"github.com/psds-microservice/api-gateway/internal/ratelimit"
+	"github.com/psds-microservice/api-gateway/internal/rbac"
+	"github.com/psds-microservice/api-gateway/internal/reqinfo"
+	"github.com/psds-microservice/api-gateway/internal/requestid"
+	"github.com/psds-microservice/api-gateway/internal/tracing"
 	"github.com/psds-microservice/api-gateway/pkg/gen"
-	"github.com/rs/cors"
+	"github.com/redis/go-redis/v9"
 	httpSwagger "github.com/swaggo/http-swagger"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/reflection"
 )
 
-// quietCancelWriter suppresses repeated "context canceled" proxy errors to avoid log flood when many clients time out.
-// quietCancelWriter подавляет повторяющиеся ошибки прокси "context canceled", чтобы не засорять лог при массовых таймаутах.
-type quietCancelWriter struct{ w io.Writer }
+// reservedPaths — пути, которые обслуживает сам gateway; маршруты прокси не могут их перекрывать.
+var reservedPaths = []string{
+	"/health", "/ready", "/metrics", "/openapi.json", "/swagger/",
+	"/v1/limits/", "/api/v1/limits/", "/api/v1/status", "/api/v1/test/",
+	"/api/v1/video/", "/api/v1/clients/", "/api/v1/admin/",
+}
 
-func (q quietCancelWriter) Write(p []byte) (n int, err error) {
-	if bytes.Contains(p, []byte("context canceled")) {
-		return len(p), nil
-	}
-	return q.w.Write(p)
+// gatewayPaths — поддеревья, которые обслуживает grpc-gateway (смонтирован в mux на "/").
+var gatewayPaths = []string{"/api/v1/video/", "/api/v1/clients/"}
+
+// Router — собранное приложение: HTTP handler, gRPC сервер, прокси-маршрутизатор, политики RBAC
+// и CORS, лимиты запросов, кэш ответов и учёт WebSocket-соединений (для hot reload и остановки)
+// и фоновые проверки зависимостей.
+type Router struct {
+	Handler    http.Handler
+	GRPC       *grpc.Server
+	Video      *grpc_server.VideoStreamServer
+	ClientInfo *grpc_server.ClientInfoServer
+	Proxy      *proxy.Router
+	Authz      *rbac.Authorizer
+	CORS       *corspolicy.Holder
+	Limiter    *ratelimit.Limiter // nil — RATE_LIMIT_ENABLED=false
+	Cache      *cache.Cache
+	WebSockets *proxy.WebSockets
+	Health     *health.Checker
+	TLS        *certs.Store // nil — TLS входящих соединений выключен
+
+	closers []func() error
 }
 
-// newReverseProxy returns a SingleHostReverseProxy that logs proxy errors except "context canceled" (reduces log spam).
-func newReverseProxy(target *url.URL) *httputil.ReverseProxy {
-	p := httputil.NewSingleHostReverseProxy(target)
-	p.ErrorLog = log.New(quietCancelWriter{w: os.Stderr}, "", 0)
-	return p
+// Close освобождает подключения, созданные NewRouter.
+func (r *Router) Close() {
+	for _, c := range r.closers {
+		_ = c()
+	}
 }
 
 // NewRouter создаёт http.Handler с net/http + grpc-gateway (по PROJECT_PROMPT, без Gin).
-func NewRouter(cfg *config.Config, logger *zap.Logger) (http.Handler, *grpc.Server, *grpc_server.VideoStreamServer, *grpc_server.ClientInfoServer, error) {
-	userClient, err := grpc_client.NewUserServiceClient(cfg, logger)
+// Компоненты получают именованные логгеры (logger.Named), уровни которых задаются LOG_LEVELS
+// и admin API (levels; nil — admin API уровней не регистрируется).
+func NewRouter(cfg *config.Config, logger *zap.Logger, levels *logging.Levels) (*Router, error) {
+	if cfg.Health.IntervalSec <= 0 {
+		return nil, fmt.Errorf("health: HEALTH_CHECK_INTERVAL_SEC must be positive, got %d", cfg.Health.IntervalSec)
+	}
+	if cfg.Health.TimeoutSec <= 0 {
+		return nil, fmt.Errorf("health: HEALTH_CHECK_TIMEOUT_SEC must be positive, got %d", cfg.Health.TimeoutSec)
+	}
+
+	var serverTLS *certs.Store
+	if cfg.TLS.HTTP || cfg.GRPCTLS() {
+		if err := cfg.TLS.Server.Validate(true); err != nil {
+			return nil, fmt.Errorf("tls: %w", err)
+		}
+		var err error
+		if serverTLS, err = certs.New("server", cfg.TLS.Server, logger.Named("tls")); err != nil {
+			return nil, err
+		}
+	}
+
+	userClient, err := grpc_client.NewUserServiceClient(cfg, logger.Named("user_client"))
 	if err != nil {
-		return nil, nil, nil, nil, fmt.Errorf("user service client: %w", err)
+		return nil, fmt.Errorf("user service client: %w", err)
 	}
 
 	streamRepo := controller.NewStreamRepository()
 	clientRepo := controller.NewClientRepository()
-	clientInfoService := controller.NewClientInfoService(logger, clientRepo)
-	videoStreamService := controller.NewVideoStreamService(logger, streamRepo, userClient)
+	metrics.SetRepositorySize("stream", streamRepo.Len)
+	metrics.SetRepositorySize("client", clientRepo.Len)
+	clientInfoService := controller.NewClientInfoService(logger.Named("controller"), clientRepo)
+	videoStreamService := controller.NewVideoStreamService(logger.Named("controller"), streamRepo, userClient)
 
 	deps := grpc_server.Deps{
 		Video:      videoStreamService,
 		ClientInfo: clientInfoService,
-		Logger:     logger,
+		Logger:     logger.Named("grpc"),
 	}
 	servers := grpc_server.NewServersFromDeps(deps)
 
-	grpcSrv := grpc.NewServer(
-		grpc.MaxRecvMsgSize(50*1024*1024),
-		grpc.MaxSendMsgSize(10*1024*1024),
-	)
+	// Postgres: API-ключи и проверка в /ready. sql.Open не подключается — недоступность
+	// базы проявится в запросах, а не при старте.
+	var closers []func() error
+	db, err := database.Open(cfg.DSN())
+	if err != nil {
+		logger.Warn("Postgres disabled", zap.Error(err))
+		db = nil
+	} else {
+		db.SetMaxOpenConns(10)
+		closers = append(closers, db.Close)
+	}
+
+	verifier, err := auth.NewVerifier(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("auth: %w", err)
+	}
+	var keys *apikey.Manager
+	if cfg.Auth.Enabled && cfg.APIKeys.Enabled && db != nil {
+		keys = apikey.NewManager(apikey.NewPostgresStore(db),
+			time.Duration(cfg.APIKeys.CacheTTLSec)*time.Second,
+			time.Duration(cfg.APIKeys.RotationGraceSec)*time.Second)
+	}
+	var devices *auth.Devices
+	if cfg.Auth.Enabled && cfg.DeviceCerts.Enabled {
+		if !cfg.GRPCTLS() || cfg.TLS.Server.ClientAuth == config.TLSClientAuthNone {
+			return nil, errors.New("device certificates: DEVICE_CERT_AUTH_ENABLED requires gRPC TLS and TLS_CLIENT_AUTH")
+		}
+		if devices, err = auth.NewDevices(cfg, logger.Named("auth")); err != nil {
+			return nil, fmt.Errorf("device certificates: %w", err)
+		}
+	}
+	gw, err := config.LoadGateway(cfg, reservedPaths...)
+	if err != nil {
+		return nil, err
+	}
+	authz := rbac.NewAuthorizer(rbac.New(gw.RBAC))
+	corsPolicy := corspolicy.NewHolder(corspolicy.New(gw.CORS, gw.Routes))
+
+	var limiter *ratelimit.Limiter
+	if cfg.RateLimit.Enabled {
+		backend, closeBackend := newRateLimitBackend(cfg, logger.Named("ratelimit"))
+		if closeBackend != nil {
+			closers = append(closers, closeBackend)
+		}
+		limiter = ratelimit.NewLimiter(ratelimit.New(gw.RateLimit, gw.Routes), backend)
+	}
+
+	responses, closeCache := newResponseCache(cfg, logger.Named("cache"))
+	if closeCache != nil {
+		closers = append(closers, closeCache)
+	}
+
+	ips, err := clientip.NewResolver(cfg.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("TRUSTED_PROXIES: %w", err)
+	}
+
+	access := accesslog.New(cfg, logger)
+
+	authLog, rbacLog, limitLog := logger.Named("auth"), logger.Named("rbac"), logger.Named("ratelimit")
+	// Порядок: метрики -> request ID -> журнал доступа -> IP клиента -> аутентификация ->
+	// лимит (знает пользователя и API-ключ) -> RBAC.
+	unary := []grpc.UnaryServerInterceptor{middleware.UnaryMetrics(), middleware.UnaryRequestID()}
+	stream := []grpc.StreamServerInterceptor{middleware.StreamMetrics(), middleware.StreamRequestID()}
+	if access != nil {
+		unary = append(unary, middleware.UnaryAccessLog(access))
+		stream = append(stream, middleware.StreamAccessLog(access))
+	}
+	unary = append(unary, middleware.UnaryClientIP(ips))
+	stream = append(stream, middleware.StreamClientIP(ips))
+	if cfg.Auth.Enabled {
+		unary = append(unary, middleware.UnaryAuth(verifier, keys, devices, cfg.Auth.PublicGRPCMethods, authLog))
+		stream = append(stream, middleware.StreamAuth(verifier, keys, devices, cfg.Auth.PublicGRPCMethods, authLog))
+	} else {
+		logger.Warn("Authentication and RBAC disabled (AUTH_ENABLED=false)")
+	}
+	if limiter != nil {
+		unary = append(unary, middleware.UnaryRateLimit(limiter, limitLog))
+		stream = append(stream, middleware.StreamRateLimit(limiter, limitLog))
+	}
+	if cfg.Auth.Enabled {
+		unary = append(unary, middleware.UnaryAuthorize(authz, rbacLog))
+		stream = append(stream, middleware.StreamAuthorize(authz, rbacLog))
+	}
+	grpcOpts := []grpc.ServerOption{
+		grpc.MaxRecvMsgSize(50 * 1024 * 1024),
+		grpc.MaxSendMsgSize(10 * 1024 * 1024),
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	}
+	// На общем порту TLS завершает HTTP-сервер (grpc.Server.ServeHTTP не использует Creds).
+	if cfg.TLS.GRPC && !cfg.SinglePort {
+		grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(serverTLS.ServerConfig("h2"))))
+	}
+	grpcSrv := grpc.NewServer(grpcOpts...)
 	gen.RegisterVideoStreamServiceServer(grpcSrv, servers.Video)
 	gen.RegisterClientInfoServiceServer(grpcSrv, servers.ClientInfo)
 	reflection.Register(grpcSrv)
 
-	gatewayMux := runtime.NewServeMux()
+	gatewayMux := runtime.NewServeMux(
+		runtime.WithErrorHandler(gatewayErrorHandler),
+		runtime.WithMiddlewares(gatewayRoute),
+	)
 	ctx := context.Background()
 	if err := gen.RegisterVideoStreamServiceHandlerServer(ctx, gatewayMux, servers.Video); err != nil {
-		return nil, nil, nil, nil, fmt.Errorf("register video gateway: %w", err)
+		return nil, fmt.Errorf("register video gateway: %w", err)
 	}
 	if err := gen.RegisterClientInfoServiceHandlerServer(ctx, gatewayMux, servers.ClientInfo); err != nil {
-		return nil, nil, nil, nil, fmt.Errorf("register client_info gateway: %w", err)
+		return nil, fmt.Errorf("register client_info gateway: %w", err)
 	}
 
-	rateLimiter := handler.NewRateLimitState(5, time.Second)
-	rateLimited := handler.RateLimitedLimitsHandler(rateLimiter)
+	rateLimited := handler.RateLimitedLimitsHandler()
 
 	mux := http.NewServeMux()
 
@@ -95,11 +247,8 @@ func NewRouter(cfg *config.Config, logger *zap.Logger) (http.Handler, *grpc.Serv
 			"status": "ok", "service": "api-gateway", "version": "1.0.0", "time": time.Now().Unix(),
 		})
 	})
-	mux.HandleFunc("/ready", func(w http.ResponseWriter, _ *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("OK"))
-	})
 
+	mux.Handle("/metrics", metrics.Handler())
 	mux.HandleFunc("/openapi.json", serveOpenAPISpec())
 	mux.Handle("/swagger/", httpSwagger.Handler(
 		httpSwagger.URL("/openapi.json"),
@@ -107,95 +256,47 @@ func NewRouter(cfg *config.Config, logger *zap.Logger) (http.Handler, *grpc.Serv
 		httpSwagger.DocExpansion("list"),
 	))
 
+	if keys != nil {
+		handler.NewAPIKeyHandler(keys, logger.Named("admin")).Register(mux)
+	}
+	// Admin API открыт только с аутентификацией: доступ к /api/v1/admin/ ограничивает RBAC.
+	if cfg.Auth.Enabled && levels != nil {
+		handler.NewLogLevelHandler(levels, logger.Named("admin")).Register(mux)
+	}
+	if cfg.Auth.Enabled {
+		handler.NewCacheHandler(responses, logger.Named("admin")).Register(mux)
+	}
+
 	mux.HandleFunc("/v1/limits/rate-limited", rateLimited)
 	mux.HandleFunc("/api/v1/limits/rate-limited", rateLimited)
 
-	if targetURL := cfg.UserServiceHTTPURL(); targetURL != "" {
-		if u, err := url.Parse(targetURL); err == nil {
-			userProxy := newReverseProxy(u)
-			mux.Handle("/api/v1/auth/", userProxy)
-			mux.Handle("/api/v1/users/", userProxy)
-			mux.Handle("/api/v1/sessions/", userProxy)
-			// user-service: operators/available, operators/stats, operators/availability, operators/{id}/availability
-			mux.Handle("/api/v1/operators/available", userProxy)
-			mux.Handle("/api/v1/operators/available/", userProxy)
-			mux.Handle("/api/v1/operators/stats", userProxy)
-			mux.Handle("/api/v1/operators/stats/", userProxy)
-			mux.Handle("/api/v1/operators/availability", userProxy)
-			mux.Handle("/api/v1/operators/availability/", userProxy)
-			// operators/{user_id}/availability — один общий прокси для /api/v1/operators/ на user-service
-			// не регистрируем: operator-directory обрабатывает GET/POST/PUT /api/v1/operators и /api/v1/operators/{id}
-		}
+	// Прокси к backend-сервисам (единая точка входа) — декларативная таблица маршрутов.
+	sockets := proxy.NewWebSockets(logger.Named("proxy"))
+	table, err := proxy.NewTable(gw, responses, sockets, logger.Named("proxy"))
+	if err != nil {
+		return nil, fmt.Errorf("proxy routes: %w", err)
 	}
+	proxyRouter := proxy.NewRouter(table, withDeadlines(coalesce.Paths(cfg.CoalescePaths, mux),
+		time.Duration(cfg.HTTP.ReadTimeoutSec)*time.Second,
+		time.Duration(cfg.HTTP.WriteTimeoutSec)*time.Second))
 
-	// Прокси к backend-сервисам (единая точка входа).
-	// /api/v1/operators/ — operator-directory (список, по id); запросы available/stats/availability уже ушли в user-service выше.
-	if targetURL := cfg.UserServiceHTTPURL(); targetURL != "" {
-		if userURL, err := url.Parse(targetURL); err == nil && userURL.Host != "" {
-			if dirURL, err := url.Parse(cfg.OperatorDirectoryURL); err == nil && dirURL.Host != "" {
-				mux.Handle("/api/v1/operators/", operatorsRouter(userURL, dirURL))
-			} else {
-				mux.Handle("/api/v1/operators/", newReverseProxy(userURL))
-			}
-		}
-	}
-	if cfg.OperatorDirectoryURL != "" {
-		if u, err := url.Parse(cfg.OperatorDirectoryURL); err == nil && u.Host != "" {
-			// регистрируем только если user-service не задан (иначе уже зарегистрирован operatorsRouter)
-			if cfg.UserServiceHTTPURL() == "" {
-				mux.Handle("/api/v1/operators/", newReverseProxy(u))
-				mux.Handle("/api/v1/operators", newReverseProxy(u))
-			}
-		}
-	}
-	if u, err := url.Parse(cfg.SessionManagerURL); err == nil && u.Host != "" {
-		mux.Handle("/session/", newReverseProxy(u))
-	}
-	if u, err := url.Parse(cfg.TicketServiceURL); err == nil && u.Host != "" {
-		mux.Handle("/api/v1/tickets/", newReverseProxy(u))
-		mux.Handle("/api/v1/tickets", newReverseProxy(u))
-	}
-	if u, err := url.Parse(cfg.SearchServiceURL); err == nil && u.Host != "" {
-		searchProxy := newReverseProxy(u)
-		mux.Handle("/search/", searchProxy)
-		mux.Handle("/search", searchProxy)
-	}
-	// /api/v1/operators (без слэша) — operator-directory, если ещё не зарегистрирован operatorsRouter
-	if cfg.UserServiceHTTPURL() == "" && cfg.OperatorDirectoryURL != "" {
-		if u, err := url.Parse(cfg.OperatorDirectoryURL); err == nil && u.Host != "" {
-			mux.Handle("/api/v1/operators/", newReverseProxy(u))
-			mux.Handle("/api/v1/operators", newReverseProxy(u))
-		}
-	}
-	if cfg.UserServiceHTTPURL() != "" && cfg.OperatorDirectoryURL != "" {
-		if u, err := url.Parse(cfg.OperatorDirectoryURL); err == nil && u.Host != "" {
-			mux.Handle("/api/v1/operators", newReverseProxy(u))
-		}
-	}
-	if u, err := url.Parse(cfg.OperatorPoolURL); err == nil && u.Host != "" {
-		mux.Handle("/operator/", newReverseProxy(u))
-	}
-	if u, err := url.Parse(cfg.NotificationServiceURL); err == nil && u.Host != "" {
-		notifyProxy := newReverseProxy(u)
-		mux.Handle("/notify/", notifyProxy)
-		mux.Handle("/ws/notify/", notifyProxy)
-	}
-	if u, err := url.Parse(cfg.DataChannelServiceURL); err == nil && u.Host != "" {
-		dataProxy := newReverseProxy(u)
-		mux.Handle("/data/", dataProxy)
-		mux.Handle("/ws/data/", dataProxy)
-	}
+	checker, healthClosers := newHealthChecker(cfg, db, userClient, proxyRouter, logger.Named("health"))
+	closers = append(closers, healthClosers...)
+	closers = append(closers, userClient.Close)
+	mux.HandleFunc("/ready", handler.Ready(checker))
 
 	mux.HandleFunc("/api/v1/status", func(w http.ResponseWriter, _ *http.Request) {
+		endpoints := make([]string, 0, len(gatewayPaths))
+		for _, p := range gatewayPaths {
+			endpoints = append(endpoints, p+"*")
+		}
+		for _, rc := range proxyRouter.Table().Routes() {
+			endpoints = append(endpoints, rc.Path)
+		}
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]any{
 			"status": "running", "timestamp": time.Now().Unix(),
-			"endpoints": []string{
-				"/api/v1/video/*", "/api/v1/clients/*",
-				"/api/v1/auth/*", "/api/v1/tickets", "/api/v1/operators",
-				"/session/*", "/search", "/search/*", "/operator/*",
-				"/notify/*", "/ws/notify/*", "/data/*", "/ws/data/*",
-			},
+			"endpoints": endpoints,
 		})
 	})
 
@@ -245,13 +346,156 @@ func NewRouter(cfg *config.Config, logger *zap.Logger) (http.Handler, *grpc.Serv
 
 	mux.Handle("/", gatewayMux)
 
-	corsOpts := cors.Options{
-		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "PATCH", "OPTIONS"},
-		AllowedHeaders:   []string{"Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization", "accept", "origin", "Cache-Control", "X-Requested-With"},
-		AllowCredentials: true,
+	var root http.Handler = proxyRouter
+	if cfg.Auth.Enabled {
+		root = middleware.Authorize(authz, rbacLog)(root)
 	}
-	return cors.New(corsOpts).Handler(mux), grpcSrv, servers.Video, servers.ClientInfo, nil
+	if limiter != nil {
+		root = middleware.RateLimit(limiter, limitLog)(root)
+	}
+	if cfg.Auth.Enabled {
+		root = middleware.Auth(verifier, keys, cfg.Auth.PublicPaths, authLog)(root)
+	}
+	root = middleware.ClientIP(ips)(root)
+	root = middleware.CORS(corsPolicy)(root)
+	root = withRoute(proxyRouter, mux)(root)
+	root = middleware.CleanPath()(root)
+	if access != nil {
+		root = middleware.AccessLog(access)(root)
+	}
+	return &Router{
+		Handler:    tracing.Handler(middleware.Metrics()(middleware.RequestID()(root))),
+		GRPC:       grpcSrv,
+		Video:      servers.Video,
+		ClientInfo: servers.ClientInfo,
+		Proxy:      proxyRouter,
+		Authz:      authz,
+		CORS:       corsPolicy,
+		Limiter:    limiter,
+		Cache:      responses,
+		WebSockets: sockets,
+		TLS:        serverTLS,
+		Health:     checker,
+		closers:    closers,
+	}, nil
+}
+
+// newRateLimitBackend создаёт хранилище вёдер по RATE_LIMIT_BACKEND. Для Redis — отдельный клиент
+// с коротким таймаутом и без повторов: медленный Redis не должен задерживать запросы,
+// при ошибке лимит считается локально. Возвращает функцию закрытия клиента (или nil).
+func newRateLimitBackend(cfg *config.Config, logger *zap.Logger) (ratelimit.Backend, func() error) {
+	local := ratelimit.NewLocal()
+	if cfg.RateLimit.Backend != config.RateLimitBackendRedis {
+		return local, nil
+	}
+	timeout := time.Duration(cfg.RateLimit.RedisTimeoutMs) * time.Millisecond
+	rdb := redis.NewClient(&redis.Options{
+		Addr:         cfg.RedisAddr(),
+		Password:     cfg.Redis.Password,
+		DB:           cfg.Redis.DB,
+		DialTimeout:  timeout,
+		ReadTimeout:  timeout,
+		WriteTimeout: timeout,
+		MaxRetries:   -1,
+	})
+	logger.Info("Rate limits shared via Redis", zap.String("addr", cfg.RedisAddr()))
+	return ratelimit.NewRedis(rdb, local, time.Duration(cfg.RateLimit.RedisRetrySec)*time.Second, logger), rdb.Close
+}
+
+// newResponseCache создаёт кэш ответов прокси по CACHE_BACKEND. Для Redis — отдельный клиент
+// с коротким таймаутом и без повторов: при ошибке Redis запрос идёт к upstream-у.
+// Возвращает функцию закрытия клиента (или nil).
+func newResponseCache(cfg *config.Config, logger *zap.Logger) (*cache.Cache, func() error) {
+	if cfg.Cache.Backend != config.CacheBackendRedis {
+		store := cache.NewMemory(cfg.Cache.MaxEntries)
+		metrics.SetRepositorySize("cache", store.Len)
+		return cache.New(store, cfg, logger), nil
+	}
+	timeout := time.Duration(cfg.Cache.RedisTimeoutMs) * time.Millisecond
+	rdb := redis.NewClient(&redis.Options{
+		Addr:         cfg.RedisAddr(),
+		Password:     cfg.Redis.Password,
+		DB:           cfg.Redis.DB,
+		DialTimeout:  timeout,
+		ReadTimeout:  timeout,
+		WriteTimeout: timeout,
+		MaxRetries:   -1,
+	})
+	logger.Info("Response cache shared via Redis", zap.String("addr", cfg.RedisAddr()))
+	return cache.New(cache.NewRedis(rdb), cfg, logger), rdb.Close
+}
+
+// gatewayErrorHandler — стандартный обработчик ошибок grpc-gateway, дополняющий тело ошибки
+// идентификатором запроса (details: google.rpc.RequestInfo).
+func gatewayErrorHandler(ctx context.Context, mux *runtime.ServeMux, m runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	if id, ok := requestid.FromContext(r.Context()); ok {
+		// Ошибки маршрутизации (404/405) несут свой HTTP-статус — сохраняем обёртку.
+		var httpErr *runtime.HTTPStatusError
+		if errors.As(err, &httpErr) {
+			httpErr.Err = requestid.Status(httpErr.Err, id)
+		} else {
+			err = requestid.Status(err, id)
+		}
+	}
+	runtime.DefaultHTTPErrorHandler(ctx, mux, m, w, r, err)
+}
+
+// gatewayRoute запоминает шаблон пути grpc-gateway ("/api/v1/video/stats/{client_id}") для метрик и трассировки.
+func gatewayRoute(next runtime.HandlerFunc) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		if pattern, ok := runtime.HTTPPattern(r.Context()); ok {
+			// Pattern.String(): "/api/v1/video/stats/{client_id=*}".
+			reqinfo.SetRoute(r.Context(), strings.ReplaceAll(pattern.String(), "=*}", "}"))
+		}
+		next(w, r, params)
+	}
+}
+
+// withRoute запоминает шаблон маршрута до аутентификации, лимитов и RBAC, чтобы отклонённые ими
+// запросы учитывались в метриках и трассировке со своим маршрутом: путь маршрута прокси, шаблон
+// mux или поддерево grpc-gateway ("/api/v1/video/*"; полный шаблон уточняет gatewayRoute).
+func withRoute(proxyRouter *proxy.Router, mux *http.ServeMux) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if route, ok := proxyRouter.Route(r); ok {
+				reqinfo.SetRoute(r.Context(), route)
+			} else if _, pattern := mux.Handler(r); pattern != "" && pattern != "/" {
+				if _, path, ok := strings.Cut(pattern, " "); ok {
+					pattern = path
+				}
+				reqinfo.SetRoute(r.Context(), pattern)
+			} else {
+				for _, p := range gatewayPaths {
+					if strings.HasPrefix(r.URL.Path, p) {
+						reqinfo.SetRoute(r.Context(), p+"*")
+						break
+					}
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// withDeadlines ограничивает чтение запроса и запись ответа собственных handler-ов gateway
+// (прежние ReadTimeout/WriteTimeout сервера). Дедлайн записи попадает и в контекст запроса,
+// поэтому вызовы user-service из gRPC-gateway handler-ов его соблюдают.
+func withDeadlines(next http.Handler, read, write time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc := http.NewResponseController(w)
+		now := time.Now()
+		if read > 0 {
+			_ = rc.SetReadDeadline(now.Add(read))
+		}
+		if write > 0 {
+			_ = rc.SetWriteDeadline(now.Add(write))
+			defer rc.SetWriteDeadline(time.Time{})
+			ctx, cancel := context.WithTimeout(r.Context(), write)
+			defer cancel()
+			r = r.WithContext(ctx)
+		}
+		next.ServeHTTP(w, r)
+	})
 }
 
 func serveOpenAPISpec() http.HandlerFunc {
@@ -283,17 +527,3 @@ func serveOpenAPISpec() http.HandlerFunc {
 		http.Error(w, "openapi.json not found. Run: make proto-openapi", http.StatusNotFound)
 	}
 }
-
-// operatorsRouter направляет запросы: .../operators/{id}/availability — в user-service, остальные /api/v1/operators/* — в operator-directory.
-func operatorsRouter(userServiceURL, operatorDirectoryURL *url.URL) http.Handler {
-	userProxy := newReverseProxy(userServiceURL)
-	dirProxy := newReverseProxy(operatorDirectoryURL)
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		path := strings.TrimSuffix(r.URL.Path, "/")
-		if strings.HasSuffix(path, "/availability") && len(path) > len("/api/v1/operators/")+len("/availability") {
-			userProxy.ServeHTTP(w, r)
-			return
-		}
-		dirProxy.ServeHTTP(w, r)
-	})
-}
@@ -0,0 +1,245 @@
+package auth
+
+import (
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/psds-microservice/api-gateway/internal/config"
+	"go.uber.org/zap"
+)
+
+// ErrInvalidCert — клиентский сертификат не идентифицирует устройство или отозван
+// (транспортный слой отвечает codes.Unauthenticated).
+var ErrInvalidCert = errors.New("invalid client certificate")
+
+// crlCheckInterval — как часто CRL и CA проверяются на изменение.
+const crlCheckInterval = 10 * time.Second
+
+// Devices сопоставляет клиентский сертификат, проверенный TLS-стеком по TLS_CLIENT_CA_FILE,
+// с identity устройства (Config.DeviceCerts). CRL перечитывается при изменении файла
+// (проверка при вызовах, не чаще crlCheckInterval); ошибочный CRL не заменяет прежний.
+type Devices struct {
+	methods   []string
+	identity  string
+	uriPrefix string
+	roles     []string
+	scopes    []string
+	logger    *zap.Logger
+
+	crlFile string
+	caFile  string
+	revoked atomic.Pointer[map[string]bool] // issuer+серийный номер; nil — CRL не задан
+
+	nextCheck atomic.Int64 // UnixNano следующей проверки файлов
+	mu        sync.Mutex
+	modTimes  map[string]time.Time
+}
+
+// NewDevices создаёт Devices из Config.DeviceCerts. CRL читается при создании и проверяется
+// подписью CA клиентских сертификатов (TLS_CLIENT_CA_FILE).
+func NewDevices(c *config.Config, logger *zap.Logger) (*Devices, error) {
+	d := &Devices{
+		methods:   c.DeviceCerts.Methods,
+		identity:  c.DeviceCerts.Identity,
+		uriPrefix: c.DeviceCerts.URIPrefix,
+		roles:     c.DeviceCerts.Roles,
+		scopes:    c.DeviceCerts.Scopes,
+		logger:    logger,
+		crlFile:   c.DeviceCerts.CRLFile,
+		caFile:    c.TLS.Server.CAFile,
+	}
+	switch d.identity {
+	case config.DeviceCertIdentityCN:
+	case config.DeviceCertIdentityURI:
+		if d.uriPrefix == "" {
+			return nil, errors.New("uri identity requires a URI prefix")
+		}
+	default:
+		return nil, fmt.Errorf("unknown identity source %q", d.identity)
+	}
+	if d.crlFile != "" {
+		d.modTimes = d.snapshot()
+		revoked, err := d.loadCRL()
+		if err != nil {
+			return nil, fmt.Errorf("crl: %w", err)
+		}
+		d.revoked.Store(&revoked)
+		d.nextCheck.Store(time.Now().Add(crlCheckInterval).UnixNano())
+	}
+	return d, nil
+}
+
+// Applies — метод вызывается устройствами: identity берётся только из сертификата.
+func (d *Devices) Applies(method string) bool {
+	for _, p := range d.methods {
+		if strings.HasPrefix(method, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Verify возвращает identity устройства по проверенным цепочкам сертификата клиента
+// (tls.ConnectionState.VerifiedChains; пусто — сертификат не предъявлен).
+func (d *Devices) Verify(chains [][]*x509.Certificate) (*Identity, error) {
+	if len(chains) == 0 || len(chains[0]) == 0 {
+		return nil, fmt.Errorf("%w: client certificate required", ErrNoCredentials)
+	}
+	leaf := chains[0][0]
+	serial := hex.EncodeToString(leaf.SerialNumber.Bytes())
+	if d.crlFile != "" {
+		d.checkCRL()
+		if (*d.revoked.Load())[revokedKey(leaf.RawIssuer, leaf.SerialNumber.Bytes())] {
+			return nil, fmt.Errorf("%w: certificate %s is revoked", ErrInvalidCert, serial)
+		}
+	}
+	clientID := d.clientID(leaf)
+	if clientID == "" {
+		return nil, fmt.Errorf("%w: no device id in certificate %s", ErrInvalidCert, serial)
+	}
+	return &Identity{
+		Subject:    clientID,
+		Roles:      d.roles,
+		Scopes:     d.scopes,
+		CertSerial: serial,
+	}, nil
+}
+
+// clientID — CN или остаток первого SAN URI с префиксом uriPrefix.
+func (d *Devices) clientID(cert *x509.Certificate) string {
+	if d.identity == config.DeviceCertIdentityCN {
+		return cert.Subject.CommonName
+	}
+	for _, u := range cert.URIs {
+		if id, ok := strings.CutPrefix(u.String(), d.uriPrefix); ok && id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+func revokedKey(issuer, serial []byte) string {
+	return string(issuer) + "\x00" + string(serial)
+}
+
+// loadCRL читает CRL (один или несколько, PEM или DER) и проверяет подпись каждого CA
+// из caFile с тем же subject.
+func (d *Devices) loadCRL() (map[string]bool, error) {
+	cas, err := readCerts(d.caFile)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(d.crlFile)
+	if err != nil {
+		return nil, err
+	}
+	var ders [][]byte
+	for rest := data; ; {
+		var b *pem.Block
+		if b, rest = pem.Decode(rest); b == nil {
+			break
+		}
+		if b.Type == "X509 CRL" {
+			ders = append(ders, b.Bytes)
+		}
+	}
+	if len(ders) == 0 {
+		ders = [][]byte{data}
+	}
+	revoked := make(map[string]bool)
+	for _, der := range ders {
+		crl, err := x509.ParseRevocationList(der)
+		if err != nil {
+			return nil, err
+		}
+		if !signedByAny(crl, cas) {
+			return nil, fmt.Errorf("%s: not signed by a client CA", d.crlFile)
+		}
+		if !crl.NextUpdate.IsZero() && time.Now().After(crl.NextUpdate) {
+			d.logger.Warn("Device CRL is past its next update", zap.String("file", d.crlFile), zap.Time("next_update", crl.NextUpdate))
+		}
+		for _, e := range crl.RevokedCertificateEntries {
+			revoked[revokedKey(crl.RawIssuer, e.SerialNumber.Bytes())] = true
+		}
+	}
+	return revoked, nil
+}
+
+func signedByAny(crl *x509.RevocationList, cas []*x509.Certificate) bool {
+	for _, ca := range cas {
+		if string(ca.RawSubject) == string(crl.RawIssuer) && crl.CheckSignatureFrom(ca) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// readCerts читает сертификаты из PEM-файла.
+func readCerts(path string) ([]*x509.Certificate, error) {
+	if path == "" {
+		return nil, errors.New("client CA file is not set")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var out []*x509.Certificate
+	for {
+		var b *pem.Block
+		if b, data = pem.Decode(data); b == nil {
+			break
+		}
+		if b.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(b.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, cert)
+	}
+	return out, nil
+}
+
+// checkCRL перечитывает CRL, если с прошлой проверки прошло crlCheckInterval и файлы изменились.
+func (d *Devices) checkCRL() {
+	now := time.Now()
+	next := d.nextCheck.Load()
+	if now.UnixNano() < next || !d.nextCheck.CompareAndSwap(next, now.Add(crlCheckInterval).UnixNano()) {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	modTimes := d.snapshot()
+	if maps.EqualFunc(modTimes, d.modTimes, time.Time.Equal) {
+		return
+	}
+	d.modTimes = modTimes
+	revoked, err := d.loadCRL()
+	if err != nil {
+		d.logger.Error("Device CRL reload failed, keeping current list", zap.String("file", d.crlFile), zap.Error(err))
+		return
+	}
+	d.revoked.Store(&revoked)
+	d.logger.Info("Device CRL reloaded", zap.String("file", d.crlFile), zap.Int("revoked", len(revoked)))
+}
+
+// snapshot возвращает mtime CRL и CA (отсутствующие файлы не попадают в карту).
+func (d *Devices) snapshot() map[string]time.Time {
+	out := make(map[string]time.Time, 2)
+	for _, f := range []string{d.crlFile, d.caFile} {
+		if st, err := os.Stat(f); err == nil {
+			out[f] = st.ModTime()
+		}
+	}
+	return out
+}
@@ -0,0 +1,46 @@
+// Package auth — проверка учётных данных вызывающего и его identity в контексте запроса.
+package auth
+
+import (
+	"context"
+	"slices"
+)
+
+// Identity — проверенный вызывающий: subject токена (или client_id API-ключа / сертификата
+// устройства) и его роли (pkg/constants Role*).
+type Identity struct {
+	Subject string
+	Roles   []string
+	// Scopes ограничивают разрешения ролей (API-ключ); nil — без ограничений.
+	Scopes []string
+	// KeyID — id API-ключа, которым аутентифицирован вызов; "" — JWT.
+	KeyID string
+	// CertSerial — серийный номер (hex) клиентского сертификата устройства; "" — не сертификат.
+	CertSerial string
+}
+
+// HasRole — у вызывающего есть роль.
+func (id *Identity) HasRole(role string) bool {
+	return id != nil && slices.Contains(id.Roles, role)
+}
+
+type identityKey struct{}
+
+// WithIdentity возвращает контекст с identity вызывающего.
+func WithIdentity(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// FromContext возвращает identity, положенную middleware аутентификации.
+func FromContext(ctx context.Context) (*Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(*Identity)
+	return id, ok && id != nil
+}
+
+// Subject возвращает subject вызывающего или "" для анонимного запроса.
+func Subject(ctx context.Context) string {
+	if id, ok := FromContext(ctx); ok {
+		return id.Subject
+	}
+	return ""
+}
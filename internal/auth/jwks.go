@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// jwk — открытый ключ из JWKS (RFC 7517): RSA (n, e) или EC P-256 (x, y).
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// keySet — ключи JWKS по kid.
+type keySet struct {
+	rsa map[string]*rsa.PublicKey
+	ec  map[string]*ecdsa.PublicKey
+}
+
+func loadKeySet(path string) (*keySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	ks := &keySet{rsa: make(map[string]*rsa.PublicKey), ec: make(map[string]*ecdsa.PublicKey)}
+	var errs []error
+	for i, k := range doc.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		switch k.Kty {
+		case "RSA":
+			pub, err := k.rsaKey()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("keys[%d] %q: %w", i, k.Kid, err))
+				continue
+			}
+			ks.rsa[k.Kid] = pub
+		case "EC":
+			pub, err := k.ecKey()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("keys[%d] %q: %w", i, k.Kid, err))
+				continue
+			}
+			ks.ec[k.Kid] = pub
+		default:
+			errs = append(errs, fmt.Errorf("keys[%d] %q: unsupported kty %q", i, k.Kid, k.Kty))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	if len(ks.rsa)+len(ks.ec) == 0 {
+		return nil, fmt.Errorf("%s: no signing keys", path)
+	}
+	return ks, nil
+}
+
+// lookup возвращает ключ для alg по kid. Без kid допускается единственный ключ нужного типа.
+func (ks *keySet) lookup(kid, alg string) (any, error) {
+	switch alg {
+	case "RS256":
+		return pick(ks.rsa, kid)
+	case "ES256":
+		return pick(ks.ec, kid)
+	}
+	return nil, fmt.Errorf("unsupported alg %s", alg)
+}
+
+func pick[K any](keys map[string]K, kid string) (any, error) {
+	if k, ok := keys[kid]; ok {
+		return k, nil
+	}
+	if kid == "" && len(keys) == 1 {
+		for _, k := range keys {
+			return k, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+func (k jwk) rsaKey() (*rsa.PublicKey, error) {
+	n, err := decodeB64(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("n: %w", err)
+	}
+	e, err := decodeB64(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("e: %w", err)
+	}
+	pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	if pub.N.BitLen() < 2048 {
+		return nil, errors.New("rsa key shorter than 2048 bits")
+	}
+	return pub, nil
+}
+
+func (k jwk) ecKey() (*ecdsa.PublicKey, error) {
+	if k.Crv != "P-256" {
+		return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+	}
+	x, err := decodeB64(k.X)
+	if err != nil {
+		return nil, fmt.Errorf("x: %w", err)
+	}
+	y, err := decodeB64(k.Y)
+	if err != nil {
+		return nil, fmt.Errorf("y: %w", err)
+	}
+	if len(x) > 32 || len(y) > 32 {
+		return nil, errors.New("invalid P-256 coordinates")
+	}
+	// Несжатая точка (0x04 || X || Y): ParseUncompressedPublicKey проверяет, что она на кривой.
+	point := make([]byte, 65)
+	point[0] = 4
+	copy(point[33-len(x):33], x)
+	copy(point[65-len(y):], y)
+	return ecdsa.ParseUncompressedPublicKey(elliptic.P256(), point)
+}
+
+func decodeB64(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/psds-microservice/api-gateway/internal/config"
+)
+
+// Ошибки аутентификации (транспортный слой отвечает на них 401 / codes.Unauthenticated).
+var (
+	ErrNoCredentials = errors.New("missing credentials")
+	ErrInvalidToken  = errors.New("invalid token")
+)
+
+// clockSkew — допустимое расхождение часов при проверке exp/nbf/iat.
+const clockSkew = 30 * time.Second
+
+// placeholderSecrets — значения JWT_SECRET из примеров конфигурации: они публичны, и токены,
+// подписанные ими, может выпустить кто угодно.
+var placeholderSecrets = []string{"change-me-in-production", "your-secret-key-change-in-production"}
+
+// claims — поддерживаемые claims токена. Роли — массив "roles" или строка "role".
+type claims struct {
+	jwt.RegisteredClaims
+	Roles []string `json:"roles,omitempty"`
+	Role  string   `json:"role,omitempty"`
+}
+
+// Verifier проверяет JWT: HS256 с JWT_SECRET, RS256/ES256 с ключами из JWT_JWKS_FILE.
+type Verifier struct {
+	secret   []byte
+	keys     *keySet // nil — асимметричные токены не принимаются
+	maxAge   time.Duration
+	issuer   string
+	audience string
+}
+
+// NewVerifier создаёт Verifier из Config.JWT. JWKS-файл читается при создании. HS256 принимается
+// только с заданным JWT_SECRET; при включённой аутентификации секрет-заглушка из примеров или
+// отсутствие и секрета, и JWKS — ошибка.
+func NewVerifier(c *config.Config) (*Verifier, error) {
+	if c.Auth.Enabled && slices.Contains(placeholderSecrets, c.JWT.Secret) {
+		return nil, errors.New("AUTH_ENABLED=true: JWT_SECRET is a placeholder value, set a real secret or JWT_JWKS_FILE")
+	}
+	v := &Verifier{
+		maxAge:   time.Duration(c.JWT.Expiration) * time.Hour,
+		issuer:   c.JWT.Issuer,
+		audience: c.JWT.Audience,
+	}
+	if c.JWT.JWKSFile != "" {
+		keys, err := loadKeySet(c.JWT.JWKSFile)
+		if err != nil {
+			return nil, fmt.Errorf("jwks: %w", err)
+		}
+		v.keys = keys
+	}
+	if c.JWT.Secret != "" && !slices.Contains(placeholderSecrets, c.JWT.Secret) {
+		v.secret = []byte(c.JWT.Secret)
+	}
+	if c.Auth.Enabled && v.secret == nil && v.keys == nil {
+		return nil, errors.New("AUTH_ENABLED=true requires JWT_SECRET or JWT_JWKS_FILE")
+	}
+	return v, nil
+}
+
+// Verify проверяет токен и возвращает identity. Токен без exp отклоняется; если задан
+// JWT_EXPIRATION, отклоняются и токены со сроком жизни (exp - iat) больше него.
+func (v *Verifier) Verify(token string) (*Identity, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"HS256", "RS256", "ES256"}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockSkew),
+	}
+	if v.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(v.issuer))
+	}
+	if v.audience != "" {
+		opts = append(opts, jwt.WithAudience(v.audience))
+	}
+	var cl claims
+	if _, err := jwt.ParseWithClaims(token, &cl, v.key, opts...); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if cl.Subject == "" {
+		return nil, fmt.Errorf("%w: empty subject", ErrInvalidToken)
+	}
+	if v.maxAge > 0 && cl.IssuedAt != nil && cl.ExpiresAt.Sub(cl.IssuedAt.Time) > v.maxAge+clockSkew {
+		return nil, fmt.Errorf("%w: lifetime exceeds %s", ErrInvalidToken, v.maxAge)
+	}
+	roles := cl.Roles
+	if cl.Role != "" && len(roles) == 0 {
+		roles = []string{cl.Role}
+	}
+	return &Identity{Subject: cl.Subject, Roles: roles}, nil
+}
+
+func (v *Verifier) key(t *jwt.Token) (any, error) {
+	switch t.Method.Alg() {
+	case "HS256":
+		if len(v.secret) == 0 {
+			return nil, errors.New("hs256 is not configured")
+		}
+		return v.secret, nil
+	default:
+		if v.keys == nil {
+			return nil, fmt.Errorf("%s requires JWT_JWKS_FILE", t.Method.Alg())
+		}
+		kid, _ := t.Header["kid"].(string)
+		return v.keys.lookup(kid, t.Method.Alg())
+	}
+}
+
+// BearerToken извлекает токен из значения заголовка Authorization ("Bearer <token>").
+func BearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
@@ -0,0 +1,370 @@
+// Package cache — HTTP-кэш GET-ответов маршрутов прокси: свежесть по Cache-Control / Expires,
+// перепроверка по ETag / Last-Modified, варианты по Vary. Хранилище — LRU в памяти реплики
+// или Redis (общий для реплик).
+package cache
+
+import (
+	"bytes"
+	"context"
+	"maps"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/psds-microservice/api-gateway/internal/auth"
+	"github.com/psds-microservice/api-gateway/internal/capture"
+	"github.com/psds-microservice/api-gateway/internal/config"
+	"github.com/psds-microservice/api-gateway/internal/metrics"
+	"github.com/psds-microservice/api-gateway/internal/requestid"
+	"github.com/psds-microservice/api-gateway/pkg/constants"
+	"go.uber.org/zap"
+)
+
+// Результаты кэша (заголовок X-Cache; в метрике — в нижнем регистре).
+const (
+	ResultHit         = "HIT"
+	ResultMiss        = "MISS"
+	ResultRevalidated = "REVALIDATED"
+	ResultBypass      = "BYPASS"
+)
+
+// cacheableStatuses — статусы, ответы с которыми можно сохранять (RFC 9110, 15.1).
+var cacheableStatuses = map[int]bool{
+	http.StatusOK: true, http.StatusNonAuthoritativeInfo: true, http.StatusNoContent: true,
+	http.StatusMovedPermanently: true, http.StatusPermanentRedirect: true,
+	http.StatusNotFound: true, http.StatusGone: true,
+}
+
+// Cache — кэш ответов поверх Store. Один экземпляр обслуживает все маршруты с options.cache
+// и переживает перезагрузку таблицы маршрутов.
+type Cache struct {
+	store   Store
+	maxBody int
+	stale   time.Duration
+	logger  *zap.Logger
+}
+
+// New создаёт кэш по CACHE_* поверх store.
+func New(store Store, cfg *config.Config, logger *zap.Logger) *Cache {
+	return &Cache{
+		store:   store,
+		maxBody: cfg.Cache.MaxBodyBytes,
+		stale:   time.Duration(cfg.Cache.StaleSec) * time.Second,
+		logger:  logger,
+	}
+}
+
+// Purge удаляет записи, путь запроса которых начинается с prefix ("" — весь кэш).
+func (c *Cache) Purge(ctx context.Context, prefix string) (int, error) {
+	return c.store.Purge(ctx, prefix)
+}
+
+// Handler кэширует GET-ответы next для маршрута route. Успешный небезопасный запрос
+// (POST, PUT, PATCH, DELETE) удаляет из кэша записи своего пути.
+func (c *Cache) Handler(route string, rc *config.CacheConfig, next http.Handler) http.Handler {
+	ttl := time.Duration(rc.TTLSec) * time.Second
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		default:
+			c.invalidating(w, r, next)
+			return
+		}
+		reqCC := directives(r.Header)
+		scope, shared := scopeOf(r)
+		key, cacheable := cacheKey(r, scope)
+		if _, noStore := reqCC["no-store"]; noStore || !cacheable || r.Header.Get("Range") != "" ||
+			r.Header.Get("Upgrade") != "" ||
+			// Учётные данные есть, но вызывающий не аутентифицирован (публичный путь):
+			// ответ может зависеть от них, а общий кэш их не различает.
+			(shared && (r.Header.Get(constants.HeaderAuthorization) != "" || r.Header.Get(constants.HeaderAPIKey) != "")) {
+			w.Header().Set(constants.HeaderCache, ResultBypass)
+			metrics.CacheRequests.WithLabelValues(route, strings.ToLower(ResultBypass)).Inc()
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := context.WithoutCancel(r.Context())
+		e, err := c.store.Get(ctx, key)
+		if err != nil {
+			requestid.Logger(r.Context(), c.logger).Debug("Cache read failed", zap.String("route", route), zap.Error(err))
+			metrics.CacheRequests.WithLabelValues(route, "error").Inc()
+			next.ServeHTTP(w, r)
+			return
+		}
+		if e != nil && !e.matches(r) {
+			e = nil
+		}
+		now := time.Now()
+		w.Header().Set(constants.HeaderCache, ResultMiss)
+		pre := w.Header().Clone()
+		if e != nil && !mustRevalidate(reqCC, r.Header) && now.Before(e.Expires) {
+			c.serve(w, r, pre, e, now, ResultHit, route)
+			return
+		}
+
+		// Устаревший ответ с валидаторами перепроверяется условным запросом; условный запрос
+		// самого клиента передаётся как есть.
+		out := r
+		conditional := e != nil && e.hasValidators() && !isConditional(r)
+		if conditional {
+			out = r.Clone(r.Context())
+			if etag := e.Header.Get("ETag"); etag != "" {
+				out.Header.Set("If-None-Match", etag)
+			}
+			if lm := e.Header.Get("Last-Modified"); lm != "" {
+				out.Header.Set("If-Modified-Since", lm)
+			}
+		}
+		rec := &capture.Recorder{ResponseWriter: w, Limit: c.maxBody, Intercept: conditional}
+		next.ServeHTTP(rec, out)
+		if rec.NotModified {
+			fresh := e.refresh(capture.WithoutHeaders(rec.SentHeader, pre), shared, ttl, now)
+			c.put(ctx, r, route, key, fresh, c.retention(fresh, now))
+			c.serve(w, r, pre, fresh, now, ResultRevalidated, route)
+			return
+		}
+		metrics.CacheRequests.WithLabelValues(route, strings.ToLower(ResultMiss)).Inc()
+		if e, ok := c.entry(r, rec, pre, shared, ttl, now); ok {
+			c.put(ctx, r, route, key, e, c.retention(e, now))
+		}
+	})
+}
+
+// invalidating передаёт небезопасный запрос next и после успешного ответа удаляет записи его пути.
+func (c *Cache) invalidating(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	rec := &capture.Recorder{ResponseWriter: w}
+	next.ServeHTTP(rec, r)
+	if rec.StatusCode >= http.StatusBadRequest {
+		return
+	}
+	if _, err := c.store.Purge(context.WithoutCancel(r.Context()), r.URL.Path+"?"); err != nil {
+		requestid.Logger(r.Context(), c.logger).Warn("Cache invalidation failed", zap.String("path", r.URL.Path), zap.Error(err))
+	}
+}
+
+func (c *Cache) put(ctx context.Context, r *http.Request, route, key string, e *Entry, ttl time.Duration) {
+	if err := c.store.Set(ctx, key, e, ttl); err != nil {
+		requestid.Logger(r.Context(), c.logger).Debug("Cache write failed", zap.String("route", route), zap.Error(err))
+		metrics.CacheRequests.WithLabelValues(route, "error").Inc()
+	}
+}
+
+// retention — сколько хранить запись: время свежести и, при наличии валидаторов, CACHE_STALE_SEC
+// для условных запросов.
+func (c *Cache) retention(e *Entry, now time.Time) time.Duration {
+	d := e.Expires.Sub(now)
+	if e.hasValidators() {
+		d += c.stale
+	}
+	return d
+}
+
+// serve отвечает сохранённым ответом (или 304 на условный запрос клиента). pre — заголовки,
+// выставленные gateway до обращения к upstream-у (request ID, лимиты, CORS).
+func (c *Cache) serve(w http.ResponseWriter, r *http.Request, pre http.Header, e *Entry, now time.Time, result, route string) {
+	h := w.Header()
+	clear(h)
+	maps.Copy(h, pre)
+	for k, vs := range e.Header {
+		h[k] = append(h[k], vs...)
+	}
+	h.Set("Age", strconv.Itoa(int(max(now.Sub(e.Stored), 0).Seconds())))
+	h.Set(constants.HeaderCache, result)
+	metrics.CacheRequests.WithLabelValues(route, strings.ToLower(result)).Inc()
+	if e.Status == http.StatusOK && notModified(r, e) {
+		h.Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.WriteHeader(e.Status)
+	w.Write(e.Body)
+}
+
+// entry строит запись из ответа upstream-а, если его можно сохранить.
+func (c *Cache) entry(r *http.Request, rec *capture.Recorder, pre http.Header, shared bool, ttl time.Duration, now time.Time) (*Entry, bool) {
+	status := rec.StatusCode
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if rec.Overflow || !cacheableStatuses[status] {
+		return nil, false
+	}
+	h := capture.WithoutHeaders(rec.SentHeader, pre)
+	if h.Get("Set-Cookie") != "" {
+		return nil, false
+	}
+	cc := directives(h)
+	if _, ok := cc["no-store"]; ok {
+		return nil, false
+	}
+	if _, ok := cc["private"]; ok && shared {
+		return nil, false
+	}
+	vary := make(map[string]string)
+	for _, v := range h.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			name = strings.TrimSpace(name)
+			if name == "*" {
+				return nil, false
+			}
+			if name != "" {
+				vary[http.CanonicalHeaderKey(name)] = r.Header.Get(name)
+			}
+		}
+	}
+	// Сжатый ответ не должен достаться клиенту без поддержки сжатия, даже если upstream забыл Vary.
+	if h.Get("Content-Encoding") != "" {
+		vary["Accept-Encoding"] = r.Header.Get("Accept-Encoding")
+	}
+	e := &Entry{Status: status, Header: h, Body: bytes.Clone(rec.Body.Bytes()), Vary: vary}
+	e = e.refresh(nil, shared, ttl, now)
+	if !e.Expires.After(now) && !e.hasValidators() {
+		return nil, false
+	}
+	return e, true
+}
+
+// refresh возвращает копию записи с заголовками, дополненными update (ответ 304), и временем
+// свежести, отсчитанным от now.
+func (e *Entry) refresh(update http.Header, shared bool, ttl time.Duration, now time.Time) *Entry {
+	h := e.Header.Clone()
+	for k, vs := range update {
+		if k != "Content-Length" {
+			h[k] = vs
+		}
+	}
+	fresh := freshness(h, shared, ttl, now)
+	h.Del("Age")
+	return &Entry{Status: e.Status, Header: h, Body: e.Body, Stored: now, Expires: now.Add(fresh), Vary: e.Vary}
+}
+
+// freshness — время свежести ответа: s-maxage (общий кэш), max-age, Expires или ttl маршрута;
+// за вычетом Age ответа.
+func freshness(h http.Header, shared bool, ttl time.Duration, now time.Time) time.Duration {
+	cc := directives(h)
+	if _, ok := cc["no-cache"]; ok {
+		return 0
+	}
+	fresh := ttl
+	if v, ok := seconds(cc, "s-maxage"); ok && shared {
+		fresh = v
+	} else if v, ok := seconds(cc, "max-age"); ok {
+		fresh = v
+	} else if exp := h.Get("Expires"); exp != "" {
+		t, err := http.ParseTime(exp)
+		if err != nil {
+			return 0 // некорректный Expires означает "уже устарел"
+		}
+		date := now
+		if d, err := http.ParseTime(h.Get("Date")); err == nil {
+			date = d
+		}
+		fresh = t.Sub(date)
+	}
+	if age, err := strconv.Atoi(h.Get("Age")); err == nil && age > 0 {
+		fresh -= time.Duration(age) * time.Second
+	}
+	return max(fresh, 0)
+}
+
+func (e *Entry) hasValidators() bool {
+	return e.Header.Get("ETag") != "" || e.Header.Get("Last-Modified") != ""
+}
+
+// matches — запрос совпадает с записью по заголовкам из Vary.
+func (e *Entry) matches(r *http.Request) bool {
+	for name, v := range e.Vary {
+		if r.Header.Get(name) != v {
+			return false
+		}
+	}
+	return true
+}
+
+// scopeOf — область кэша: для аутентифицированного вызывающего — его subject (частный кэш),
+// иначе общая (shared = true).
+func scopeOf(r *http.Request) (scope string, shared bool) {
+	if id, ok := auth.FromContext(r.Context()); ok {
+		return "user:" + id.Subject, false
+	}
+	return "", true
+}
+
+// cacheKey — путь, отсортированный query и область кэша. false — query не разбирается.
+func cacheKey(r *http.Request, scope string) (string, bool) {
+	q, err := url.ParseQuery(r.URL.RawQuery)
+	if err != nil {
+		return "", false
+	}
+	return r.URL.Path + "?" + q.Encode() + "\x00" + scope, true
+}
+
+// directives разбирает Cache-Control: имя директивы в нижнем регистре -> значение.
+func directives(h http.Header) map[string]string {
+	out := make(map[string]string)
+	for _, v := range h.Values("Cache-Control") {
+		for _, d := range strings.Split(v, ",") {
+			name, val, _ := strings.Cut(strings.TrimSpace(d), "=")
+			if name != "" {
+				out[strings.ToLower(name)] = strings.Trim(val, `"`)
+			}
+		}
+	}
+	return out
+}
+
+func seconds(cc map[string]string, name string) (time.Duration, bool) {
+	v, ok := cc[name]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, true
+	}
+	return time.Duration(n) * time.Second, true
+}
+
+// mustRevalidate — клиент требует проверки у upstream-а (no-cache, max-age=0, Pragma: no-cache).
+func mustRevalidate(cc map[string]string, h http.Header) bool {
+	if _, ok := cc["no-cache"]; ok {
+		return true
+	}
+	if v, ok := seconds(cc, "max-age"); ok && v == 0 {
+		return true
+	}
+	return len(cc) == 0 && strings.Contains(strings.ToLower(h.Get("Pragma")), "no-cache")
+}
+
+func isConditional(r *http.Request) bool {
+	return r.Header.Get("If-None-Match") != "" || r.Header.Get("If-Modified-Since") != ""
+}
+
+// notModified — условный запрос клиента совпал с сохранённым ответом.
+func notModified(r *http.Request, e *Entry) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		etag := strings.TrimPrefix(e.Header.Get("ETag"), "W/")
+		if etag == "" {
+			return false
+		}
+		for _, t := range strings.Split(inm, ",") {
+			t = strings.TrimSpace(t)
+			if t == "*" || strings.TrimPrefix(t, "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+	ims, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	lm, err := http.ParseTime(e.Header.Get("Last-Modified"))
+	return err == nil && !lm.After(ims)
+}
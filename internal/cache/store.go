@@ -0,0 +1,185 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Entry — сохранённый ответ upstream-а.
+type Entry struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+	// Stored — когда ответ получен (для заголовка Age).
+	Stored time.Time `json:"stored"`
+	// Expires — до какого момента ответ свежий; после — только условный запрос к upstream-у.
+	Expires time.Time `json:"expires"`
+	// Vary — значения заголовков запроса, перечисленных в Vary ответа.
+	Vary map[string]string `json:"vary,omitempty"`
+}
+
+// Store — хранилище записей кэша. Ключи начинаются с пути запроса, поэтому Purge по префиксу
+// пути удаляет все варианты (query, вызывающие).
+type Store interface {
+	// Get возвращает запись или nil, если её нет.
+	Get(ctx context.Context, key string) (*Entry, error)
+	// Set сохраняет запись на ttl.
+	Set(ctx context.Context, key string, e *Entry, ttl time.Duration) error
+	// Purge удаляет записи с ключами, начинающимися с prefix ("" — все), и возвращает их число.
+	Purge(ctx context.Context, prefix string) (int, error)
+}
+
+// Memory — LRU в памяти процесса (кэш одной реплики gateway).
+type Memory struct {
+	max int
+
+	mu    sync.Mutex
+	ll    *list.List // от недавно использованных к давно
+	items map[string]*list.Element
+}
+
+type memoryItem struct {
+	key      string
+	entry    *Entry
+	deadline time.Time
+}
+
+// NewMemory создаёт LRU на maxEntries записей (не меньше одной).
+func NewMemory(maxEntries int) *Memory {
+	return &Memory{max: max(maxEntries, 1), ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+// Get возвращает запись, если её срок хранения не истёк.
+func (m *Memory) Get(_ context.Context, key string) (*Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	el, ok := m.items[key]
+	if !ok {
+		return nil, nil
+	}
+	it := el.Value.(*memoryItem)
+	if !time.Now().Before(it.deadline) {
+		m.remove(el)
+		return nil, nil
+	}
+	m.ll.MoveToFront(el)
+	return it.entry, nil
+}
+
+// Set сохраняет запись, вытесняя давно не использованные при переполнении.
+func (m *Memory) Set(_ context.Context, key string, e *Entry, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	it := &memoryItem{key: key, entry: e, deadline: time.Now().Add(ttl)}
+	if el, ok := m.items[key]; ok {
+		el.Value = it
+		m.ll.MoveToFront(el)
+		return nil
+	}
+	m.items[key] = m.ll.PushFront(it)
+	for m.ll.Len() > m.max {
+		m.remove(m.ll.Back())
+	}
+	return nil
+}
+
+// Purge удаляет записи по префиксу ключа.
+func (m *Memory) Purge(_ context.Context, prefix string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for key, el := range m.items {
+		if strings.HasPrefix(key, prefix) {
+			m.remove(el)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// Len — число записей (включая ещё не удалённые просроченные).
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.ll.Len()
+}
+
+func (m *Memory) remove(el *list.Element) {
+	m.ll.Remove(el)
+	delete(m.items, el.Value.(*memoryItem).key)
+}
+
+// redisKeyPrefix — префикс ключей кэша в Redis.
+const redisKeyPrefix = "api_gateway:cache:"
+
+// purgeBatch — сколько ключей Purge запрашивает за один SCAN и удаляет за один DEL.
+const purgeBatch = 500
+
+// Redis — кэш в Redis, общий для всех реплик gateway; срок хранения — TTL ключа.
+type Redis struct {
+	client *redis.Client
+}
+
+// NewRedis создаёт Store поверх client.
+func NewRedis(client *redis.Client) *Redis {
+	return &Redis{client: client}
+}
+
+// Get читает запись (JSON).
+func (r *Redis) Get(ctx context.Context, key string) (*Entry, error) {
+	data, err := r.client.Get(ctx, redisKeyPrefix+key).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	e := &Entry{}
+	if err := json.Unmarshal(data, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Set сохраняет запись с TTL.
+func (r *Redis) Set(ctx context.Context, key string, e *Entry, ttl time.Duration) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, redisKeyPrefix+key, data, ttl).Err()
+}
+
+// Purge удаляет записи по префиксу ключа (SCAN + DEL).
+func (r *Redis) Purge(ctx context.Context, prefix string) (int, error) {
+	match := redisKeyPrefix + globEscaper.Replace(prefix) + "*"
+	n := 0
+	var cursor uint64
+	for {
+		keys, next, err := r.client.Scan(ctx, cursor, match, purgeBatch).Result()
+		if err != nil {
+			return n, err
+		}
+		if len(keys) > 0 {
+			deleted, err := r.client.Del(ctx, keys...).Result()
+			if err != nil {
+				return n, err
+			}
+			n += int(deleted)
+		}
+		if next == 0 {
+			return n, nil
+		}
+		cursor = next
+	}
+}
+
+// globEscaper экранирует спецсимволы шаблона MATCH в префиксе.
+var globEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
@@ -0,0 +1,81 @@
+// Package capture — запись ответа, который передаётся клиенту, для повторной отдачи:
+// статус, заголовки и тело (кэш ответов, объединение одинаковых запросов).
+package capture
+
+import (
+	"bytes"
+	"net/http"
+	"slices"
+)
+
+// Recorder передаёт ответ клиенту и запоминает статус, заголовки и тело (до Limit байт).
+// С Intercept ответ 304 на условный запрос gateway клиенту не передаётся.
+type Recorder struct {
+	http.ResponseWriter
+	Limit     int
+	Intercept bool
+
+	StatusCode  int
+	SentHeader  http.Header // заголовки на момент WriteHeader
+	Body        bytes.Buffer
+	Overflow    bool // тело больше Limit — не сохраняется
+	NotModified bool // перехвачен 304
+}
+
+func (r *Recorder) WriteHeader(code int) {
+	if code < http.StatusOK || r.StatusCode != 0 {
+		r.ResponseWriter.WriteHeader(code)
+		return
+	}
+	r.StatusCode = code
+	r.SentHeader = r.ResponseWriter.Header().Clone()
+	if r.Intercept && code == http.StatusNotModified {
+		r.NotModified = true
+		return
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *Recorder) Write(b []byte) (int, error) {
+	if r.StatusCode == 0 {
+		r.WriteHeader(http.StatusOK)
+	}
+	if r.NotModified {
+		return len(b), nil
+	}
+	if !r.Overflow {
+		if r.Body.Len()+len(b) > r.Limit {
+			r.Overflow = true
+			r.Body = bytes.Buffer{}
+		} else {
+			r.Body.Write(b)
+		}
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *Recorder) Flush() {
+	if r.NotModified {
+		return
+	}
+	_ = http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+func (r *Recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// WithoutHeaders возвращает заголовки ответа h без значений, выставленных gateway до
+// обработчика (pre): их gateway выставит заново при повторной отдаче.
+func WithoutHeaders(h, pre http.Header) http.Header {
+	out := make(http.Header, len(h))
+	for k, vs := range h {
+		if pv := pre[k]; len(pv) > 0 && len(vs) >= len(pv) && slices.Equal(vs[:len(pv)], pv) {
+			vs = vs[len(pv):]
+		}
+		if len(vs) > 0 {
+			out[k] = slices.Clone(vs)
+		}
+	}
+	return out
+}
@@ -0,0 +1,60 @@
+package capture
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	w := httptest.NewRecorder()
+	rec := &Recorder{ResponseWriter: w, Limit: 5}
+	rec.Header().Set("X-Upstream", "a")
+	rec.Write([]byte("abc"))
+	rec.Header().Set("X-Late", "b") // после WriteHeader не сохраняется
+	if rec.StatusCode != http.StatusOK || rec.Body.String() != "abc" || rec.SentHeader.Get("X-Late") != "" {
+		t.Fatalf("status %d, body %q, header %v", rec.StatusCode, rec.Body.String(), rec.SentHeader)
+	}
+	rec.Write([]byte("def"))
+	if !rec.Overflow || rec.Body.Len() != 0 {
+		t.Fatalf("overflow %v, body %q", rec.Overflow, rec.Body.String())
+	}
+	if w.Body.String() != "abcdef" {
+		t.Fatalf("client got %q", w.Body.String())
+	}
+}
+
+func TestRecorderIntercept(t *testing.T) {
+	w := httptest.NewRecorder()
+	rec := &Recorder{ResponseWriter: w, Limit: 5, Intercept: true}
+	rec.WriteHeader(http.StatusNotModified)
+	rec.Write([]byte("x"))
+	if !rec.NotModified || w.Code != http.StatusOK || w.Body.Len() != 0 || w.Flushed {
+		t.Fatalf("304 reached the client: code %d, body %q", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	rec = &Recorder{ResponseWriter: w, Limit: 5}
+	rec.WriteHeader(http.StatusNotModified)
+	if rec.NotModified || w.Code != http.StatusNotModified {
+		t.Fatalf("304 intercepted without Intercept: code %d", w.Code)
+	}
+}
+
+func TestWithoutHeaders(t *testing.T) {
+	pre := http.Header{"Vary": {"Origin"}, "X-Request-Id": {"r1"}}
+	h := http.Header{
+		"Vary":         {"Origin", "Accept-Encoding"},
+		"X-Request-Id": {"r1"},
+		"Content-Type": {"application/json"},
+	}
+	got := WithoutHeaders(h, pre)
+	if len(got) != 2 || !slices.Equal(got["Vary"], []string{"Accept-Encoding"}) || got.Get("Content-Type") != "application/json" {
+		t.Fatalf("got %v", got)
+	}
+	got["Content-Type"][0] = "text/plain"
+	if h.Get("Content-Type") != "application/json" {
+		t.Fatal("result shares values with h")
+	}
+}
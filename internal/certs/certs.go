@@ -0,0 +1,235 @@
+// Package certs — TLS-конфигурации сервера и клиентов из PEM-файлов (config.TLSConfig).
+// Файлы проверяются на изменение (mtime) при установке соединений, не чаще CheckInterval:
+// новые соединения получают перечитанные сертификат, ключ и CA, установленные не прерываются.
+package certs
+
+import (
+	"cmp"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/psds-microservice/api-gateway/internal/config"
+	"github.com/psds-microservice/api-gateway/internal/metrics"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/credentials"
+)
+
+// CheckInterval — как часто файлы сертификатов проверяются на изменение.
+const CheckInterval = 10 * time.Second
+
+// Store хранит текущие сертификат и CA одной TLS-конфигурации. Методы безопасны для конкурентного вызова.
+type Store struct {
+	name   string // для логов и метрик
+	cfg    config.TLSConfig
+	logger *zap.Logger
+
+	current   atomic.Pointer[material]
+	nextCheck atomic.Int64 // UnixNano следующей проверки файлов
+
+	mu       sync.Mutex
+	modTimes map[string]time.Time
+}
+
+// material — загруженные файлы (неизменяемые).
+type material struct {
+	cert *tls.Certificate // nil — CertFile не задан
+	pool *x509.CertPool   // nil — CAFile не задан
+}
+
+// New загружает файлы cfg; name — имя конфигурации в логах и метриках (server, user-service,
+// upstream). cfg должен быть провалидирован (config.TLSConfig.Validate).
+func New(name string, cfg config.TLSConfig, logger *zap.Logger) (*Store, error) {
+	s := &Store{name: name, cfg: cfg, logger: logger}
+	s.modTimes = s.snapshot()
+	m, err := s.load()
+	if err != nil {
+		return nil, fmt.Errorf("tls %s: %w", name, err)
+	}
+	s.current.Store(m)
+	s.nextCheck.Store(time.Now().Add(CheckInterval).UnixNano())
+	return s, nil
+}
+
+func (s *Store) load() (*material, error) {
+	m := &material{}
+	if s.cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(s.cfg.CertFile, s.cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		m.cert = &cert
+		if cert.Leaf != nil {
+			metrics.TLSCertExpiry.WithLabelValues(s.name).Set(float64(cert.Leaf.NotAfter.Unix()))
+		}
+	}
+	if s.cfg.CAFile != "" {
+		data, err := os.ReadFile(s.cfg.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		m.pool = x509.NewCertPool()
+		if !m.pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("%s: no PEM certificates found", s.cfg.CAFile)
+		}
+	}
+	return m, nil
+}
+
+// check перечитывает файлы, если с прошлой проверки прошло CheckInterval и их mtime изменился.
+// При ошибке (например, файл записан наполовину) остаются прежние сертификаты; следующая
+// попытка — после следующего изменения файлов.
+func (s *Store) check() {
+	now := time.Now()
+	next := s.nextCheck.Load()
+	if now.UnixNano() < next || !s.nextCheck.CompareAndSwap(next, now.Add(CheckInterval).UnixNano()) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	modTimes := s.snapshot()
+	if maps.EqualFunc(modTimes, s.modTimes, time.Time.Equal) {
+		return
+	}
+	s.modTimes = modTimes
+	m, err := s.load()
+	if err != nil {
+		metrics.TLSReloads.WithLabelValues(s.name, "error").Inc()
+		s.logger.Error("TLS certificate reload failed, keeping current certificate",
+			zap.String("cert", s.name), zap.Error(err))
+		return
+	}
+	s.current.Store(m)
+	metrics.TLSReloads.WithLabelValues(s.name, "ok").Inc()
+	s.logger.Info("TLS certificate reloaded", zap.String("cert", s.name))
+}
+
+// snapshot возвращает mtime файлов конфигурации (отсутствующие файлы не попадают в карту).
+func (s *Store) snapshot() map[string]time.Time {
+	out := make(map[string]time.Time, 3)
+	for _, f := range []string{s.cfg.CertFile, s.cfg.KeyFile, s.cfg.CAFile} {
+		if f == "" {
+			continue
+		}
+		if st, err := os.Stat(f); err == nil {
+			out[f] = st.ModTime()
+		}
+	}
+	return out
+}
+
+func (s *Store) minVersion() uint16 {
+	if s.cfg.MinVersion == "1.3" {
+		return tls.VersionTLS13
+	}
+	return tls.VersionTLS12
+}
+
+// ServerConfig — конфигурация сервера с протоколами ALPN nextProtos. Сертификат и CA клиентских
+// сертификатов берутся текущие на момент каждого handshake.
+func (s *Store) ServerConfig(nextProtos ...string) *tls.Config {
+	base := &tls.Config{
+		MinVersion: s.minVersion(),
+		NextProtos: nextProtos,
+	}
+	switch s.cfg.ClientAuth {
+	case config.TLSClientAuthOptional:
+		base.ClientAuth = tls.VerifyClientCertIfGiven
+	case config.TLSClientAuthRequire:
+		base.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	out := base.Clone()
+	out.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		s.check()
+		m := s.current.Load()
+		c := base.Clone()
+		if m.cert != nil {
+			c.Certificates = []tls.Certificate{*m.cert}
+		}
+		c.ClientCAs = m.pool
+		return c, nil
+	}
+	return out
+}
+
+// ClientConfig — конфигурация клиента для одного соединения с сервером serverName (ServerName
+// конфигурации имеет приоритет): CA сервера (nil — системные) и клиентский сертификат — текущие
+// на момент вызова.
+func (s *Store) ClientConfig(serverName string) *tls.Config {
+	s.check()
+	m := s.current.Load()
+	c := &tls.Config{
+		MinVersion: s.minVersion(),
+		RootCAs:    m.pool,
+		ServerName: cmp.Or(s.cfg.ServerName, serverName),
+	}
+	if m.cert != nil {
+		c.Certificates = []tls.Certificate{*m.cert}
+	}
+	return c
+}
+
+// DialTLS — DialTLSContext для t: соединения устанавливаются через t.DialContext, каждое получает
+// ClientConfig для хоста адреса (HTTP/2 по ALPN — при t.ForceAttemptHTTP2).
+func (s *Store) DialTLS(t *http.Transport) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	dial := t.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	var nextProtos []string
+	if t.ForceAttemptHTTP2 {
+		nextProtos = []string{"h2", "http/1.1"}
+	}
+	timeout := t.TLSHandshakeTimeout
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		c := s.ClientConfig(host)
+		c.NextProtos = nextProtos
+		tc := tls.Client(conn, c)
+		hctx := ctx
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			hctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		if err := tc.HandshakeContext(hctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return tc, nil
+	}
+}
+
+// Credentials — TLS-учётные данные gRPC-клиента: каждое соединение получает ClientConfig
+// (имя сервера — из адреса, если ServerName не задан).
+func (s *Store) Credentials() credentials.TransportCredentials {
+	return &clientCredentials{TransportCredentials: credentials.NewTLS(s.ClientConfig("")), store: s}
+}
+
+type clientCredentials struct {
+	credentials.TransportCredentials
+	store *Store
+}
+
+func (c *clientCredentials) ClientHandshake(ctx context.Context, authority string, conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return credentials.NewTLS(c.store.ClientConfig("")).ClientHandshake(ctx, authority, conn)
+}
+
+func (c *clientCredentials) Clone() credentials.TransportCredentials {
+	return &clientCredentials{TransportCredentials: c.TransportCredentials.Clone(), store: c.store}
+}
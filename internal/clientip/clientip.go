@@ -0,0 +1,168 @@
+// Package clientip — определение IP клиента с учётом доверенных прокси (балансировщиков)
+// перед gateway. Заголовкам Forwarded / X-Forwarded-For / X-Real-IP верят, только если
+// запрос пришёл от доверенного прокси; цепочка разбирается справа налево.
+package clientip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/psds-microservice/api-gateway/pkg/constants"
+)
+
+// Resolver определяет IP клиента по адресу соединения и заголовкам доверенных прокси.
+type Resolver struct {
+	trusted []netip.Prefix
+}
+
+// NewResolver создаёт Resolver. trusted — CIDR или отдельные IP доверенных прокси;
+// пустой список — заголовкам не верим, IP клиента — адрес соединения.
+func NewResolver(trusted []string) (*Resolver, error) {
+	r := &Resolver{}
+	for _, s := range trusted {
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			addr, aerr := netip.ParseAddr(s)
+			if aerr != nil {
+				return nil, fmt.Errorf("trusted proxy %q: %w", s, err)
+			}
+			p = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		r.trusted = append(r.trusted, p.Masked())
+	}
+	return r, nil
+}
+
+// Resolve возвращает IP клиента HTTP-запроса.
+func (r *Resolver) Resolve(req *http.Request) string {
+	return r.resolve(hostOnly(req.RemoteAddr), req.Header)
+}
+
+// ResolveAddr возвращает IP клиента по адресу соединения remote ("host:port" или IP) и
+// заголовкам (например, gRPC-метаданным, приведённым к http.Header).
+func (r *Resolver) ResolveAddr(remote string, h http.Header) string {
+	return r.resolve(hostOnly(remote), h)
+}
+
+func (r *Resolver) resolve(remote string, h http.Header) string {
+	if !r.isTrusted(remote) {
+		return remote
+	}
+	// Цепочка адресов от клиента к последнему прокси; идём справа налево, пропуская
+	// доверенные прокси. Первый недоверенный адрес — клиент.
+	chain := forwardedFor(h.Values(constants.HeaderForwarded))
+	if len(chain) == 0 {
+		chain = xForwardedFor(h.Values(constants.HeaderForwardedFor))
+	}
+	if len(chain) == 0 {
+		if ip, ok := parseIP(h.Get(constants.HeaderRealIP)); ok {
+			return ip
+		}
+		return remote
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		ip, ok := parseIP(chain[i])
+		if !ok {
+			// Мусор в цепочке: дальше неё верить нельзя — клиент тот, кто её передал.
+			if i == len(chain)-1 {
+				return remote
+			}
+			return chain[i+1]
+		}
+		if !r.isTrusted(ip) {
+			return ip
+		}
+		chain[i] = ip
+	}
+	return chain[0]
+}
+
+// TrustsRemote сообщает, пришло ли соединение remote от доверенного прокси.
+func (r *Resolver) TrustsRemote(remote string) bool {
+	return r.isTrusted(hostOnly(remote))
+}
+
+func (r *Resolver) isTrusted(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range r.trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// xForwardedFor — адреса из всех заголовков X-Forwarded-For по порядку.
+func xForwardedFor(values []string) []string {
+	var out []string
+	for _, v := range values {
+		for _, s := range strings.Split(v, ",") {
+			out = append(out, strings.TrimSpace(s))
+		}
+	}
+	return out
+}
+
+// forwardedFor — параметры for= из заголовков Forwarded (RFC 7239) по порядку.
+func forwardedFor(values []string) []string {
+	var out []string
+	for _, v := range values {
+		for _, elem := range strings.Split(v, ",") {
+			for _, pair := range strings.Split(elem, ";") {
+				k, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(k, "for") {
+					out = append(out, strings.Trim(val, `"`))
+				}
+			}
+		}
+	}
+	return out
+}
+
+// parseIP нормализует адрес из заголовка: "1.2.3.4", "1.2.3.4:80", "[2001:db8::1]:80", "2001:db8::1".
+// Обфусцированные идентификаторы RFC 7239 ("unknown", "_hidden") не являются IP.
+func parseIP(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", false
+	}
+	if addr, err := netip.ParseAddr(strings.Trim(s, "[]")); err == nil {
+		return addr.Unmap().String(), true
+	}
+	if ap, err := netip.ParseAddrPort(s); err == nil {
+		return ap.Addr().Unmap().String(), true
+	}
+	return "", false
+}
+
+func hostOnly(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil || host == "" {
+		host = addr
+	}
+	if ip, ok := parseIP(host); ok {
+		return ip
+	}
+	return host
+}
+
+type ipKey struct{}
+
+// WithIP возвращает контекст с IP клиента.
+func WithIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, ipKey{}, ip)
+}
+
+// FromContext возвращает IP клиента, определённый middleware.ClientIP.
+func FromContext(ctx context.Context) (string, bool) {
+	ip, ok := ctx.Value(ipKey{}).(string)
+	return ip, ok && ip != ""
+}
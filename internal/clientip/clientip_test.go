@@ -0,0 +1,77 @@
+package clientip
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	r, err := NewResolver([]string{"10.0.0.0/8", "192.168.1.1", "2001:db8:ff::/48"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		remote  string
+		headers map[string][]string
+		want    string
+	}{
+		{"no headers", "203.0.113.5:4000", nil, "203.0.113.5"},
+		{"untrusted remote, spoofed XFF", "203.0.113.5:4000",
+			map[string][]string{"X-Forwarded-For": {"1.1.1.1"}}, "203.0.113.5"},
+		{"untrusted remote, spoofed X-Real-IP", "203.0.113.5:4000",
+			map[string][]string{"X-Real-Ip": {"1.1.1.1"}}, "203.0.113.5"},
+		{"trusted remote, XFF", "10.0.0.2:4000",
+			map[string][]string{"X-Forwarded-For": {"198.51.100.7"}}, "198.51.100.7"},
+		{"multiple trusted hops", "10.0.0.2:4000",
+			map[string][]string{"X-Forwarded-For": {"1.1.1.1, 198.51.100.7, 192.168.1.1", "10.0.0.3"}}, "198.51.100.7"},
+		{"spoofed prefix ignored", "10.0.0.2:4000",
+			map[string][]string{"X-Forwarded-For": {"10.9.9.9, 198.51.100.7"}}, "198.51.100.7"},
+		{"all hops trusted", "10.0.0.2:4000",
+			map[string][]string{"X-Forwarded-For": {"10.0.0.4, 10.0.0.3"}}, "10.0.0.4"},
+		{"garbage mid-chain", "10.0.0.2:4000",
+			map[string][]string{"X-Forwarded-For": {"198.51.100.7, not-an-ip, 10.0.0.3"}}, "10.0.0.3"},
+		{"garbage last hop", "10.0.0.2:4000",
+			map[string][]string{"X-Forwarded-For": {"198.51.100.7, garbage"}}, "10.0.0.2"},
+		{"X-Real-IP from trusted", "10.0.0.2:4000",
+			map[string][]string{"X-Real-Ip": {"198.51.100.7"}}, "198.51.100.7"},
+		{"Forwarded preferred over XFF", "10.0.0.2:4000",
+			map[string][]string{"Forwarded": {"for=198.51.100.7"}, "X-Forwarded-For": {"1.1.1.1"}}, "198.51.100.7"},
+		{"Forwarded quoted IPv6 with port", "10.0.0.2:4000",
+			map[string][]string{"Forwarded": {`for="[2001:db8:cafe::17]:4711";proto=https, for=10.0.0.3`}}, "2001:db8:cafe::17"},
+		{"Forwarded IPv4 with port", "10.0.0.2:4000",
+			map[string][]string{"Forwarded": {`For="198.51.100.7:8080"`}}, "198.51.100.7"},
+		{"Forwarded unknown", "10.0.0.2:4000",
+			map[string][]string{"Forwarded": {"for=unknown, for=10.0.0.3"}}, "10.0.0.3"},
+		{"Forwarded obfuscated", "10.0.0.2:4000",
+			map[string][]string{"Forwarded": {"for=198.51.100.7, for=_hidden"}}, "10.0.0.2"},
+		{"IPv4-mapped remote", "[::ffff:10.0.0.2]:4000",
+			map[string][]string{"X-Forwarded-For": {"198.51.100.7"}}, "198.51.100.7"},
+		{"IPv4-mapped in chain", "10.0.0.2:4000",
+			map[string][]string{"X-Forwarded-For": {"::ffff:198.51.100.7, ::ffff:10.0.0.3"}}, "198.51.100.7"},
+		{"trusted IPv6 remote", "[2001:db8:ff::1]:4000",
+			map[string][]string{"X-Forwarded-For": {"2001:db8::1"}}, "2001:db8::1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			for k, vs := range tt.headers {
+				for _, v := range vs {
+					h.Add(k, v)
+				}
+			}
+			if got := r.ResolveAddr(tt.remote, h); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewResolverInvalid(t *testing.T) {
+	if _, err := NewResolver([]string{"10.0.0.0/33"}); err == nil {
+		t.Fatal("invalid CIDR accepted")
+	}
+	if _, err := NewResolver([]string{"proxy.local"}); err == nil {
+		t.Fatal("host name accepted")
+	}
+}
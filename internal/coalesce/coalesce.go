@@ -0,0 +1,153 @@
+// Package coalesce — объединение одновременных одинаковых GET-запросов (singleflight): пока
+// первый запрос ждёт ответа upstream-а или handler-а, такие же запросы (метод, путь, query,
+// вызывающий) ждут его и получают копию ответа.
+package coalesce
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/psds-microservice/api-gateway/internal/auth"
+	"github.com/psds-microservice/api-gateway/internal/capture"
+	"github.com/psds-microservice/api-gateway/internal/match"
+	"github.com/psds-microservice/api-gateway/internal/metrics"
+	"github.com/psds-microservice/api-gateway/internal/reqinfo"
+)
+
+// MaxBodyBytes — наибольший ответ, который раздаётся ожидающим; при большем ответе каждый
+// ожидающий выполняет свой запрос.
+const MaxBodyBytes = 4 << 20
+
+// Group объединяет запросы, проходящие через её Handler. Методы безопасны для конкурентного вызова.
+type Group struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+// call — выполняющийся запрос; поля ответа заполняются до закрытия done.
+type call struct {
+	done chan struct{}
+
+	ok       bool // ответ можно раздать ожидающим
+	status   int
+	header   http.Header
+	body     []byte
+	route    string
+	upstream string
+}
+
+// New создаёт пустую группу.
+func New() *Group {
+	return &Group{calls: make(map[string]*call)}
+}
+
+// Handler объединяет одинаковые GET-запросы к next: ответ первого (заголовки, выставленные
+// gateway до next, не копируются) получают все, кто пришёл до его завершения. Если первый
+// запрос прерван клиентом, завершился паникой или его ответ больше MaxBodyBytes, ожидающие
+// выполняют запрос сами.
+func (g *Group) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.Header.Get("Range") != "" || r.Header.Get("Upgrade") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		key := key(r)
+		g.mu.Lock()
+		if c, ok := g.calls[key]; ok {
+			g.mu.Unlock()
+			select {
+			case <-c.done:
+			case <-r.Context().Done():
+				return // клиент ушёл, не дождавшись ответа
+			}
+			if c.ok {
+				c.serve(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		c := &call{done: make(chan struct{})}
+		g.calls[key] = c
+		g.mu.Unlock()
+		defer func() {
+			g.mu.Lock()
+			delete(g.calls, key)
+			g.mu.Unlock()
+			close(c.done)
+		}()
+
+		pre := w.Header().Clone()
+		rec := &capture.Recorder{ResponseWriter: w, Limit: MaxBodyBytes}
+		next.ServeHTTP(rec, r)
+		if rec.Overflow || r.Context().Err() != nil {
+			return
+		}
+		c.ok = true
+		c.status = rec.StatusCode
+		if c.status == 0 {
+			c.status = http.StatusOK
+		}
+		c.header = capture.WithoutHeaders(rec.SentHeader, pre)
+		c.body = rec.Body.Bytes()
+		if info := reqinfo.From(r.Context()); info != nil {
+			c.route, c.upstream = info.Route, info.Upstream
+		}
+	})
+}
+
+// Paths объединяет запросы к путям из paths (синтаксис путей маршрутов прокси: "/x/" — всё
+// внутри); остальные передаются next как есть.
+func Paths(paths []string, next http.Handler) http.Handler {
+	if len(paths) == 0 {
+		return next
+	}
+	var m match.HTTP[bool]
+	for _, p := range paths {
+		m.Add(p, nil, true)
+	}
+	coalesced := New().Handler(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := m.Lookup(r.Method, r.URL.Path); ok {
+			coalesced.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// serve отвечает ожидающему копией ответа первого запроса.
+func (c *call) serve(w http.ResponseWriter, r *http.Request) {
+	h := w.Header()
+	for k, vs := range c.header {
+		h[k] = append(h[k], vs...)
+	}
+	if c.route != "" {
+		reqinfo.SetRoute(r.Context(), c.route)
+	}
+	if c.upstream != "" {
+		reqinfo.SetUpstream(r.Context(), c.upstream)
+	}
+	route := c.route
+	if route == "" {
+		route = "unmatched"
+	}
+	metrics.CoalescedRequests.WithLabelValues(route).Inc()
+	w.WriteHeader(c.status)
+	w.Write(c.body)
+}
+
+// key — метод, путь, отсортированный query, вызывающий и Accept-Encoding (сжатый ответ
+// не должен достаться клиенту без поддержки сжатия).
+func key(r *http.Request) string {
+	query := r.URL.RawQuery
+	if q, err := url.ParseQuery(query); err == nil {
+		query = q.Encode()
+	}
+	subject := ""
+	if id, ok := auth.FromContext(r.Context()); ok {
+		subject = id.Subject
+	}
+	return r.Method + " " + r.URL.Path + "?" + query + "\x00" + subject + "\x00" + r.Header.Get("Accept-Encoding")
+}
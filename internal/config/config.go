@@ -5,6 +5,7 @@ import (
 	"net/url"
 	"os"
 	"strconv"
+	"strings"
 )
 
 func getEnv(key, def string) string {
@@ -26,11 +27,100 @@ func getEnvInt(key string, def int) int {
 	return v
 }
 
+func getEnvFloat(key string, def float64) float64 {
+	v, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+func getEnvBool(key string, def bool) bool {
+	v, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// getEnvIntList — список целых через запятую (некорректные элементы отбрасываются).
+func getEnvIntList(key, def string) []int {
+	var out []int
+	for _, s := range getEnvList(key, def) {
+		if v, err := strconv.Atoi(s); err == nil {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// getEnvMap — пары "ключ=значение" через запятую (элементы без "=" отбрасываются).
+func getEnvMap(key, def string) map[string]string {
+	out := make(map[string]string)
+	for _, s := range getEnvList(key, def) {
+		if k, v, ok := strings.Cut(s, "="); ok {
+			out[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return out
+}
+
+// getEnvIntMap — пары "ключ=целое" через запятую (некорректные элементы отбрасываются).
+func getEnvIntMap(key, def string) map[string]int {
+	out := make(map[string]int)
+	for k, v := range getEnvMap(key, def) {
+		if n, err := strconv.Atoi(v); err == nil {
+			out[k] = n
+		}
+	}
+	return out
+}
+
+// getEnvList — список через запятую (пустые элементы отбрасываются).
+func getEnvList(key, def string) []string {
+	var out []string
+	for _, s := range strings.Split(getEnv(key, def), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// Хранилища кэша ответов (CACHE_BACKEND).
+const (
+	CacheBackendMemory = "memory"
+	CacheBackendRedis  = "redis"
+)
+
+// Форматы логов (LOG_FORMAT).
+const (
+	LogFormatJSON    = "json"
+	LogFormatConsole = "console"
+)
+
+// Источник client_id в сертификате устройства (DEVICE_CERT_IDENTITY).
+const (
+	DeviceCertIdentityCN  = "cn"
+	DeviceCertIdentityURI = "uri"
+)
+
+// Экспортёры трассировки (TRACING_EXPORTER).
+const (
+	TracingExporterNone   = "none"
+	TracingExporterOTLP   = "otlp"
+	TracingExporterStdout = "stdout"
+	TracingExporterFile   = "file"
+)
+
 // Config — конфигурация из .env (12-factor).
 type Config struct {
 	Host     string
 	Port     int
 	GRPCPort string
+	// SinglePort — gRPC обслуживается на Port вместе с HTTP (по content-type application/grpc,
+	// без TLS — h2c); GRPCPort не открывается.
+	SinglePort bool
 
 	UserService struct {
 		Host              string
@@ -40,6 +130,19 @@ type Config struct {
 		RequestTimeoutSec int
 		MaxRetries        int
 		RetryDelaySec     int
+		// RetryCodes — коды gRPC, после которых вызов повторяется (UNAVAILABLE, ...).
+		RetryCodes []string
+		// TLSEnabled — gRPC-соединение с user-service по TLS (TLS; CertFile/KeyFile — mTLS).
+		TLSEnabled bool
+		TLS        TLSConfig
+	}
+
+	// TLS — TLS входящих соединений: HTTP и GRPC включают его на своих портах (при SinglePort
+	// действует HTTP), Server — общий сертификат и проверка клиентских сертификатов (mTLS).
+	TLS struct {
+		HTTP   bool
+		GRPC   bool
+		Server TLSConfig
 	}
 
 	// Backend HTTP base URLs for reverse proxy (optional; empty = proxy not registered).
@@ -51,6 +154,77 @@ type Config struct {
 	NotificationServiceURL string // e.g. http://localhost:8092
 	DataChannelServiceURL  string // e.g. http://localhost:8097
 
+	// HTTP — таймауты собственных handler-ов gateway (маршруты прокси используют свои, см. ProxyTimeoutMs).
+	HTTP struct {
+		ReadTimeoutSec  int
+		WriteTimeoutSec int
+	}
+	// TrustedProxies — CIDR (или IP) балансировщиков перед gateway, которым разрешено
+	// передавать IP клиента в Forwarded / X-Forwarded-For / X-Real-IP (пусто = не верим никому).
+	TrustedProxies []string
+	// CoalescePaths — пути собственных handler-ов gateway (в т.ч. gRPC-gateway), одновременные
+	// одинаковые GET-запросы к которым обслуживаются одним вызовом (синтаксис путей маршрутов).
+	// Для маршрутов прокси — options.coalesce.
+	CoalescePaths []string
+	// ProxyTimeoutMs — таймаут запроса маршрута прокси по умолчанию.
+	ProxyTimeoutMs int
+	// ProxyIdleTimeoutSec — таймаут простоя upgraded-соединений (WebSocket) по умолчанию.
+	ProxyIdleTimeoutSec int
+	// WebSocket — параметры маршрутов в режиме WebSocket-прокси (options.websocket) по умолчанию.
+	WebSocket WebSocketConfig
+
+	// GatewayFile — JSON-файл с таблицей маршрутов прокси (пусто = встроенная таблица, см. DefaultGateway).
+	GatewayFile string
+	// GatewayReloadIntervalSec — период проверки .env и GatewayFile на изменения (0 = только SIGHUP).
+	GatewayReloadIntervalSec int
+
+	// CircuitBreaker — параметры circuit breaker upstream-ов прокси по умолчанию.
+	CircuitBreaker CircuitBreakerConfig
+
+	// Retry — политика повторов маршрутов прокси по умолчанию. По умолчанию Attempts = 0:
+	// маршрут включает повторы сам (options.retry.attempts), остальные поля берутся отсюда.
+	Retry RetryConfig
+	// RetryBudget — бюджет повторов upstream-ов прокси по умолчанию (на реплику).
+	RetryBudget RetryBudgetConfig
+
+	// RateLimit — лимиты запросов: Enabled выключает их целиком, Default — лимит по умолчанию
+	// (RATE_LIMIT_RPS/BURST/KEY), если в GATEWAY_CONFIG_FILE не задан свой.
+	RateLimit struct {
+		Enabled bool
+		Default RateLimitRule
+		// Backend — где хранятся вёдра: RateLimitBackendLocal (в памяти реплики) или
+		// RateLimitBackendRedis (общие для всех реплик, Config.Redis).
+		Backend string
+		// RedisTimeoutMs — таймаут обращения к Redis; при ошибке лимит считается локально.
+		RedisTimeoutMs int
+		// RedisRetrySec — сколько после ошибки Redis лимиты считаются локально без попыток Redis.
+		RedisRetrySec int
+	}
+
+	// Cache — хранилище кэша ответов маршрутов прокси (options.cache маршрута).
+	Cache struct {
+		// Backend — CacheBackendMemory (LRU в памяти реплики) или CacheBackendRedis (общий, Config.Redis).
+		Backend string
+		// MaxEntries — размер LRU в памяти.
+		MaxEntries int
+		// MaxBodyBytes — ответы с телом больше этого размера не кэшируются.
+		MaxBodyBytes int
+		// StaleSec — сколько устаревший ответ с ETag / Last-Modified хранится для условного
+		// запроса к upstream-у (ответ 304 продлевает его без передачи тела).
+		StaleSec int
+		// RedisTimeoutMs — таймаут обращения к Redis; при ошибке запрос идёт к upstream-у.
+		RedisTimeoutMs int
+	}
+
+	// Health — фоновые проверки зависимостей для /ready.
+	Health struct {
+		IntervalSec int
+		TimeoutSec  int
+		// Required — зависимости, без которых gateway не готов: user-service-grpc, postgres, redis
+		// или имя upstream-а прокси (user-service, ticket-service, ...).
+		Required []string
+	}
+
 	Database struct {
 		Host     string
 		Port     int
@@ -68,13 +242,95 @@ type Config struct {
 	}
 
 	JWT struct {
+		// Secret — ключ HS256 (пусто = HS256 не принимается).
 		Secret     string
 		Expiration int
+		// JWKSFile — локальный JWKS с открытыми ключами для RS256/ES256 (пусто = только HS256).
+		JWKSFile string
+		Issuer   string // пусто = iss не проверяется
+		Audience string // пусто = aud не проверяется
+	}
+
+	// Auth — аутентификация входящих HTTP и gRPC запросов.
+	Auth struct {
+		// Enabled — AUTH_ENABLED (по умолчанию false: при обновлении gateway без JWT_SECRET /
+		// JWT_JWKS_FILE запросы проходят, как до появления аутентификации).
+		Enabled bool
+		// PublicPaths — префиксы HTTP-путей без аутентификации ("/x/" — поддерево, "/x" — сам путь и поддерево).
+		PublicPaths []string
+		// PublicGRPCMethods — префиксы полных имён gRPC-методов без аутентификации.
+		PublicGRPCMethods []string
+	}
+
+	// DeviceCerts — идентификация устройств по клиентскому сертификату gRPC (mTLS, TLS_CLIENT_AUTH):
+	// для методов Methods identity берётся только из сертификата, токены и API-ключи не принимаются.
+	DeviceCerts struct {
+		Enabled bool
+		// Methods — префиксы полных имён gRPC-методов, вызываемых устройствами.
+		Methods []string
+		// Identity — откуда берётся client_id: DeviceCertIdentityCN или DeviceCertIdentityURI
+		// (SAN URI с префиксом URIPrefix, client_id — остаток URI).
+		Identity  string
+		URIPrefix string
+		// Roles и Scopes — роли устройства и ограничение их разрешений (как у API-ключа).
+		Roles  []string
+		Scopes []string
+		// CRLFile — список отзыва (PEM или DER), подписанный CA из TLS_CLIENT_CA_FILE; пусто — без отзыва.
+		CRLFile string
+	}
+
+	// APIKeys — API-ключи устройств и машинных клиентов (таблица api_keys в Postgres).
+	APIKeys struct {
+		Enabled bool
+		// CacheTTLSec — сколько проверенный ключ кэшируется; отзыв на других экземплярах
+		// gateway вступает в силу в пределах этого времени.
+		CacheTTLSec int
+		// RotationGraceSec — сколько старый ключ действует после ротации.
+		RotationGraceSec int
 	}
 
+	// Logging — логгер приложения (internal/logging).
 	Logging struct {
-		Level  string
+		// Level — общий уровень: debug, info, warn, error.
+		Level string
+		// Format — LogFormatJSON или LogFormatConsole (человекочитаемый, для разработки).
 		Format string
+		// Levels — уровни отдельных логгеров по имени ("proxy", "access", ...), переопределяют Level.
+		Levels map[string]string
+	}
+
+	// CORS — политика CORS по умолчанию (cors.default в GATEWAY_CONFIG_FILE её заменяет).
+	CORS CORSPolicy
+
+	// AccessLog — журнал HTTP-запросов и gRPC-вызовов (логгер "access").
+	AccessLog struct {
+		Enabled bool
+		// Sample — шаблон маршрута HTTP или полное имя gRPC-метода -> N: пишется каждый N-й
+		// успешный запрос (ошибки — всегда).
+		Sample map[string]int
+		// RedactParams — параметры query, значения которых заменяются на [REDACTED]
+		// (токены, ключи, полезная нагрузка кадров).
+		RedactParams []string
+		// RedactEmails — адреса e-mail в пути и query заменяются на [EMAIL].
+		RedactEmails bool
+		// RedactTokens — JWT и API-ключи в пути и query заменяются на [TOKEN].
+		RedactTokens bool
+	}
+
+	// Tracing — трассировка OpenTelemetry (W3C traceparent во входящих и исходящих запросах).
+	Tracing struct {
+		// Exporter — куда отправляются спаны: TracingExporterOTLP (коллектор по gRPC),
+		// TracingExporterStdout / TracingExporterFile (JSON, для локального запуска) или
+		// TracingExporterNone (спаны не пишутся, контекст трассировки всё равно передаётся дальше).
+		Exporter string
+		// OTLPEndpoint — host:port коллектора; пусто — OTEL_EXPORTER_OTLP_ENDPOINT или localhost:4317.
+		OTLPEndpoint string
+		OTLPInsecure bool
+		// File — файл спанов для TracingExporterFile.
+		File string
+		// SampleRatio — доля трассируемых запросов без входящего traceparent (решение родителя соблюдается).
+		SampleRatio float64
+		ServiceName string
 	}
 
 	Video struct {
@@ -91,6 +347,7 @@ func Load() *Config {
 		Port:     getEnvInt("SERVER_PORT", 8080),
 		GRPCPort: getEnv("GRPC_PORT", "9090"),
 	}
+	cfg.SinglePort = getEnvBool("SINGLE_PORT_ENABLED", false)
 	cfg.UserService.Host = getEnv("USER_SERVICE_HOST", "localhost")
 	cfg.UserService.Port = getEnvInt("USER_SERVICE_PORT", 9090)
 	cfg.UserService.HTTPPort = getEnvInt("USER_SERVICE_HTTP_PORT", 8080)
@@ -98,6 +355,24 @@ func Load() *Config {
 	cfg.UserService.RequestTimeoutSec = getEnvInt("USER_SERVICE_REQUEST_TIMEOUT_SEC", 5)
 	cfg.UserService.MaxRetries = getEnvInt("USER_SERVICE_MAX_RETRIES", 3)
 	cfg.UserService.RetryDelaySec = getEnvInt("USER_SERVICE_RETRY_DELAY_SEC", 1)
+	cfg.UserService.RetryCodes = getEnvList("USER_SERVICE_RETRY_CODES", "UNAVAILABLE,DEADLINE_EXCEEDED")
+	cfg.UserService.TLSEnabled = getEnvBool("USER_SERVICE_TLS_ENABLED", false)
+	cfg.UserService.TLS = TLSConfig{
+		CAFile:     getEnv("USER_SERVICE_TLS_CA_FILE", ""),
+		CertFile:   getEnv("USER_SERVICE_TLS_CERT_FILE", ""),
+		KeyFile:    getEnv("USER_SERVICE_TLS_KEY_FILE", ""),
+		ServerName: getEnv("USER_SERVICE_TLS_SERVER_NAME", ""),
+	}
+
+	cfg.TLS.HTTP = getEnvBool("TLS_HTTP_ENABLED", false)
+	cfg.TLS.GRPC = getEnvBool("TLS_GRPC_ENABLED", false)
+	cfg.TLS.Server = TLSConfig{
+		CertFile:   getEnv("TLS_CERT_FILE", ""),
+		KeyFile:    getEnv("TLS_KEY_FILE", ""),
+		CAFile:     getEnv("TLS_CLIENT_CA_FILE", ""),
+		ClientAuth: getEnv("TLS_CLIENT_AUTH", TLSClientAuthNone),
+		MinVersion: getEnv("TLS_MIN_VERSION", "1.2"),
+	}
 
 	cfg.SessionManagerURL = getEnv("SESSION_MANAGER_URL", "")
 	cfg.TicketServiceURL = getEnv("TICKET_SERVICE_URL", "")
@@ -106,6 +381,61 @@ func Load() *Config {
 	cfg.OperatorPoolURL = getEnv("OPERATOR_POOL_URL", "")
 	cfg.NotificationServiceURL = getEnv("NOTIFICATION_SERVICE_URL", "")
 	cfg.DataChannelServiceURL = getEnv("DATA_CHANNEL_SERVICE_URL", "")
+	cfg.HTTP.ReadTimeoutSec = getEnvInt("HTTP_READ_TIMEOUT_SEC", 15)
+	cfg.HTTP.WriteTimeoutSec = getEnvInt("HTTP_WRITE_TIMEOUT_SEC", 30)
+	cfg.TrustedProxies = getEnvList("TRUSTED_PROXIES", "")
+	cfg.CoalescePaths = getEnvList("COALESCE_PATHS", "")
+	cfg.ProxyTimeoutMs = getEnvInt("PROXY_TIMEOUT_MS", 30000)
+	cfg.ProxyIdleTimeoutSec = getEnvInt("PROXY_IDLE_TIMEOUT_SEC", 300)
+	cfg.WebSocket = WebSocketConfig{
+		MaxConnsPerUser: getEnvInt("WS_MAX_CONNS_PER_USER", 10),
+		MaxConnsPerIP:   getEnvInt("WS_MAX_CONNS_PER_IP", 100),
+		PingIntervalSec: getEnvInt("WS_PING_INTERVAL_SEC", 30),
+		PongTimeoutSec:  getEnvInt("WS_PONG_TIMEOUT_SEC", 10),
+		MaxMessageBytes: getEnvInt("WS_MAX_MESSAGE_BYTES", 1<<20),
+	}
+	cfg.GatewayFile = getEnv("GATEWAY_CONFIG_FILE", "")
+	cfg.GatewayReloadIntervalSec = getEnvInt("GATEWAY_RELOAD_INTERVAL_SEC", 5)
+
+	cfg.CircuitBreaker = CircuitBreakerConfig{
+		Failures:         getEnvInt("CIRCUIT_BREAKER_FAILURES", 5),
+		ErrorRatePct:     getEnvInt("CIRCUIT_BREAKER_ERROR_RATE_PCT", 50),
+		MinRequests:      getEnvInt("CIRCUIT_BREAKER_MIN_REQUESTS", 20),
+		WindowSec:        getEnvInt("CIRCUIT_BREAKER_WINDOW_SEC", 30),
+		OpenSec:          getEnvInt("CIRCUIT_BREAKER_OPEN_SEC", 10),
+		HalfOpenRequests: getEnvInt("CIRCUIT_BREAKER_HALF_OPEN_REQUESTS", 3),
+	}
+
+	cfg.Retry = RetryConfig{
+		Attempts:    getEnvInt("PROXY_RETRY_ATTEMPTS", 0),
+		Methods:     getEnvList("PROXY_RETRY_METHODS", "GET,HEAD,OPTIONS"),
+		Statuses:    getEnvIntList("PROXY_RETRY_STATUSES", "502,503,504"),
+		Errors:      getEnvList("PROXY_RETRY_ON", "connect,reset"),
+		BaseDelayMs: getEnvInt("PROXY_RETRY_BASE_DELAY_MS", 50),
+		MaxDelayMs:  getEnvInt("PROXY_RETRY_MAX_DELAY_MS", 1000),
+	}
+	cfg.RetryBudget.Percent = getEnvInt("RETRY_BUDGET_PERCENT", 20)
+	cfg.RetryBudget.MinConcurrent = getEnvInt("RETRY_BUDGET_MIN_CONCURRENT", 3)
+
+	cfg.RateLimit.Enabled = getEnvBool("RATE_LIMIT_ENABLED", true)
+	cfg.RateLimit.Default = RateLimitRule{
+		RPS:   getEnvFloat("RATE_LIMIT_RPS", 50),
+		Burst: getEnvInt("RATE_LIMIT_BURST", 100),
+		Key:   getEnv("RATE_LIMIT_KEY", RateLimitByUser),
+	}
+	cfg.RateLimit.Backend = getEnv("RATE_LIMIT_BACKEND", RateLimitBackendLocal)
+	cfg.RateLimit.RedisTimeoutMs = getEnvInt("RATE_LIMIT_REDIS_TIMEOUT_MS", 100)
+	cfg.RateLimit.RedisRetrySec = getEnvInt("RATE_LIMIT_REDIS_RETRY_SEC", 5)
+
+	cfg.Cache.Backend = getEnv("CACHE_BACKEND", CacheBackendMemory)
+	cfg.Cache.MaxEntries = getEnvInt("CACHE_MAX_ENTRIES", 10000)
+	cfg.Cache.MaxBodyBytes = getEnvInt("CACHE_MAX_BODY_BYTES", 1<<20)
+	cfg.Cache.StaleSec = getEnvInt("CACHE_STALE_SEC", 600)
+	cfg.Cache.RedisTimeoutMs = getEnvInt("CACHE_REDIS_TIMEOUT_MS", 100)
+
+	cfg.Health.IntervalSec = getEnvInt("HEALTH_CHECK_INTERVAL_SEC", 10)
+	cfg.Health.TimeoutSec = getEnvInt("HEALTH_CHECK_TIMEOUT_SEC", 2)
+	cfg.Health.Required = getEnvList("READY_REQUIRED", "")
 
 	cfg.Database.Host = getEnv("DB_HOST", "localhost")
 	cfg.Database.Port = getEnvInt("DB_PORT", 5432)
@@ -119,11 +449,60 @@ func Load() *Config {
 	cfg.Redis.Password = getEnv("REDIS_PASSWORD", "")
 	cfg.Redis.DB = getEnvInt("REDIS_DB", 0)
 
-	cfg.JWT.Secret = getEnv("JWT_SECRET", "change-me-in-production")
+	cfg.JWT.Secret = getEnv("JWT_SECRET", "")
 	cfg.JWT.Expiration = getEnvInt("JWT_EXPIRATION", 24)
+	cfg.JWT.JWKSFile = getEnv("JWT_JWKS_FILE", "")
+	cfg.JWT.Issuer = getEnv("JWT_ISSUER", "")
+	cfg.JWT.Audience = getEnv("JWT_AUDIENCE", "")
+
+	cfg.Auth.Enabled = getEnvBool("AUTH_ENABLED", false)
+	cfg.Auth.PublicPaths = getEnvList("AUTH_PUBLIC_PATHS",
+		"/health,/ready,/metrics,/openapi.json,/swagger/,/api/v1/status,/api/v1/auth/")
+	cfg.Auth.PublicGRPCMethods = getEnvList("AUTH_PUBLIC_GRPC_METHODS",
+		"/grpc.reflection.v1.ServerReflection/,/grpc.reflection.v1alpha.ServerReflection/")
+
+	cfg.DeviceCerts.Enabled = getEnvBool("DEVICE_CERT_AUTH_ENABLED", false)
+	cfg.DeviceCerts.Methods = getEnvList("DEVICE_CERT_METHODS", "/video_stream.VideoStreamService/StreamVideo")
+	cfg.DeviceCerts.Identity = getEnv("DEVICE_CERT_IDENTITY", DeviceCertIdentityURI)
+	cfg.DeviceCerts.URIPrefix = getEnv("DEVICE_CERT_URI_PREFIX", "urn:psds:device:")
+	cfg.DeviceCerts.Roles = getEnvList("DEVICE_CERT_ROLES", "client")
+	cfg.DeviceCerts.Scopes = getEnvList("DEVICE_CERT_SCOPES", "video:stream")
+	cfg.DeviceCerts.CRLFile = getEnv("DEVICE_CERT_CRL_FILE", "")
+
+	cfg.APIKeys.Enabled = getEnvBool("API_KEYS_ENABLED", true)
+	cfg.APIKeys.CacheTTLSec = getEnvInt("API_KEYS_CACHE_TTL_SEC", 30)
+	cfg.APIKeys.RotationGraceSec = getEnvInt("API_KEYS_ROTATION_GRACE_SEC", 3600)
 
 	cfg.Logging.Level = getEnv("LOG_LEVEL", "info")
-	cfg.Logging.Format = getEnv("LOG_FORMAT", "json")
+	cfg.Logging.Format = getEnv("LOG_FORMAT", LogFormatJSON)
+	cfg.Logging.Levels = getEnvMap("LOG_LEVELS", "")
+
+	cfg.CORS = CORSPolicy{
+		AllowedOrigins:        getEnvList("CORS_ALLOWED_ORIGINS", "*"),
+		AllowedOriginPatterns: getEnvList("CORS_ALLOWED_ORIGIN_PATTERNS", ""),
+		AllowedMethods:        getEnvList("CORS_ALLOWED_METHODS", "GET,POST,PUT,DELETE,PATCH,OPTIONS"),
+		AllowedHeaders: getEnvList("CORS_ALLOWED_HEADERS",
+			"Content-Type,Content-Length,Accept-Encoding,X-CSRF-Token,Authorization,X-API-Key,X-Request-ID,Accept,Origin,Cache-Control,X-Requested-With"),
+		ExposedHeaders: getEnvList("CORS_EXPOSED_HEADERS",
+			"X-Request-ID,X-Cache,RateLimit-Limit,RateLimit-Remaining,RateLimit-Reset,Retry-After"),
+		AllowCredentials: getEnvBool("CORS_ALLOW_CREDENTIALS", false),
+		MaxAgeSec:        getEnvInt("CORS_MAX_AGE_SEC", 600),
+	}
+
+	cfg.AccessLog.Enabled = getEnvBool("ACCESS_LOG_ENABLED", true)
+	cfg.AccessLog.Sample = getEnvIntMap("ACCESS_LOG_SAMPLE",
+		"/api/v1/video/frame=100,/video_stream.VideoStreamService/SendFrame=100")
+	cfg.AccessLog.RedactParams = getEnvList("ACCESS_LOG_REDACT_PARAMS",
+		"token,access_token,refresh_token,id_token,api_key,apikey,key,password,secret,code,frame,frame_data,data")
+	cfg.AccessLog.RedactEmails = getEnvBool("ACCESS_LOG_REDACT_EMAILS", true)
+	cfg.AccessLog.RedactTokens = getEnvBool("ACCESS_LOG_REDACT_TOKENS", true)
+
+	cfg.Tracing.Exporter = getEnv("TRACING_EXPORTER", TracingExporterNone)
+	cfg.Tracing.OTLPEndpoint = getEnv("TRACING_OTLP_ENDPOINT", "")
+	cfg.Tracing.OTLPInsecure = getEnvBool("TRACING_OTLP_INSECURE", true)
+	cfg.Tracing.File = getEnv("TRACING_FILE", "traces.jsonl")
+	cfg.Tracing.SampleRatio = getEnvFloat("TRACING_SAMPLE_RATIO", 1)
+	cfg.Tracing.ServiceName = getEnv("TRACING_SERVICE_NAME", "api-gateway")
 
 	cfg.Video.MaxFrameSize = getEnvInt("VIDEO_MAX_FRAME_SIZE", 10*1024*1024)
 	cfg.Video.MaxFPS = getEnvInt("VIDEO_MAX_FPS", 30)
@@ -131,6 +510,14 @@ func Load() *Config {
 	return cfg
 }
 
+// GRPCTLS — gRPC принимается по TLS: на общем порту (SinglePort) — TLS HTTP-сервера.
+func (c *Config) GRPCTLS() bool {
+	if c.SinglePort {
+		return c.TLS.HTTP
+	}
+	return c.TLS.GRPC
+}
+
 // DSN возвращает connection string для lib/pq
 func (c *Config) DSN() string {
 	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
@@ -144,6 +531,11 @@ func (c *Config) DatabaseURL() string {
 		c.Database.User, pass, c.Database.Host, c.Database.Port, c.Database.Name, c.Database.SSLMode)
 }
 
+// RedisAddr возвращает адрес Redis (host:port).
+func (c *Config) RedisAddr() string {
+	return fmt.Sprintf("%s:%d", c.Redis.Host, c.Redis.Port)
+}
+
 // UserServiceHTTPURL возвращает базовый URL HTTP API user-service (для прокси auth).
 func (c *Config) UserServiceHTTPURL() string {
 	port := c.UserService.HTTPPort
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// CORS — политика CORS: политика по умолчанию и правила для HTTP-путей (синтаксис путей
+// маршрутов; побеждает самое специфичное). Задаётся секцией "cors" в GATEWAY_CONFIG_FILE;
+// политику маршрута прокси можно задать и в его options.cors. Без default в файле действует CORS_*.
+type CORS struct {
+	Default *CORSPolicy  `json:"default,omitempty"`
+	Rules   []CORSPolicy `json:"rules,omitempty"`
+}
+
+// CORSPolicy — кому и что браузер разрешает при кросс-доменных запросах. В правилах пустые
+// списки и MaxAgeSec берутся из default; origin-ы, AllowCredentials и Disabled — нет: правило
+// без origin-ов не отвечает заголовками CORS. "*" в AllowedOrigins несовместим с AllowCredentials.
+type CORSPolicy struct {
+	// Path — путь правила; в default и options.cors не задаётся.
+	Path     string `json:"path,omitempty"`
+	Disabled bool   `json:"disabled,omitempty"` // не отвечать заголовками CORS
+	// AllowedOrigins — origin-ы целиком ("https://app.example.com") или "*" — любой.
+	AllowedOrigins []string `json:"allowed_origins,omitempty"`
+	// AllowedOriginPatterns — origin-ы с одной "*" вместо части имени ("https://*.example.com").
+	AllowedOriginPatterns []string `json:"allowed_origin_patterns,omitempty"`
+	AllowedMethods        []string `json:"allowed_methods,omitempty"`
+	AllowedHeaders        []string `json:"allowed_headers,omitempty"`
+	// ExposedHeaders — заголовки ответа, доступные скрипту (X-Request-ID, RateLimit-*).
+	ExposedHeaders   []string `json:"exposed_headers,omitempty"`
+	AllowCredentials bool     `json:"allow_credentials,omitempty"`
+	// MaxAgeSec — сколько браузер кэширует ответ на preflight; 0 — не кэширует.
+	MaxAgeSec int `json:"max_age_sec,omitempty"`
+}
+
+// DefaultCORS возвращает политику без правил: def (CORS_*) для всех путей.
+func DefaultCORS(def *CORSPolicy) *CORS {
+	return &CORS{Default: def}
+}
+
+// inherit дополняет правило списками и MaxAgeSec из def.
+func (p *CORSPolicy) inherit(def *CORSPolicy) {
+	if def == nil {
+		return
+	}
+	for _, f := range []struct{ dst, src *[]string }{
+		{&p.AllowedMethods, &def.AllowedMethods}, {&p.AllowedHeaders, &def.AllowedHeaders},
+		{&p.ExposedHeaders, &def.ExposedHeaders},
+	} {
+		if len(*f.dst) == 0 {
+			*f.dst = slices.Clone(*f.src)
+		}
+	}
+	if p.MaxAgeSec == 0 {
+		p.MaxAgeSec = def.MaxAgeSec
+	}
+}
+
+func (c *CORS) normalize() {
+	for i := range c.Rules {
+		c.Rules[i].inherit(c.Default)
+	}
+}
+
+func (p *CORSPolicy) validate(where string) []error {
+	var errs []error
+	for _, o := range p.AllowedOrigins {
+		if o == "*" {
+			if p.AllowCredentials {
+				errs = append(errs, fmt.Errorf("%s: origin \"*\" is not allowed with allow_credentials", where))
+			}
+			continue
+		}
+		if !validOrigin(o) {
+			errs = append(errs, fmt.Errorf("%s: invalid origin %q (want scheme://host[:port])", where, o))
+		}
+	}
+	for _, o := range p.AllowedOriginPatterns {
+		if strings.Count(o, "*") != 1 || !validOrigin(strings.Replace(o, "*", "x", 1)) {
+			errs = append(errs, fmt.Errorf("%s: invalid origin pattern %q (want scheme://host with one *)", where, o))
+		}
+	}
+	for _, m := range p.AllowedMethods {
+		if !validMethod(m) {
+			errs = append(errs, fmt.Errorf("%s: unsupported method %q", where, m))
+		}
+	}
+	if p.MaxAgeSec < 0 {
+		errs = append(errs, fmt.Errorf("%s: max_age_sec must not be negative", where))
+	}
+	return errs
+}
+
+// validOrigin — "scheme://host[:port]" без пути, query и учётных данных.
+func validOrigin(o string) bool {
+	u, err := url.Parse(o)
+	return err == nil && u.Scheme != "" && u.Host != "" && u.Path == "" && u.RawQuery == "" && u.User == nil
+}
+
+// Validate проверяет политику CORS.
+func (c *CORS) Validate() []error {
+	var errs []error
+	if d := c.Default; d != nil {
+		if d.Path != "" {
+			errs = append(errs, fmt.Errorf("cors.default: path is not allowed"))
+		}
+		errs = append(errs, d.validate("cors.default")...)
+	}
+	for i, r := range c.Rules {
+		where := fmt.Sprintf("cors.rules[%d]", i)
+		if !strings.HasPrefix(r.Path, "/") {
+			errs = append(errs, fmt.Errorf("%s: path must start with /", where))
+		}
+		errs = append(errs, r.validate(where)...)
+	}
+	return errs
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"os"
+	"slices"
+	"sync"
+
+	"github.com/joho/godotenv"
+)
+
+// dotenv — переменные, которые задал файл .env (а не окружение процесса). Окружение процесса
+// важнее файла и при старте, и при перечитывании: перечитывание меняет только эти переменные.
+var dotenv = struct {
+	mu   sync.Mutex
+	keys map[string]bool
+}{keys: make(map[string]bool)}
+
+// LoadDotEnv загружает path в окружение процесса, не меняя уже заданных переменных (как godotenv.Load).
+func LoadDotEnv(path string) error {
+	vals, err := godotenv.Read(path)
+	if err != nil {
+		return err
+	}
+	dotenv.mu.Lock()
+	defer dotenv.mu.Unlock()
+	for k, v := range vals {
+		if _, ok := os.LookupEnv(k); ok && !dotenv.keys[k] {
+			continue
+		}
+		os.Setenv(k, v)
+		dotenv.keys[k] = true
+	}
+	return nil
+}
+
+// ReloadDotEnv перечитывает path с приоритетом LoadDotEnv: переменные, заданные файлом, обновляются
+// (удалённые из файла — удаляются из окружения), заданные окружением процесса не меняются.
+// Возвращает имена изменённых переменных и переменных, значение которых в файле проигнорировано
+// из-за окружения процесса (shadowed).
+func ReloadDotEnv(path string) (changed, shadowed []string, err error) {
+	vals, err := godotenv.Read(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	dotenv.mu.Lock()
+	defer dotenv.mu.Unlock()
+	for k, v := range vals {
+		cur, ok := os.LookupEnv(k)
+		switch {
+		case ok && !dotenv.keys[k]:
+			if cur != v {
+				shadowed = append(shadowed, k)
+			}
+		case !ok || cur != v:
+			os.Setenv(k, v)
+			dotenv.keys[k] = true
+			changed = append(changed, k)
+		}
+	}
+	for k := range dotenv.keys {
+		if _, ok := vals[k]; !ok {
+			os.Unsetenv(k)
+			delete(dotenv.keys, k)
+			changed = append(changed, k)
+		}
+	}
+	slices.Sort(changed)
+	slices.Sort(shadowed)
+	return changed, shadowed, nil
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestDotEnvPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	write := func(s string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(s), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("DOTENV_TEST_PROCESS", "from-process")
+	for _, k := range []string{"DOTENV_TEST_FILE", "DOTENV_TEST_REMOVED"} {
+		t.Setenv(k, "")
+		os.Unsetenv(k)
+	}
+
+	write("DOTENV_TEST_PROCESS=from-file\nDOTENV_TEST_FILE=v1\nDOTENV_TEST_REMOVED=x\n")
+	if err := LoadDotEnv(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := os.Getenv("DOTENV_TEST_PROCESS"); got != "from-process" {
+		t.Fatalf("process variable overridden at start: %q", got)
+	}
+	if got := os.Getenv("DOTENV_TEST_FILE"); got != "v1" {
+		t.Fatalf("file variable = %q, want v1", got)
+	}
+
+	write("DOTENV_TEST_PROCESS=from-file-2\nDOTENV_TEST_FILE=v2\n")
+	changed, shadowed, err := ReloadDotEnv(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := os.Getenv("DOTENV_TEST_PROCESS"); got != "from-process" {
+		t.Fatalf("process variable overridden on reload: %q", got)
+	}
+	if got := os.Getenv("DOTENV_TEST_FILE"); got != "v2" {
+		t.Fatalf("file variable = %q, want v2", got)
+	}
+	if _, ok := os.LookupEnv("DOTENV_TEST_REMOVED"); ok {
+		t.Fatal("variable removed from file is still set")
+	}
+	if want := []string{"DOTENV_TEST_FILE", "DOTENV_TEST_REMOVED"}; !slices.Equal(changed, want) {
+		t.Fatalf("changed = %v, want %v", changed, want)
+	}
+	if want := []string{"DOTENV_TEST_PROCESS"}; !slices.Equal(shadowed, want) {
+		t.Fatalf("shadowed = %v, want %v", shadowed, want)
+	}
+}
@@ -0,0 +1,636 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Gateway — декларативная таблица маршрутов прокси: upstream-ы и маршруты к ним.
+// Загружается из JSON-файла (GATEWAY_CONFIG_FILE) или строится из .env (DefaultGateway);
+// upstream-ы переопределяются переменными <NAME>_URL (список через запятую), <NAME>_LB_POLICY
+// и <NAME>_LB_HASH_ON, например TICKET_SERVICE_URL=http://t1:8095,http://t2:8095.
+type Gateway struct {
+	Upstreams []UpstreamConfig `json:"upstreams"`
+	Routes    []RouteConfig    `json:"routes"`
+	// RBAC — политики доступа; без секции в файле — DefaultRBAC.
+	RBAC *RBAC `json:"rbac,omitempty"`
+	// RateLimit — лимиты запросов; без секции в файле — DefaultRateLimit.
+	RateLimit *RateLimit `json:"rate_limit,omitempty"`
+	// CORS — политика CORS; без секции в файле — DefaultCORS.
+	CORS *CORS `json:"cors,omitempty"`
+}
+
+// UpstreamConfig — backend-сервис, на который проксируются маршруты.
+// Экземпляры задаются списком Targets или одним URL; без экземпляров upstream не подключён
+// и его маршруты пропускаются.
+type UpstreamConfig struct {
+	Name    string         `json:"name"`
+	URL     string         `json:"url,omitempty"`
+	Targets []TargetConfig `json:"targets,omitempty"`
+	// Policy — политика балансировки (LBRoundRobin по умолчанию).
+	Policy string `json:"policy,omitempty"`
+	// HashOn — ключ для LBConsistentHash: "header:<Name>" или "cookie:<name>".
+	HashOn string `json:"hash_on,omitempty"`
+	// HealthPath — путь активной проверки экземпляров (GET, 2xx = up); пусто = не проверять.
+	HealthPath string `json:"health_path,omitempty"`
+	// Required — недоступность upstream-а (все экземпляры down) делает /ready 503.
+	Required bool `json:"required,omitempty"`
+	// CircuitBreaker — параметры circuit breaker; незаданные поля берутся из CIRCUIT_BREAKER_*.
+	CircuitBreaker *CircuitBreakerConfig `json:"circuit_breaker,omitempty"`
+	// RetryBudget — бюджет повторов; по умолчанию RETRY_BUDGET_*.
+	RetryBudget *RetryBudgetConfig `json:"retry_budget,omitempty"`
+	// TLS — CA и клиентский сертификат (mTLS) для экземпляров с https://; nil — системные CA.
+	TLS *TLSConfig `json:"tls,omitempty"`
+}
+
+// RetryBudgetConfig — бюджет повторов upstream-а, общий для всех его маршрутов: одновременных
+// повторов не больше max(MinConcurrent, Percent% активных запросов). Не даёт повторам
+// умножать нагрузку на отказавший backend. Считается на каждой реплике gateway отдельно:
+// MinConcurrent повторов доступно каждой реплике.
+type RetryBudgetConfig struct {
+	Percent       int `json:"percent"`
+	MinConcurrent int `json:"min_concurrent"`
+}
+
+// CircuitBreakerConfig — параметры circuit breaker upstream-а.
+// Breaker размыкается после Failures ошибок подряд или при доле ошибок не менее ErrorRatePct
+// (из минимум MinRequests запросов за окно WindowSec). Разомкнутый breaker сразу отвечает 503,
+// через OpenSec пропускает HalfOpenRequests пробных запросов: все успешны — замыкается,
+// любая ошибка — снова размыкается.
+type CircuitBreakerConfig struct {
+	Disabled         bool `json:"disabled,omitempty"`
+	Failures         int  `json:"failures,omitempty"`       // 0 = не учитывать ошибки подряд
+	ErrorRatePct     int  `json:"error_rate_pct,omitempty"` // 0 = не учитывать долю ошибок
+	MinRequests      int  `json:"min_requests,omitempty"`
+	WindowSec        int  `json:"window_sec,omitempty"`
+	OpenSec          int  `json:"open_sec,omitempty"`
+	HalfOpenRequests int  `json:"half_open_requests,omitempty"`
+}
+
+// TargetConfig — экземпляр upstream-а.
+type TargetConfig struct {
+	URL    string `json:"url"`
+	Weight int    `json:"weight,omitempty"` // для LBWeighted; 0 = 1
+}
+
+// Политики балансировки между экземплярами upstream-а.
+const (
+	LBRoundRobin     = "round_robin"
+	LBWeighted       = "weighted"
+	LBLeastRequests  = "least_requests"
+	LBConsistentHash = "consistent_hash"
+)
+
+// RouteConfig — маршрут прокси.
+// Path — префикс пути: "/api/v1/tickets" совпадает с самим путём и с "/api/v1/tickets/...",
+// "/session/" — только с путями внутри. Сегмент "*" совпадает с любым одним сегментом.
+// При пересечении побеждает маршрут с большим числом сегментов (литеральный сегмент важнее "*").
+type RouteConfig struct {
+	Path     string       `json:"path"`
+	Methods  []string     `json:"methods,omitempty"` // пусто = любые методы
+	Upstream string       `json:"upstream"`
+	Rewrite  string       `json:"rewrite,omitempty"` // замена совпавшего префикса ("/" — отрезать префикс)
+	Options  RouteOptions `json:"options"`
+}
+
+// RouteOptions — дополнительные параметры маршрута.
+type RouteOptions struct {
+	// Headers — заголовки, выставляемые в запросе к upstream.
+	Headers map[string]string `json:"headers,omitempty"`
+	// Retry — политика повторов; незаданные поля берутся из PROXY_RETRY_*.
+	Retry *RetryConfig `json:"retry,omitempty"`
+	// TimeoutMs — таймаут запроса (чтение тела, ответ upstream-а и его передача клиенту);
+	// 0 = PROXY_TIMEOUT_MS. Остаток передаётся upstream-у в X-Request-Timeout-Ms.
+	TimeoutMs int `json:"timeout_ms,omitempty"`
+	// IdleTimeoutSec — таймаут простоя upgraded-соединения (WebSocket); 0 = PROXY_IDLE_TIMEOUT_SEC.
+	IdleTimeoutSec int `json:"idle_timeout_sec,omitempty"`
+	// RateLimit — лимит запросов маршрута (для его path и methods); правила rate_limit.rules
+	// той же специфичности важнее.
+	RateLimit *RateLimitRule `json:"rate_limit,omitempty"`
+	// Cache — кэширование GET-ответов маршрута (CACHE_*); nil — не кэшировать.
+	Cache *CacheConfig `json:"cache,omitempty"`
+	// Coalesce — одновременные одинаковые GET-запросы (метод, путь, query, вызывающий)
+	// обслуживаются одним запросом к upstream-у.
+	Coalesce bool `json:"coalesce,omitempty"`
+	// CORS — политика CORS маршрута (для его path); правила cors.rules той же специфичности важнее.
+	CORS *CORSPolicy `json:"cors,omitempty"`
+	// WebSocket — режим WebSocket-прокси: лимиты соединений, ping клиента, размер сообщений;
+	// простой (IdleTimeoutSec) считается по кадрам данных. nil — upgrade проксируется как есть.
+	WebSocket *WebSocketConfig `json:"websocket,omitempty"`
+}
+
+// WebSocketConfig — параметры WebSocket-соединений маршрута; нулевые поля берутся из WS_*.
+type WebSocketConfig struct {
+	// MaxConnsPerUser / MaxConnsPerIP — одновременные соединения маршрута на subject и на IP
+	// клиента (0 в WS_* — без лимита).
+	MaxConnsPerUser int `json:"max_conns_per_user,omitempty"`
+	MaxConnsPerIP   int `json:"max_conns_per_ip,omitempty"`
+	// PingIntervalSec — как часто gateway отправляет клиенту ping; PongTimeoutSec — сколько
+	// ждать ответа (любого кадра) до разрыва соединения (0 в WS_* — не отправлять / не ждать).
+	PingIntervalSec int `json:"ping_interval_sec,omitempty"`
+	PongTimeoutSec  int `json:"pong_timeout_sec,omitempty"`
+	// MaxMessageBytes — наибольшее сообщение клиента (с учётом фрагментов); при превышении
+	// соединение закрывается с кодом 1009 (0 в WS_* — без лимита).
+	MaxMessageBytes int `json:"max_message_bytes,omitempty"`
+}
+
+// CacheConfig — кэш ответов маршрута. Время свежести берётся из Cache-Control (s-maxage, max-age)
+// или Expires ответа; TTLSec — для ответов без них. Ответы аутентифицированным вызывающим
+// кэшируются отдельно для каждого subject.
+type CacheConfig struct {
+	// TTLSec — время свежести ответа без Cache-Control / Expires; 0 — такие ответы не кэшируются
+	// (кроме ответов с ETag / Last-Modified, которые перепроверяются у upstream-а).
+	TTLSec int `json:"ttl_sec,omitempty"`
+}
+
+// RetryConfig — политика повторов запроса к upstream-у. Повторяются только запросы с методами
+// из Methods после ошибки из Errors или ответа со статусом из Statuses; задержка — экспоненциальная
+// с jitter. Повторы ограничены бюджетом upstream-а (RETRY_BUDGET_*).
+type RetryConfig struct {
+	Disabled bool     `json:"disabled,omitempty"`
+	Attempts int      `json:"attempts,omitempty"` // число повторов после первой попытки
+	Methods  []string `json:"methods,omitempty"`
+	Statuses []int    `json:"statuses,omitempty"`
+	// Errors — ошибки транспорта: RetryOnConnect, RetryOnReset, RetryOnTimeout.
+	Errors      []string `json:"errors,omitempty"`
+	BaseDelayMs int      `json:"base_delay_ms,omitempty"`
+	MaxDelayMs  int      `json:"max_delay_ms,omitempty"`
+}
+
+// Ошибки транспорта, после которых возможен повтор.
+const (
+	RetryOnConnect = "connect" // не удалось установить соединение (запрос не отправлен)
+	RetryOnReset   = "reset"   // соединение сброшено/закрыто до ответа
+	RetryOnTimeout = "timeout" // таймаут сети
+)
+
+// Enabled — повторы включены.
+func (r *RetryConfig) Enabled() bool {
+	return r != nil && !r.Disabled && r.Attempts > 0
+}
+
+// Имена встроенных upstream-ов (совпадают с префиксами переменных окружения *_URL).
+const (
+	UpstreamUserService         = "user-service"
+	UpstreamOperatorDirectory   = "operator-directory"
+	UpstreamSessionManager      = "session-manager"
+	UpstreamTicketService       = "ticket-service"
+	UpstreamSearchService       = "search-service"
+	UpstreamOperatorPool        = "operator-pool"
+	UpstreamNotificationService = "notification-service"
+	UpstreamDataChannelService  = "data-channel-service"
+)
+
+// DefaultGateway возвращает встроенную таблицу маршрутов (прежняя ручная разводка NewRouter).
+func DefaultGateway(c *Config) *Gateway {
+	def := c.RateLimit.Default
+	corsDef := c.CORS
+	return &Gateway{
+		Upstreams: []UpstreamConfig{
+			{Name: UpstreamUserService, URL: c.UserServiceHTTPURL(), HealthPath: "/health"},
+			{Name: UpstreamOperatorDirectory, URL: c.OperatorDirectoryURL, HealthPath: "/health"},
+			{Name: UpstreamSessionManager, URL: c.SessionManagerURL, HealthPath: "/health"},
+			{Name: UpstreamTicketService, URL: c.TicketServiceURL, HealthPath: "/health"},
+			{Name: UpstreamSearchService, URL: c.SearchServiceURL, HealthPath: "/health"},
+			{Name: UpstreamOperatorPool, URL: c.OperatorPoolURL, HealthPath: "/health"},
+			{Name: UpstreamNotificationService, URL: c.NotificationServiceURL, HealthPath: "/health"},
+			{Name: UpstreamDataChannelService, URL: c.DataChannelServiceURL, HealthPath: "/health"},
+		},
+		Routes: []RouteConfig{
+			{Path: "/api/v1/auth/", Upstream: UpstreamUserService},
+			{Path: "/api/v1/users/", Upstream: UpstreamUserService},
+			{Path: "/api/v1/sessions/", Upstream: UpstreamUserService},
+			// user-service: operators/available, operators/stats, operators/availability, operators/{id}/availability
+			{Path: "/api/v1/operators/available", Upstream: UpstreamUserService},
+			{Path: "/api/v1/operators/stats", Upstream: UpstreamUserService},
+			{Path: "/api/v1/operators/availability", Upstream: UpstreamUserService},
+			{Path: "/api/v1/operators/*/availability", Upstream: UpstreamUserService},
+			// остальные /api/v1/operators[/{id}] — operator-directory
+			{Path: "/api/v1/operators", Upstream: UpstreamOperatorDirectory},
+			{Path: "/session/", Upstream: UpstreamSessionManager},
+			{Path: "/api/v1/tickets", Upstream: UpstreamTicketService},
+			{Path: "/search", Upstream: UpstreamSearchService},
+			{Path: "/operator/", Upstream: UpstreamOperatorPool},
+			{Path: "/notify/", Upstream: UpstreamNotificationService},
+			{Path: "/ws/notify/", Upstream: UpstreamNotificationService, Options: RouteOptions{WebSocket: &WebSocketConfig{}}},
+			{Path: "/data/", Upstream: UpstreamDataChannelService},
+			{Path: "/ws/data/", Upstream: UpstreamDataChannelService, Options: RouteOptions{WebSocket: &WebSocketConfig{}}},
+		},
+		RBAC:      DefaultRBAC(),
+		RateLimit: DefaultRateLimit(&def),
+		CORS:      DefaultCORS(&corsDef),
+	}
+}
+
+// LoadGateway загружает таблицу маршрутов: файл GATEWAY_CONFIG_FILE (если задан) или DefaultGateway,
+// затем применяет переопределения из окружения и валидирует результат.
+// reserved — пути, которые обслуживает сам gateway; маршрут не может их перекрывать.
+func LoadGateway(c *Config, reserved ...string) (*Gateway, error) {
+	gw := DefaultGateway(c)
+	if c.GatewayFile != "" {
+		data, err := os.ReadFile(c.GatewayFile)
+		if err != nil {
+			return nil, fmt.Errorf("read gateway config: %w", err)
+		}
+		gw = &Gateway{}
+		if err := json.Unmarshal(data, gw); err != nil {
+			return nil, fmt.Errorf("parse gateway config %s: %w", c.GatewayFile, err)
+		}
+		for i := range gw.Upstreams {
+			u := &gw.Upstreams[i]
+			if u.Name == UpstreamUserService && u.URL == "" && len(u.Targets) == 0 {
+				u.URL = c.UserServiceHTTPURL()
+			}
+		}
+		if gw.RBAC == nil {
+			gw.RBAC = DefaultRBAC()
+		}
+		if gw.RateLimit == nil {
+			def := c.RateLimit.Default
+			gw.RateLimit = DefaultRateLimit(&def)
+		} else if gw.RateLimit.Default == nil {
+			def := c.RateLimit.Default
+			gw.RateLimit.Default = &def
+		}
+		if gw.CORS == nil {
+			def := c.CORS
+			gw.CORS = DefaultCORS(&def)
+		} else if gw.CORS.Default == nil {
+			def := c.CORS
+			gw.CORS.Default = &def
+		}
+	}
+	gw.applyEnv()
+	for i := range gw.Upstreams {
+		gw.Upstreams[i].normalize(c)
+	}
+	for i := range gw.Routes {
+		gw.Routes[i].normalize(c)
+	}
+	gw.RBAC.Default = getEnv("RBAC_DEFAULT", gw.RBAC.Default)
+	gw.RBAC.normalize()
+	gw.RateLimit.normalize()
+	gw.CORS.normalize()
+	for i := range gw.Routes {
+		if p := gw.Routes[i].Options.CORS; p != nil {
+			p.inherit(gw.CORS.Default)
+		}
+	}
+	if err := gw.Validate(reserved...); err != nil {
+		return nil, fmt.Errorf("gateway config: %w", err)
+	}
+	return gw, nil
+}
+
+// UpstreamEnvPrefix возвращает префикс переменных окружения upstream-а: "ticket-service" -> TICKET_SERVICE.
+func UpstreamEnvPrefix(name string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+}
+
+func (g *Gateway) applyEnv() {
+	for i := range g.Upstreams {
+		u := &g.Upstreams[i]
+		prefix := UpstreamEnvPrefix(u.Name)
+		if v := os.Getenv(prefix + "_URL"); v != "" {
+			u.URL = ""
+			u.Targets = nil
+			for _, s := range strings.Split(v, ",") {
+				if s = strings.TrimSpace(s); s != "" {
+					u.Targets = append(u.Targets, TargetConfig{URL: s})
+				}
+			}
+		}
+		u.Policy = getEnv(prefix+"_LB_POLICY", u.Policy)
+		u.HashOn = getEnv(prefix+"_LB_HASH_ON", u.HashOn)
+		u.HealthPath = getEnv(prefix+"_HEALTH_PATH", u.HealthPath)
+		if ca, cert := os.Getenv(prefix+"_TLS_CA_FILE"), os.Getenv(prefix+"_TLS_CERT_FILE"); ca != "" || cert != "" {
+			u.TLS = &TLSConfig{
+				CAFile:     ca,
+				CertFile:   cert,
+				KeyFile:    os.Getenv(prefix + "_TLS_KEY_FILE"),
+				ServerName: os.Getenv(prefix + "_TLS_SERVER_NAME"),
+			}
+		}
+	}
+}
+
+// normalize сводит URL к списку Targets и проставляет значения по умолчанию.
+func (u *UpstreamConfig) normalize(c *Config) {
+	if u.URL != "" && len(u.Targets) == 0 {
+		u.Targets = []TargetConfig{{URL: u.URL}}
+	}
+	u.URL = ""
+	for i := range u.Targets {
+		if u.Targets[i].Weight == 0 {
+			u.Targets[i].Weight = 1
+		}
+	}
+	if u.Policy == "" {
+		u.Policy = LBRoundRobin
+	}
+	cb := c.CircuitBreaker
+	if o := u.CircuitBreaker; o != nil {
+		cb.Disabled = o.Disabled
+		for _, f := range []struct{ dst, src *int }{
+			{&cb.Failures, &o.Failures}, {&cb.ErrorRatePct, &o.ErrorRatePct}, {&cb.MinRequests, &o.MinRequests},
+			{&cb.WindowSec, &o.WindowSec}, {&cb.OpenSec, &o.OpenSec}, {&cb.HalfOpenRequests, &o.HalfOpenRequests},
+		} {
+			if *f.src != 0 {
+				*f.dst = *f.src
+			}
+		}
+	}
+	u.CircuitBreaker = &cb
+	if u.RetryBudget == nil {
+		rb := c.RetryBudget
+		u.RetryBudget = &rb
+	}
+}
+
+// normalize подставляет в параметры маршрута значения по умолчанию из окружения.
+func (r *RouteConfig) normalize(c *Config) {
+	rc := c.Retry
+	if o := r.Options.Retry; o != nil {
+		rc.Disabled = o.Disabled
+		if o.Attempts != 0 {
+			rc.Attempts = o.Attempts
+		}
+		if len(o.Methods) > 0 {
+			rc.Methods = o.Methods
+		}
+		if len(o.Statuses) > 0 {
+			rc.Statuses = o.Statuses
+		}
+		if len(o.Errors) > 0 {
+			rc.Errors = o.Errors
+		}
+		if o.BaseDelayMs != 0 {
+			rc.BaseDelayMs = o.BaseDelayMs
+		}
+		if o.MaxDelayMs != 0 {
+			rc.MaxDelayMs = o.MaxDelayMs
+		}
+	}
+	r.Options.Retry = &rc
+	if r.Options.TimeoutMs == 0 {
+		r.Options.TimeoutMs = c.ProxyTimeoutMs
+	}
+	if r.Options.IdleTimeoutSec == 0 {
+		r.Options.IdleTimeoutSec = c.ProxyIdleTimeoutSec
+	}
+	if r.Options.RateLimit != nil {
+		r.Options.RateLimit.normalize()
+	}
+	if o := r.Options.WebSocket; o != nil {
+		ws := c.WebSocket
+		for _, f := range []struct{ dst, src *int }{
+			{&ws.MaxConnsPerUser, &o.MaxConnsPerUser}, {&ws.MaxConnsPerIP, &o.MaxConnsPerIP},
+			{&ws.PingIntervalSec, &o.PingIntervalSec}, {&ws.PongTimeoutSec, &o.PongTimeoutSec},
+			{&ws.MaxMessageBytes, &o.MaxMessageBytes},
+		} {
+			if *f.src != 0 {
+				*f.dst = *f.src
+			}
+		}
+		r.Options.WebSocket = &ws
+	}
+}
+
+// Enabled — breaker включён и задано хотя бы одно условие размыкания.
+func (b CircuitBreakerConfig) Enabled() bool {
+	return !b.Disabled && (b.Failures > 0 || b.ErrorRatePct > 0)
+}
+
+// Enabled — у upstream-а есть хотя бы один экземпляр.
+func (u UpstreamConfig) Enabled() bool {
+	return u.URL != "" || len(u.Targets) > 0
+}
+
+// Upstream возвращает upstream по имени.
+func (g *Gateway) Upstream(name string) (UpstreamConfig, bool) {
+	for _, u := range g.Upstreams {
+		if u.Name == name {
+			return u, true
+		}
+	}
+	return UpstreamConfig{}, false
+}
+
+// Validate проверяет таблицу: имена upstream-ов, URL, методы, дубликаты и пересечения путей.
+// Возвращает все найденные ошибки сразу.
+func (g *Gateway) Validate(reserved ...string) error {
+	var errs []error
+	names := make(map[string]bool, len(g.Upstreams))
+	for i, u := range g.Upstreams {
+		if u.Name == "" {
+			errs = append(errs, fmt.Errorf("upstreams[%d]: empty name", i))
+			continue
+		}
+		if names[u.Name] {
+			errs = append(errs, fmt.Errorf("upstream %q: duplicate name", u.Name))
+		}
+		names[u.Name] = true
+		for _, t := range u.Targets {
+			if pu, err := url.Parse(t.URL); err != nil || pu.Host == "" || (pu.Scheme != "http" && pu.Scheme != "https") {
+				errs = append(errs, fmt.Errorf("upstream %q: invalid url %q", u.Name, t.URL))
+			}
+			if t.Weight < 0 {
+				errs = append(errs, fmt.Errorf("upstream %q: negative weight for %q", u.Name, t.URL))
+			}
+		}
+		if u.HealthPath != "" && !strings.HasPrefix(u.HealthPath, "/") {
+			errs = append(errs, fmt.Errorf("upstream %q: health_path must start with /", u.Name))
+		}
+		if cb := u.CircuitBreaker; cb != nil {
+			if cb.Failures < 0 || cb.MinRequests < 0 || cb.WindowSec < 0 || cb.OpenSec < 0 || cb.HalfOpenRequests < 0 {
+				errs = append(errs, fmt.Errorf("upstream %q: circuit_breaker values must not be negative", u.Name))
+			}
+			if cb.ErrorRatePct < 0 || cb.ErrorRatePct > 100 {
+				errs = append(errs, fmt.Errorf("upstream %q: circuit_breaker error_rate_pct must be 0..100", u.Name))
+			}
+		}
+		if rb := u.RetryBudget; rb != nil && (rb.Percent < 0 || rb.MinConcurrent < 0) {
+			errs = append(errs, fmt.Errorf("upstream %q: retry_budget values must not be negative", u.Name))
+		}
+		if u.TLS != nil {
+			if err := u.TLS.Validate(false); err != nil {
+				errs = append(errs, fmt.Errorf("upstream %q: tls: %w", u.Name, err))
+			}
+		}
+		switch u.Policy {
+		case "", LBRoundRobin, LBWeighted, LBLeastRequests:
+		case LBConsistentHash:
+			if kind, key, _ := strings.Cut(u.HashOn, ":"); (kind != "header" && kind != "cookie") || key == "" {
+				errs = append(errs, fmt.Errorf("upstream %q: hash_on must be header:<name> or cookie:<name>, got %q", u.Name, u.HashOn))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("upstream %q: unknown policy %q", u.Name, u.Policy))
+		}
+	}
+
+	for i, r := range g.Routes {
+		where := fmt.Sprintf("routes[%d] %q", i, r.Path)
+		if !strings.HasPrefix(r.Path, "/") {
+			errs = append(errs, fmt.Errorf("%s: path must start with /", where))
+			continue
+		}
+		if strings.Contains(r.Path, "//") {
+			errs = append(errs, fmt.Errorf("%s: empty path segment", where))
+		}
+		if !names[r.Upstream] {
+			errs = append(errs, fmt.Errorf("%s: unknown upstream %q", where, r.Upstream))
+		}
+		if r.Rewrite != "" && !strings.HasPrefix(r.Rewrite, "/") {
+			errs = append(errs, fmt.Errorf("%s: rewrite must start with /", where))
+		}
+		for _, m := range r.Methods {
+			if !validMethod(m) {
+				errs = append(errs, fmt.Errorf("%s: unsupported method %q", where, m))
+			}
+		}
+		if r.Options.TimeoutMs < 0 || r.Options.IdleTimeoutSec < 0 {
+			errs = append(errs, fmt.Errorf("%s: timeouts must not be negative", where))
+		}
+		if rc := r.Options.Retry; rc != nil {
+			errs = append(errs, rc.validate(where)...)
+		}
+		if c := r.Options.Cache; c != nil {
+			if c.TTLSec < 0 {
+				errs = append(errs, fmt.Errorf("%s: cache ttl_sec must not be negative", where))
+			}
+			if len(r.Methods) > 0 && !slices.Contains(r.Methods, http.MethodGet) {
+				errs = append(errs, fmt.Errorf("%s: cache requires GET among route methods", where))
+			}
+		}
+		if p := r.Options.CORS; p != nil {
+			if p.Path != "" {
+				errs = append(errs, fmt.Errorf("%s: cors takes path from the route", where))
+			}
+			errs = append(errs, p.validate(where+" cors")...)
+		}
+		if ws := r.Options.WebSocket; ws != nil {
+			if ws.MaxConnsPerUser < 0 || ws.MaxConnsPerIP < 0 || ws.PingIntervalSec < 0 || ws.PongTimeoutSec < 0 || ws.MaxMessageBytes < 0 {
+				errs = append(errs, fmt.Errorf("%s: websocket limits must not be negative", where))
+			}
+			if len(r.Methods) > 0 && !slices.Contains(r.Methods, http.MethodGet) {
+				errs = append(errs, fmt.Errorf("%s: websocket requires GET among route methods", where))
+			}
+		}
+		if r.Options.Coalesce && len(r.Methods) > 0 && !slices.Contains(r.Methods, http.MethodGet) {
+			errs = append(errs, fmt.Errorf("%s: coalesce requires GET among route methods", where))
+		}
+		if rl := r.Options.RateLimit; rl != nil {
+			if rl.Path != "" || rl.GRPC != "" || len(rl.Methods) > 0 {
+				errs = append(errs, fmt.Errorf("%s: rate_limit takes path and methods from the route", where))
+			}
+			errs = append(errs, rl.validate(where+" rate_limit")...)
+		}
+		for _, p := range reserved {
+			if pathsOverlap(r.Path, p) {
+				errs = append(errs, fmt.Errorf("%s: conflicts with gateway path %q", where, p))
+			}
+		}
+		for j := 0; j < i; j++ {
+			o := g.Routes[j]
+			if samePattern(r.Path, o.Path) && methodsOverlap(r.Methods, o.Methods) {
+				errs = append(errs, fmt.Errorf("%s: duplicates routes[%d] %q", where, j, o.Path))
+			}
+		}
+	}
+	if g.RBAC != nil {
+		errs = append(errs, g.RBAC.Validate()...)
+	}
+	if g.RateLimit != nil {
+		errs = append(errs, g.RateLimit.Validate()...)
+	}
+	if g.CORS != nil {
+		errs = append(errs, g.CORS.Validate()...)
+	}
+	return errors.Join(errs...)
+}
+
+func (r *RetryConfig) validate(where string) []error {
+	var errs []error
+	if r.Attempts < 0 || r.BaseDelayMs < 0 || r.MaxDelayMs < 0 {
+		errs = append(errs, fmt.Errorf("%s: retry values must not be negative", where))
+	}
+	for _, m := range r.Methods {
+		if !validMethod(m) {
+			errs = append(errs, fmt.Errorf("%s: retry: unsupported method %q", where, m))
+		}
+	}
+	for _, st := range r.Statuses {
+		if st < 100 || st > 599 {
+			errs = append(errs, fmt.Errorf("%s: retry: invalid status %d", where, st))
+		}
+	}
+	for _, e := range r.Errors {
+		switch e {
+		case RetryOnConnect, RetryOnReset, RetryOnTimeout:
+		default:
+			errs = append(errs, fmt.Errorf("%s: retry: unknown error kind %q", where, e))
+		}
+	}
+	return errs
+}
+
+func validMethod(m string) bool {
+	switch m {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// SplitPath разбивает путь на сегменты без пустых ("/a/b/" -> [a b]).
+func SplitPath(p string) []string {
+	return strings.FieldsFunc(p, func(r rune) bool { return r == '/' })
+}
+
+// samePattern — пути с одинаковыми сегментами совпадают с одним и тем же множеством запросов
+// (с точностью до "/x" и "/x/", которые ServeMux тоже считает пересекающимися).
+func samePattern(a, b string) bool {
+	as, bs := SplitPath(a), SplitPath(b)
+	if len(as) != len(bs) {
+		return false
+	}
+	for i := range as {
+		if as[i] != bs[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// pathsOverlap — true, если один путь является префиксом другого (с учётом "*").
+func pathsOverlap(a, b string) bool {
+	as, bs := SplitPath(a), SplitPath(b)
+	n := min(len(as), len(bs))
+	for i := 0; i < n; i++ {
+		if as[i] != bs[i] && as[i] != "*" && bs[i] != "*" {
+			return false
+		}
+	}
+	return true
+}
+
+func methodsOverlap(a, b []string) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return true
+	}
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGatewayValidateRoutes(t *testing.T) {
+	reserved := []string{"/health", "/api/v1/video/"}
+	tests := []struct {
+		name    string
+		routes  []RouteConfig
+		wantErr string // "" — без ошибок
+	}{
+		{"distinct", []RouteConfig{{Path: "/a"}, {Path: "/a/b"}, {Path: "/a/*/c"}}, ""},
+		{"same path, disjoint methods", []RouteConfig{
+			{Path: "/a", Methods: []string{"GET"}}, {Path: "/a", Methods: []string{"POST"}},
+		}, ""},
+		{"duplicate", []RouteConfig{{Path: "/a/b"}, {Path: "/a/b"}}, `routes[1] "/a/b": duplicates routes[0]`},
+		{"duplicate with trailing slash", []RouteConfig{{Path: "/a/"}, {Path: "/a"}}, "duplicates routes[0]"},
+		{"duplicate, overlapping methods", []RouteConfig{
+			{Path: "/a", Methods: []string{"GET", "POST"}}, {Path: "/a", Methods: []string{"POST"}},
+		}, "duplicates routes[0]"},
+		{"duplicate, any method", []RouteConfig{{Path: "/a", Methods: []string{"GET"}}, {Path: "/a"}}, "duplicates routes[0]"},
+		{"reserved subtree", []RouteConfig{{Path: "/api/v1/video/frames"}}, `conflicts with gateway path "/api/v1/video/"`},
+		{"reserved parent", []RouteConfig{{Path: "/api/v1"}}, `conflicts with gateway path "/api/v1/video/"`},
+		{"reserved via wildcard", []RouteConfig{{Path: "/*/v1/video"}}, `conflicts with gateway path "/api/v1/video/"`},
+		{"relative path", []RouteConfig{{Path: "a"}}, "path must start with /"},
+		{"empty segment", []RouteConfig{{Path: "/a//b"}}, "empty path segment"},
+		{"unknown upstream", []RouteConfig{{Path: "/a", Upstream: "missing"}}, `unknown upstream "missing"`},
+		{"bad method", []RouteConfig{{Path: "/a", Methods: []string{"TRACE"}}}, `unsupported method "TRACE"`},
+		{"bad rewrite", []RouteConfig{{Path: "/a", Rewrite: "b"}}, "rewrite must start with /"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := range tt.routes {
+				if tt.routes[i].Upstream == "" {
+					tt.routes[i].Upstream = "backend"
+				}
+			}
+			gw := &Gateway{
+				Upstreams: []UpstreamConfig{{Name: "backend", Targets: []TargetConfig{{URL: "http://backend:8080"}}}},
+				Routes:    tt.routes,
+			}
+			err := gw.Validate(reserved...)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tt.wantErr != "" && err == nil:
+				t.Fatalf("no error, want %q", tt.wantErr)
+			case tt.wantErr != "" && !strings.Contains(err.Error(), tt.wantErr):
+				t.Fatalf("error %q, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGatewayValidateUpstreams(t *testing.T) {
+	tests := []struct {
+		name     string
+		upstream UpstreamConfig
+		wantErr  string
+	}{
+		{"ok", UpstreamConfig{Name: "a", Targets: []TargetConfig{{URL: "https://a:8443"}}}, ""},
+		{"bad scheme", UpstreamConfig{Name: "a", Targets: []TargetConfig{{URL: "ftp://a"}}}, `invalid url "ftp://a"`},
+		{"no host", UpstreamConfig{Name: "a", Targets: []TargetConfig{{URL: "http://"}}}, "invalid url"},
+		{"empty name", UpstreamConfig{Targets: []TargetConfig{{URL: "http://a"}}}, "empty name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gw := &Gateway{Upstreams: []UpstreamConfig{tt.upstream}}
+			err := gw.Validate()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	dup := &Gateway{Upstreams: []UpstreamConfig{{Name: "a"}, {Name: "a"}}}
+	if err := dup.Validate(); err == nil || !strings.Contains(err.Error(), `upstream "a": duplicate name`) {
+		t.Fatalf("duplicate upstream: error %v", err)
+	}
+}
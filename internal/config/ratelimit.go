@@ -0,0 +1,127 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RateLimit — лимиты запросов (token bucket): лимит по умолчанию и правила для HTTP-путей
+// и gRPC-методов. Задаётся секцией "rate_limit" в GATEWAY_CONFIG_FILE; лимит маршрута прокси
+// можно задать и в его options.rate_limit. Без default в файле действует RATE_LIMIT_*.
+type RateLimit struct {
+	// Default — лимит запросов, не совпавших ни с одним правилом; nil — без лимита.
+	Default *RateLimitRule  `json:"default,omitempty"`
+	Rules   []RateLimitRule `json:"rules,omitempty"`
+}
+
+// RateLimitRule — лимит: RPS токенов в секунду, не больше Burst подряд. Ведро токенов
+// заводится на каждое значение ключа Key. Задаётся не больше одного из Path и GRPC
+// (синтаксис как у config.PolicyConfig); в default и options.rate_limit — ни одного.
+type RateLimitRule struct {
+	Path     string   `json:"path,omitempty"`
+	Methods  []string `json:"methods,omitempty"`
+	GRPC     string   `json:"grpc,omitempty"`
+	Disabled bool     `json:"disabled,omitempty"` // не ограничивать совпавшие запросы
+	RPS      float64  `json:"rps,omitempty"`
+	Burst    int      `json:"burst,omitempty"` // 0 = ceil(RPS)
+	Key      string   `json:"key,omitempty"`   // RateLimitBy*; пусто = RateLimitByUser
+}
+
+// Ключи лимита.
+const (
+	RateLimitByIP     = "ip"      // IP клиента
+	RateLimitByUser   = "user"    // subject вызывающего; анонимные — по IP
+	RateLimitByAPIKey = "api_key" // id API-ключа; без ключа — как RateLimitByUser
+	RateLimitByRoute  = "route"   // одно ведро на правило (общий лимит маршрута)
+)
+
+// Хранилища вёдер (RATE_LIMIT_BACKEND).
+const (
+	RateLimitBackendLocal = "local"
+	RateLimitBackendRedis = "redis"
+)
+
+// DefaultRateLimit возвращает встроенные лимиты: def (RATE_LIMIT_*) и демонстрационный
+// /v1/limits/rate-limited — 5 запросов в секунду с IP.
+func DefaultRateLimit(def *RateLimitRule) *RateLimit {
+	demo := RateLimitRule{RPS: 5, Burst: 5, Key: RateLimitByIP}
+	rl := &RateLimit{Default: def}
+	for _, p := range []string{"/v1/limits/rate-limited", "/api/v1/limits/rate-limited"} {
+		r := demo
+		r.Path = p
+		rl.Rules = append(rl.Rules, r)
+	}
+	return rl
+}
+
+func (r *RateLimitRule) normalize() {
+	if r.Burst == 0 && r.RPS > 0 {
+		r.Burst = int(r.RPS)
+		if float64(r.Burst) < r.RPS {
+			r.Burst++
+		}
+	}
+	if r.Key == "" {
+		r.Key = RateLimitByUser
+	}
+}
+
+func (r *RateLimitRule) validate(where string) []error {
+	var errs []error
+	if r.Disabled {
+		return nil
+	}
+	if r.RPS <= 0 {
+		errs = append(errs, fmt.Errorf("%s: rps must be > 0", where))
+	}
+	if r.Burst < 0 {
+		errs = append(errs, fmt.Errorf("%s: burst must not be negative", where))
+	}
+	switch r.Key {
+	case RateLimitByIP, RateLimitByUser, RateLimitByAPIKey, RateLimitByRoute:
+	default:
+		errs = append(errs, fmt.Errorf("%s: unknown key %q", where, r.Key))
+	}
+	return errs
+}
+
+func (rl *RateLimit) normalize() {
+	if rl.Default != nil {
+		rl.Default.normalize()
+	}
+	for i := range rl.Rules {
+		rl.Rules[i].normalize()
+	}
+}
+
+// Validate проверяет лимиты.
+func (rl *RateLimit) Validate() []error {
+	var errs []error
+	if d := rl.Default; d != nil {
+		if d.Path != "" || d.GRPC != "" || len(d.Methods) > 0 {
+			errs = append(errs, fmt.Errorf("rate_limit.default: path, methods and grpc are not allowed"))
+		}
+		errs = append(errs, d.validate("rate_limit.default")...)
+	}
+	for i, r := range rl.Rules {
+		where := fmt.Sprintf("rate_limit.rules[%d]", i)
+		switch {
+		case (r.Path == "") == (r.GRPC == ""):
+			errs = append(errs, fmt.Errorf("%s: exactly one of path and grpc is required", where))
+		case r.Path != "" && !strings.HasPrefix(r.Path, "/"):
+			errs = append(errs, fmt.Errorf("%s: path must start with /", where))
+		case r.GRPC != "" && !strings.HasPrefix(r.GRPC, "/"):
+			errs = append(errs, fmt.Errorf("%s: grpc method must start with /", where))
+		}
+		if r.GRPC != "" && len(r.Methods) > 0 {
+			errs = append(errs, fmt.Errorf("%s: methods apply to path rules only", where))
+		}
+		for _, m := range r.Methods {
+			if !validMethod(m) {
+				errs = append(errs, fmt.Errorf("%s: unsupported method %q", where, m))
+			}
+		}
+		errs = append(errs, r.validate(where)...)
+	}
+	return errs
+}
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/psds-microservice/api-gateway/pkg/constants"
+)
+
+// RBAC — политики доступа по ролям (pkg/constants Role*): разрешения ролей и правила
+// для HTTP-путей и gRPC-методов. Задаётся секцией "rbac" в GATEWAY_CONFIG_FILE;
+// без неё используется DefaultRBAC.
+type RBAC struct {
+	// Default — решение для запроса, не совпавшего ни с одним правилом: RBACAllow или RBACDeny.
+	Default string `json:"default,omitempty"`
+	// RolePermissions — разрешения ролей; "*" — любые.
+	RolePermissions map[string][]string `json:"role_permissions,omitempty"`
+	Policies        []PolicyConfig      `json:"policies"`
+}
+
+// PolicyConfig — правило доступа. Задаётся ровно одно из Path (синтаксис как у маршрутов)
+// и GRPC (полное имя метода "/pkg.Service/Method" или префикс сервиса "/pkg.Service/").
+// Вызывающему нужна любая из Roles и все Permissions. При пересечении действует
+// наиболее специфичное правило.
+type PolicyConfig struct {
+	Path        string   `json:"path,omitempty"`
+	Methods     []string `json:"methods,omitempty"` // для Path; пусто = любые
+	GRPC        string   `json:"grpc,omitempty"`
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+}
+
+// Решения RBAC по умолчанию.
+const (
+	RBACAllow = "allow"
+	RBACDeny  = "deny"
+)
+
+// Разрешения встроенной политики.
+const (
+	PermVideoStream = "video:stream"
+	PermVideoRead   = "video:read"
+	PermClientsRead = "clients:read"
+)
+
+// DefaultRBAC возвращает встроенную политику: публикация видео требует video:stream,
+// активные стримы видят операторы, список активных клиентов и admin API — только администраторы.
+func DefaultRBAC() *RBAC {
+	staff := []string{constants.RoleOperator, constants.RoleAdmin}
+	stream := []string{PermVideoStream}
+	return &RBAC{
+		Default: RBACAllow,
+		RolePermissions: map[string][]string{
+			constants.RoleClient:   {PermVideoStream},
+			constants.RoleOperator: {PermVideoStream, PermVideoRead, PermClientsRead},
+			constants.RoleAdmin:    {"*"},
+		},
+		Policies: []PolicyConfig{
+			{Path: constants.BasePathAPI + constants.PathVideoStart, Permissions: stream},
+			{Path: constants.BasePathAPI + constants.PathVideoFrame, Permissions: stream},
+			{Path: constants.BasePathAPI + constants.PathVideoStop, Permissions: stream},
+			{GRPC: "/video_stream.VideoStreamService/StartStream", Permissions: stream},
+			{GRPC: "/video_stream.VideoStreamService/SendFrame", Permissions: stream},
+			{GRPC: "/video_stream.VideoStreamService/StreamVideo", Permissions: stream},
+			{GRPC: "/video_stream.VideoStreamService/StopStream", Permissions: stream},
+			{Path: constants.BasePathAPI + constants.PathVideoActive, Methods: []string{"GET"}, Roles: staff},
+			{GRPC: "/video_stream.VideoStreamService/GetActiveStreams", Roles: staff},
+			{Path: constants.BasePathAPI + constants.PathVideoAllStats, Permissions: []string{PermVideoRead}},
+			{GRPC: "/video_stream.VideoStreamService/GetAllStats", Permissions: []string{PermVideoRead}},
+			{Path: constants.BasePathAPI + constants.PathClientsActive, Roles: []string{constants.RoleAdmin}},
+			{GRPC: "/client_info.ClientInfoService/ListActiveClients", Roles: []string{constants.RoleAdmin}},
+			{Path: constants.BasePathAPI + constants.PathAdmin, Roles: []string{constants.RoleAdmin}},
+		},
+	}
+}
+
+func (r *RBAC) normalize() {
+	if r.Default == "" {
+		r.Default = RBACAllow
+	}
+}
+
+// Validate проверяет правила RBAC.
+func (r *RBAC) Validate() []error {
+	var errs []error
+	if r.Default != RBACAllow && r.Default != RBACDeny {
+		errs = append(errs, fmt.Errorf("rbac: default must be %q or %q", RBACAllow, RBACDeny))
+	}
+	for i, p := range r.Policies {
+		where := fmt.Sprintf("rbac.policies[%d]", i)
+		switch {
+		case (p.Path == "") == (p.GRPC == ""):
+			errs = append(errs, fmt.Errorf("%s: exactly one of path and grpc is required", where))
+		case p.Path != "" && !strings.HasPrefix(p.Path, "/"):
+			errs = append(errs, fmt.Errorf("%s: path must start with /", where))
+		case p.GRPC != "" && !strings.HasPrefix(p.GRPC, "/"):
+			errs = append(errs, fmt.Errorf("%s: grpc method must start with /", where))
+		}
+		if p.GRPC != "" && len(p.Methods) > 0 {
+			errs = append(errs, fmt.Errorf("%s: methods apply to path policies only", where))
+		}
+		for _, m := range p.Methods {
+			if !validMethod(m) {
+				errs = append(errs, fmt.Errorf("%s: unsupported method %q", where, m))
+			}
+		}
+		if len(p.Roles) == 0 && len(p.Permissions) == 0 {
+			errs = append(errs, fmt.Errorf("%s: roles or permissions are required", where))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Проверка клиентских сертификатов сервером (TLS_CLIENT_AUTH).
+const (
+	TLSClientAuthNone     = "none"
+	TLSClientAuthOptional = "optional" // проверяется, если клиент его предъявил
+	TLSClientAuthRequire  = "require"  // mTLS: без сертификата соединение отклоняется
+)
+
+// TLSConfig — TLS одной стороны соединения (файлы PEM; перечитываются при изменении, см. internal/certs).
+// Сервер: CertFile/KeyFile — сертификат gateway, CAFile — CA клиентских сертификатов для ClientAuth.
+// Клиент: CAFile — CA сервера (пусто — системные), CertFile/KeyFile — клиентский сертификат (mTLS),
+// ServerName — имя в сертификате сервера, если оно отличается от хоста в адресе.
+type TLSConfig struct {
+	CertFile   string `json:"cert_file,omitempty"`
+	KeyFile    string `json:"key_file,omitempty"`
+	CAFile     string `json:"ca_file,omitempty"`
+	ServerName string `json:"server_name,omitempty"`
+	// ClientAuth — только для сервера: TLSClientAuthNone (по умолчанию), Optional или Require.
+	ClientAuth string `json:"-"`
+	// MinVersion — "1.2" (по умолчанию) или "1.3".
+	MinVersion string `json:"min_version,omitempty"`
+}
+
+// Validate проверяет настройки: server — конфигурация сервера (сертификат обязателен).
+func (t *TLSConfig) Validate(server bool) error {
+	var errs []error
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		errs = append(errs, errors.New("cert_file and key_file must be set together"))
+	}
+	if server && t.CertFile == "" {
+		errs = append(errs, errors.New("cert_file is required"))
+	}
+	switch t.MinVersion {
+	case "", "1.2", "1.3":
+	default:
+		errs = append(errs, fmt.Errorf("unsupported min_version %q (want 1.2 or 1.3)", t.MinVersion))
+	}
+	switch t.ClientAuth {
+	case "", TLSClientAuthNone:
+	case TLSClientAuthOptional, TLSClientAuthRequire:
+		if t.CAFile == "" {
+			errs = append(errs, fmt.Errorf("client auth %q requires a CA file", t.ClientAuth))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown client auth %q", t.ClientAuth))
+	}
+	return errors.Join(errs...)
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"time"
 
+	"github.com/psds-microservice/api-gateway/internal/clientip"
+	"github.com/psds-microservice/api-gateway/internal/requestid"
 	pb "github.com/psds-microservice/api-gateway/pkg/gen"
 	"go.uber.org/zap"
 )
@@ -31,11 +33,25 @@ func NewClientInfoService(logger *zap.Logger, repo ClientStore) *ClientInfoServi
 	}
 }
 
+// ClientConnected регистрирует клиента. IP берётся из запроса (clientip), а не из тела:
+// присланный клиентом ip_address подделывается так же легко, как X-Forwarded-For.
 func (s *ClientInfoServiceImpl) ClientConnected(ctx context.Context, req *pb.ConnectionEvent) (*pb.ApiResponse, error) {
-	s.logger.Info("Client connected",
+	if ip, ok := clientip.FromContext(ctx); ok {
+		if req.IpAddress != "" && req.IpAddress != ip {
+			s.log(ctx).Debug("Reported client IP replaced by resolved one",
+				zap.String("client_id", req.ClientId),
+				zap.String("reported_ip", req.IpAddress),
+				zap.String("ip", ip))
+		}
+		req.IpAddress = ip
+		if req.ClientInfo != nil {
+			req.ClientInfo.IpAddress = ip
+		}
+	}
+	s.log(ctx).Info("Client connected",
 		zap.String("client_id", req.ClientId),
 		zap.String("ip", req.IpAddress))
-	s.repo.SaveClient(req.ClientInfo)
+	s.repo.SaveClient(ctx, req.ClientInfo)
 	return &pb.ApiResponse{
 		Status:    "ok",
 		Message:   "Client connected successfully",
@@ -44,8 +60,8 @@ func (s *ClientInfoServiceImpl) ClientConnected(ctx context.Context, req *pb.Con
 }
 
 func (s *ClientInfoServiceImpl) ClientDisconnected(ctx context.Context, req *pb.ConnectionEvent) (*pb.ApiResponse, error) {
-	s.logger.Info("Client disconnected", zap.String("client_id", req.ClientId))
-	s.repo.RemoveClient(req.ClientId)
+	s.log(ctx).Info("Client disconnected", zap.String("client_id", req.ClientId))
+	s.repo.RemoveClient(ctx, req.ClientId)
 	return &pb.ApiResponse{
 		Status:    "ok",
 		Message:   "Client disconnected",
@@ -54,9 +70,9 @@ func (s *ClientInfoServiceImpl) ClientDisconnected(ctx context.Context, req *pb.
 }
 
 func (s *ClientInfoServiceImpl) UpdateClientInfo(ctx context.Context, req *pb.UpdateClientRequest) (*pb.ApiResponse, error) {
-	s.logger.Info("Updating client info", zap.String("client_id", req.ClientId))
+	s.log(ctx).Info("Updating client info", zap.String("client_id", req.ClientId))
 	if req.ClientInfo != nil {
-		s.repo.SaveClient(req.ClientInfo)
+		s.repo.SaveClient(ctx, req.ClientInfo)
 	}
 	return &pb.ApiResponse{
 		Status:    "ok",
@@ -66,13 +82,13 @@ func (s *ClientInfoServiceImpl) UpdateClientInfo(ctx context.Context, req *pb.Up
 }
 
 func (s *ClientInfoServiceImpl) GetClientInfo(ctx context.Context, req *pb.GetClientInfoRequest) (*pb.ClientInfo, error) {
-	s.logger.Debug("Getting client info", zap.String("client_id", req.ClientId))
-	return s.repo.GetClient(req.ClientId), nil
+	s.log(ctx).Debug("Getting client info", zap.String("client_id", req.ClientId))
+	return s.repo.GetClient(ctx, req.ClientId), nil
 }
 
 func (s *ClientInfoServiceImpl) ListActiveClients(ctx context.Context, req *pb.ListClientsRequest) (*pb.ListClientsResponse, error) {
-	s.logger.Debug("Listing active clients")
-	allClients := s.repo.GetAllClients()
+	s.log(ctx).Debug("Listing active clients")
+	allClients := s.repo.GetAllClients(ctx)
 	page := int(req.Page)
 	limit := int(req.Limit)
 	totalClients := len(allClients)
@@ -94,3 +110,8 @@ func (s *ClientInfoServiceImpl) ListActiveClients(ctx context.Context, req *pb.L
 		Total:   int32(totalClients),
 	}, nil
 }
+
+// log — логгер с request_id вызова.
+func (s *ClientInfoServiceImpl) log(ctx context.Context) *zap.Logger {
+	return requestid.Logger(ctx, s.logger)
+}
@@ -66,6 +66,13 @@ func (r *ClientRepository) GetClient(ctx context.Context, clientID string) *pb.C
 	return proto.Clone(c).(*pb.ClientInfo)
 }
 
+// Len — число клиентов в репозитории.
+func (r *ClientRepository) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.clients)
+}
+
 func (r *ClientRepository) RemoveClient(ctx context.Context, clientID string) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -190,6 +197,13 @@ func (r *StreamRepository) GetAllStreams(ctx context.Context) []*pb.ActiveStream
 	return out
 }
 
+// Len — число стримов в репозитории.
+func (r *StreamRepository) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.streams)
+}
+
 func (r *StreamRepository) RemoveStream(ctx context.Context, streamID string) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
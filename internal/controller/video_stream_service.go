@@ -6,8 +6,12 @@ import (
 	"sync"
 	"time"
 
+	"github.com/psds-microservice/api-gateway/internal/auth"
 	"github.com/psds-microservice/api-gateway/internal/errors"
 	"github.com/psds-microservice/api-gateway/internal/grpc_client"
+	"github.com/psds-microservice/api-gateway/internal/metrics"
+	"github.com/psds-microservice/api-gateway/internal/requestid"
+	"github.com/psds-microservice/api-gateway/pkg/constants"
 	pb "github.com/psds-microservice/api-gateway/pkg/gen"
 	"go.uber.org/zap"
 )
@@ -43,15 +47,24 @@ func NewVideoStreamService(logger *zap.Logger, repo StreamStore, userClient grpc
 	}
 }
 
+// StartStream проверяет пользователя в user-service в пределах дедлайна входящего вызова (ctx).
 func (s *VideoStreamServiceImpl) StartStream(ctx context.Context, req *pb.StartStreamRequest) (*pb.StartStreamResponse, error) {
-	s.logger.Info("Starting stream",
+	s.log(ctx).Info("Starting stream",
 		zap.String("client_id", req.ClientId),
-		zap.String("camera", req.CameraName))
+		zap.String("camera", req.CameraName),
+		zap.String("subject", auth.Subject(ctx)))
+
+	if err := s.checkClient(ctx, req.ClientId); err != nil {
+		return nil, err
+	}
 
 	userName := req.UserId
 	if s.userClient != nil {
 		user, err := s.userClient.GetUserByClientID(ctx, req.ClientId)
 		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
 			return nil, fmt.Errorf("user not found or unauthorized: %w", err)
 		}
 		userName = user.Username
@@ -71,7 +84,7 @@ func (s *VideoStreamServiceImpl) StartStream(ctx context.Context, req *pb.StartS
 		IsRecording: true,
 		IsStreaming: true,
 	}
-	s.repo.SaveStream(streamID, activeStream)
+	s.repo.SaveStream(ctx, streamID, activeStream)
 
 	return &pb.StartStreamResponse{
 		StreamId: streamID,
@@ -90,18 +103,22 @@ func (s *VideoStreamServiceImpl) SendFrame(ctx context.Context, req *pb.SendFram
 	return s.SendFrameInternal(ctx, streamID, clientID, userName, req.Frame)
 }
 
-// SendFrameInternal внутренний метод обработки кадра
+// SendFrameInternal внутренний метод обработки кадра. Несуществующий стрим создаётся с владельцем
+// clientID; в существующий стрим кадры принимаются только от его владельца (checkClient).
 func (s *VideoStreamServiceImpl) SendFrameInternal(ctx context.Context, streamID, clientID, userName string, frame *pb.VideoFrame) (*pb.ApiResponse, error) {
 	if frame == nil {
 		return &pb.ApiResponse{Status: "error", Message: "Frame is nil"}, nil
 	}
+	if err := s.checkClient(ctx, clientID); err != nil {
+		return nil, err
+	}
 
 	s.mu.RLock()
-	stream := s.repo.GetStream(streamID)
+	stream := s.repo.GetStream(ctx, streamID)
 	s.mu.RUnlock()
 
 	if stream == nil {
-		s.logger.Info("Auto-creating stream",
+		s.log(ctx).Info("Auto-creating stream",
 			zap.String("stream_id", streamID),
 			zap.String("client_id", clientID))
 
@@ -109,6 +126,10 @@ func (s *VideoStreamServiceImpl) SendFrameInternal(ctx context.Context, streamID
 		if s.userClient != nil {
 			user, err := s.userClient.GetUserByClientID(ctx, clientID)
 			if err != nil {
+				if ctx.Err() != nil {
+					// Истёк дедлайн/отменён вызов клиента — это не ошибка валидации пользователя.
+					return nil, ctx.Err()
+				}
 				return &pb.ApiResponse{Status: "error", Message: fmt.Sprintf("User validation failed: %v", err)}, nil
 			}
 			userNameToUse = user.Username
@@ -126,12 +147,23 @@ func (s *VideoStreamServiceImpl) SendFrameInternal(ctx context.Context, streamID
 			IsStreaming: true,
 		}
 		s.mu.Lock()
-		s.repo.SaveStream(streamID, activeStream)
+		// Стрим мог создать параллельный кадр, пока проверялся пользователь: владелец остаётся прежним.
+		if stream = s.repo.GetStream(ctx, streamID); stream == nil {
+			s.repo.SaveStream(ctx, streamID, activeStream)
+			stream = activeStream
+		}
 		s.mu.Unlock()
 	}
+	// Кадры в чужой стрим не принимаются: client_id запроса проверен выше, здесь — владелец стрима.
+	if stream.ClientId != clientID {
+		if err := s.checkClient(ctx, stream.ClientId); err != nil {
+			return nil, err
+		}
+	}
 
-	stats := s.repo.UpdateStats(streamID, frame)
-	s.logger.Debug("Frame received",
+	stats := s.repo.UpdateStats(ctx, streamID, frame)
+	metrics.ObserveFrame(streamID, len(frame.FrameData))
+	s.log(ctx).Debug("Frame received",
 		zap.String("stream_id", streamID),
 		zap.String("client_id", clientID),
 		zap.Int64("frame_size", int64(len(frame.FrameData))),
@@ -153,11 +185,19 @@ func (s *VideoStreamServiceImpl) SendFrameInternal(ctx context.Context, streamID
 	}, nil
 }
 
+// StopStream останавливает стрим. Клиент может остановить только свой стрим (checkClient).
 func (s *VideoStreamServiceImpl) StopStream(ctx context.Context, req *pb.StopStreamRequest) (*pb.ApiResponse, error) {
-	s.logger.Info("Stopping stream",
+	s.log(ctx).Info("Stopping stream",
 		zap.String("stream_id", req.StreamId),
-		zap.String("client_id", req.ClientId))
-	s.repo.RemoveStream(req.StreamId)
+		zap.String("client_id", req.ClientId),
+		zap.String("subject", auth.Subject(ctx)))
+	if stream := s.repo.GetStream(ctx, req.StreamId); stream != nil {
+		if err := s.checkClient(ctx, stream.ClientId); err != nil {
+			return nil, err
+		}
+	}
+	s.repo.RemoveStream(ctx, req.StreamId)
+	metrics.DeleteStream(req.StreamId)
 	return &pb.ApiResponse{
 		Status:    "ok",
 		Message:   fmt.Sprintf("Stream %s stopped", req.StreamId),
@@ -173,7 +213,7 @@ func (s *VideoStreamServiceImpl) StopStream(ctx context.Context, req *pb.StopStr
 }
 
 func (s *VideoStreamServiceImpl) GetStreamStats(ctx context.Context, req *pb.GetStreamStatsRequest) (*pb.StreamStats, error) {
-	stats := s.repo.GetStats(req.StreamId)
+	stats := s.repo.GetStats(ctx, req.StreamId)
 	if stats == nil {
 		return nil, errors.ErrStreamNotFound
 	}
@@ -181,15 +221,15 @@ func (s *VideoStreamServiceImpl) GetStreamStats(ctx context.Context, req *pb.Get
 }
 
 func (s *VideoStreamServiceImpl) GetAllActiveStreams() []*pb.ActiveStream {
-	return s.repo.GetAllActiveStreams()
+	return s.repo.GetAllActiveStreams(context.Background())
 }
 
 func (s *VideoStreamServiceImpl) GetAllStats() []*pb.StreamStats {
-	return s.repo.GetAllStats()
+	return s.repo.GetAllStats(context.Background())
 }
 
 func (s *VideoStreamServiceImpl) GetStreamsByClient(clientID string) []*pb.ActiveStream {
-	allStreams := s.repo.GetAllStreams()
+	allStreams := s.repo.GetAllStreams(context.Background())
 	var clientStreams []*pb.ActiveStream
 	for _, stream := range allStreams {
 		if stream.ClientId == clientID {
@@ -200,15 +240,15 @@ func (s *VideoStreamServiceImpl) GetStreamsByClient(clientID string) []*pb.Activ
 }
 
 func (s *VideoStreamServiceImpl) GetStream(streamID string) *pb.ActiveStream {
-	return s.repo.GetStream(streamID)
+	return s.repo.GetStream(context.Background(), streamID)
 }
 
 func (s *VideoStreamServiceImpl) GetActiveStreamsCount() int {
-	return len(s.repo.GetAllActiveStreams())
+	return len(s.repo.GetAllActiveStreams(context.Background()))
 }
 
 func (s *VideoStreamServiceImpl) GetTotalStats() map[string]any {
-	allStats := s.repo.GetAllStats()
+	allStats := s.repo.GetAllStats(context.Background())
 	var totalFrames, totalBytes int64
 	for _, stats := range allStats {
 		totalFrames += stats.FramesReceived
@@ -233,3 +273,25 @@ func calculateAverageFPS(stats []*pb.StreamStats) float32 {
 	}
 	return totalFPS / float32(len(stats))
 }
+
+// checkClient: вызывающий без роли operator/admin (клиент, устройство с API-ключом) может
+// действовать только от своего client_id — subject токена или client_id ключа.
+// Анонимный вызов (аутентификация выключена) не ограничивается.
+func (s *VideoStreamServiceImpl) checkClient(ctx context.Context, clientID string) error {
+	id, ok := auth.FromContext(ctx)
+	if !ok || id.HasRole(constants.RoleOperator) || id.HasRole(constants.RoleAdmin) || id.Subject == clientID {
+		return nil
+	}
+	s.log(ctx).Warn("Access denied",
+		zap.String("subject", id.Subject),
+		zap.Strings("roles", id.Roles),
+		zap.String("key_id", id.KeyID),
+		zap.String("cert_serial", id.CertSerial),
+		zap.String("client_id", clientID))
+	return errors.ErrForbidden
+}
+
+// log — логгер с request_id вызова.
+func (s *VideoStreamServiceImpl) log(ctx context.Context) *zap.Logger {
+	return requestid.Logger(ctx, s.logger)
+}
@@ -0,0 +1,82 @@
+// Package corspolicy — политика CORS (config.CORS) для HTTP-путей: ответы на preflight
+// и заголовки Access-Control-* по правилу пути.
+package corspolicy
+
+import (
+	"slices"
+	"sync/atomic"
+
+	"github.com/psds-microservice/api-gateway/internal/config"
+	"github.com/psds-microservice/api-gateway/internal/match"
+	"github.com/rs/cors"
+)
+
+// Policy — скомпилированная config.CORS (неизменяемая). nil-обработчик — путь без CORS.
+type Policy struct {
+	def  *cors.Cors
+	http match.HTTP[*cors.Cors]
+}
+
+// New компилирует политику: правила cfg.Rules, затем options.cors маршрутов прокси.
+// cfg должен быть провалидирован (config.LoadGateway).
+func New(cfg *config.CORS, routes []config.RouteConfig) *Policy {
+	p := &Policy{}
+	if cfg == nil {
+		return p
+	}
+	p.def = compile(cfg.Default)
+	for _, r := range cfg.Rules {
+		p.http.Add(r.Path, nil, compile(&r))
+	}
+	for _, rc := range routes {
+		if rc.Options.CORS != nil {
+			p.http.Add(rc.Path, nil, compile(rc.Options.CORS))
+		}
+	}
+	return p
+}
+
+func compile(c *config.CORSPolicy) *cors.Cors {
+	// Пустой AllowedOrigins в rs/cors означает "*": без origin-ов CORS выключен явно.
+	if c == nil || c.Disabled || len(c.AllowedOrigins)+len(c.AllowedOriginPatterns) == 0 {
+		return nil
+	}
+	return cors.New(cors.Options{
+		AllowedOrigins:   slices.Concat(c.AllowedOrigins, c.AllowedOriginPatterns),
+		AllowedMethods:   c.AllowedMethods,
+		AllowedHeaders:   c.AllowedHeaders,
+		ExposedHeaders:   c.ExposedHeaders,
+		AllowCredentials: c.AllowCredentials,
+		MaxAge:           c.MaxAgeSec,
+	})
+}
+
+// HTTP возвращает обработчик CORS для пути; nil — заголовки CORS не выставляются.
+func (p *Policy) HTTP(path string) *cors.Cors {
+	if c, ok := p.http.Lookup("", path); ok {
+		return c
+	}
+	return p.def
+}
+
+// Holder хранит текущую политику; при hot reload политика подменяется атомарно.
+type Holder struct {
+	policy atomic.Pointer[Policy]
+}
+
+// NewHolder создаёт Holder с начальной политикой.
+func NewHolder(p *Policy) *Holder {
+	h := &Holder{}
+	h.policy.Store(p)
+	return h
+}
+
+// Policy возвращает текущую политику.
+func (h *Holder) Policy() *Policy {
+	return h.policy.Load()
+}
+
+// Swap подменяет политику.
+func (h *Holder) Swap(p *Policy) {
+	h.policy.Store(p)
+}
@@ -7,4 +7,5 @@ var (
 	ErrStreamNotFound = errors.New("stream not found")
 	ErrClientNotFound = errors.New("client not found")
 	ErrInvalidRequest = errors.New("invalid request")
+	ErrForbidden      = errors.New("forbidden")
 )
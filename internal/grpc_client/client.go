@@ -3,15 +3,27 @@ package grpc_client
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 
+	"github.com/psds-microservice/api-gateway/internal/certs"
 	"github.com/psds-microservice/api-gateway/internal/config"
+	"github.com/psds-microservice/api-gateway/internal/metrics"
+	"github.com/psds-microservice/api-gateway/internal/requestid"
+	"github.com/psds-microservice/api-gateway/internal/retry"
 	uspb "github.com/psds-microservice/user-service/pkg/gen/user_service"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
+// retryTarget — имя user-service в метрике повторов.
+const retryTarget = "user-service-grpc"
+
 // UserServiceClient — интерфейс клиента user-service
 type UserServiceClient interface {
 	Close() error
@@ -26,19 +38,37 @@ type grpcUserServiceClient struct {
 	client uspb.UserServiceClient
 	logger *zap.Logger
 	cfg    *config.Config
+
+	// Повторы: USER_SERVICE_MAX_RETRIES / USER_SERVICE_RETRY_DELAY_SEC / USER_SERVICE_RETRY_CODES.
+	retryCodes map[codes.Code]bool
+	budget     *retry.LocalBudget
 }
 
-// NewUserServiceClient создаёт gRPC‑клиент (при ошибке — stub)
+// NewUserServiceClient создаёт gRPC‑клиент (при ошибке подключения — stub; TLS —
+// USER_SERVICE_TLS_*, ошибка в его настройках возвращается)
 func NewUserServiceClient(cfg *config.Config, logger *zap.Logger) (UserServiceClient, error) {
 	addr := fmt.Sprintf("%s:%d", cfg.UserService.Host, cfg.UserService.Port)
 
+	creds := insecure.NewCredentials()
+	if cfg.UserService.TLSEnabled {
+		if err := cfg.UserService.TLS.Validate(false); err != nil {
+			return nil, fmt.Errorf("tls: %w", err)
+		}
+		store, err := certs.New("user-service", cfg.UserService.TLS, logger)
+		if err != nil {
+			return nil, err
+		}
+		creds = store.Credentials()
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
 	conn, err := grpc.DialContext(
 		ctx,
 		addr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithTransportCredentials(creds),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
 		grpc.WithBlock(),
 	)
 	if err != nil {
@@ -48,17 +78,85 @@ func NewUserServiceClient(cfg *config.Config, logger *zap.Logger) (UserServiceCl
 		return NewStubUserServiceClient(cfg, logger), nil
 	}
 
-	logger.Info("Connected to user-service via gRPC", zap.String("addr", addr))
+	logger.Info("Connected to user-service via gRPC", zap.String("addr", addr), zap.Bool("tls", cfg.UserService.TLSEnabled))
 	client := uspb.NewUserServiceClient(conn)
 
 	return &grpcUserServiceClient{
-		conn:   conn,
-		client: client,
-		logger: logger,
-		cfg:    cfg,
+		conn:       conn,
+		client:     client,
+		logger:     logger,
+		cfg:        cfg,
+		retryCodes: parseRetryCodes(cfg.UserService.RetryCodes, logger),
+		budget:     retry.NewLocalBudget(cfg.RetryBudget.Percent, cfg.RetryBudget.MinConcurrent),
 	}, nil
 }
 
+// parseRetryCodes разбирает имена кодов gRPC ("UNAVAILABLE", ...); неизвестные пропускаются.
+func parseRetryCodes(names []string, logger *zap.Logger) map[codes.Code]bool {
+	out := make(map[codes.Code]bool, len(names))
+	for _, name := range names {
+		var c codes.Code
+		if err := c.UnmarshalJSON([]byte(strconv.Quote(name))); err != nil {
+			logger.Warn("Unknown gRPC code in USER_SERVICE_RETRY_CODES", zap.String("code", name))
+			continue
+		}
+		out[c] = true
+	}
+	return out
+}
+
+// invoke выполняет вызов в пределах дедлайна ctx (входящего gRPC/HTTP запроса)
+// с таймаутом на попытку (USER_SERVICE_REQUEST_TIMEOUT_SEC) и повторами
+// по кодам из retryCodes: backoff с jitter от USER_SERVICE_RETRY_DELAY_SEC, не больше
+// USER_SERVICE_MAX_RETRIES повторов и в пределах бюджета повторов (RETRY_BUDGET_*).
+// Идентификатор запроса передаётся user-service в метаданных "x-request-id".
+func (c *grpcUserServiceClient) invoke(ctx context.Context, method string, call func(ctx context.Context) error) error {
+	if id, ok := requestid.FromContext(ctx); ok {
+		ctx = metadata.AppendToOutgoingContext(ctx, requestid.MetadataKey, id)
+	}
+	end := c.budget.Begin()
+	defer end()
+	base := time.Duration(c.cfg.UserService.RetryDelaySec) * time.Second
+	var release func() // слот бюджета держится до завершения повторной попытки
+	for attempt := 1; ; attempt++ {
+		err := c.attempt(ctx, call)
+		if release != nil {
+			release()
+		}
+		if err == nil || attempt > c.cfg.UserService.MaxRetries || ctx.Err() != nil ||
+			!c.retryCodes[status.Code(err)] {
+			return err
+		}
+		var ok bool
+		if release, ok = c.budget.TryRetry(); !ok {
+			metrics.Retries.WithLabelValues(retryTarget, "budget_exhausted").Inc()
+			return err
+		}
+		requestid.Logger(ctx, c.logger).Debug("Retrying user-service call",
+			zap.String("method", method), zap.Int("attempt", attempt), zap.Error(err))
+		delay := retry.Backoff(attempt, base, 0)
+		if dl, ok := ctx.Deadline(); ok && time.Until(dl) <= delay {
+			// Повтор не успеет до дедлайна вызывающего — возвращаем ошибку сразу.
+			release()
+			return err
+		}
+		if retry.Sleep(ctx, delay) != nil {
+			release()
+			return err
+		}
+		metrics.Retries.WithLabelValues(retryTarget, "retried").Inc()
+	}
+}
+
+func (c *grpcUserServiceClient) attempt(ctx context.Context, call func(ctx context.Context) error) error {
+	if d := c.cfg.UserService.RequestTimeoutSec; d > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(d)*time.Second)
+		defer cancel()
+	}
+	return call(ctx)
+}
+
 func (c *grpcUserServiceClient) Close() error {
 	if c.conn != nil {
 		return c.conn.Close()
@@ -68,7 +166,12 @@ func (c *grpcUserServiceClient) Close() error {
 
 // GetUserByClientID — для простоты считаем, что clientID == user_id (UUID)
 func (c *grpcUserServiceClient) GetUserByClientID(ctx context.Context, clientID string) (*UserInfo, error) {
-	resp, err := c.client.GetUser(ctx, &uspb.GetUserRequest{Id: clientID})
+	var resp *uspb.UserResponse
+	err := c.invoke(ctx, "GetUser", func(ctx context.Context) error {
+		var err error
+		resp, err = c.client.GetUser(ctx, &uspb.GetUserRequest{Id: clientID})
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -87,13 +190,13 @@ func (c *grpcUserServiceClient) GetUserByClientID(ctx context.Context, clientID
 }
 
 // GetStreamingConfig — пока user-service не даёт отдельный стриминг‑конфиг,
-// возвращаем базовый статический конфиг (как раньше в stub).
+// возвращаем базовый статический конфиг (как раньше в stub). APIKey не заполняется:
+// ключи выдаются через admin API (/api/v1/admin/api-keys) и в открытом виде не хранятся.
 func (c *grpcUserServiceClient) GetStreamingConfig(ctx context.Context, userID string) (*StreamingConfig, error) {
 	_ = ctx
 	return &StreamingConfig{
 		ServerURL:      "video-service-1",
 		ServerPort:     8080,
-		APIKey:         "user-" + userID,
 		StreamEndpoint: "/api/v1/video/frame",
 		MaxBitrate:     5000,
 		MaxResolution:  1080,
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/psds-microservice/api-gateway/internal/config"
+	"github.com/psds-microservice/api-gateway/internal/requestid"
 	"go.uber.org/zap"
 )
 
@@ -24,7 +25,7 @@ func (c *StubUserServiceClient) Close() error {
 }
 
 func (c *StubUserServiceClient) GetUserByClientID(ctx context.Context, clientID string) (*UserInfo, error) {
-	c.logger.Debug("Stub: Getting user by client ID", zap.String("client_id", clientID))
+	requestid.Logger(ctx, c.logger).Debug("Stub: Getting user by client ID", zap.String("client_id", clientID))
 	return &UserInfo{
 		ID:       clientID,
 		Username: "user_" + clientID,
@@ -44,7 +45,7 @@ func (c *StubUserServiceClient) GetUserByClientID(ctx context.Context, clientID
 }
 
 func (c *StubUserServiceClient) GetStreamingConfig(ctx context.Context, userID string) (*StreamingConfig, error) {
-	c.logger.Debug("Stub: Getting streaming config", zap.String("user_id", userID))
+	requestid.Logger(ctx, c.logger).Debug("Stub: Getting streaming config", zap.String("user_id", userID))
 	return &StreamingConfig{
 		ServerURL:      "video-service-1.example.com",
 		ServerPort:     8082,
@@ -13,7 +13,7 @@ type UserInfo struct {
 type StreamingConfig struct {
 	ServerURL      string
 	ServerPort     int
-	APIKey         string
+	APIKey         string // не заполняется: API-ключи выдаются admin API gateway (internal/apikey)
 	StreamEndpoint string
 	MaxBitrate     int
 	MaxResolution  int
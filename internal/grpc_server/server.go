@@ -9,9 +9,13 @@ import (
 	"sync"
 	"time"
 
+	"github.com/psds-microservice/api-gateway/internal/auth"
 	"github.com/psds-microservice/api-gateway/internal/controller"
 	apperrors "github.com/psds-microservice/api-gateway/internal/errors"
+	"github.com/psds-microservice/api-gateway/internal/metrics"
+	"github.com/psds-microservice/api-gateway/internal/requestid"
 	pb "github.com/psds-microservice/api-gateway/pkg/gen"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -21,6 +25,7 @@ import (
 // Logger — минимальный интерфейс логгера для Deps (D: зависимость от абстракции).
 type Logger interface {
 	Info(msg string, fields ...zap.Field)
+	Warn(msg string, fields ...zap.Field)
 	Error(msg string, fields ...zap.Field)
 	Debug(msg string, fields ...zap.Field)
 }
@@ -61,15 +66,48 @@ func mapError(err error) error {
 		return nil
 	}
 	switch {
+	case errors.Is(err, context.DeadlineExceeded), status.Code(err) == codes.DeadlineExceeded:
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, context.Canceled), status.Code(err) == codes.Canceled:
+		return status.Error(codes.Canceled, err.Error())
 	case errors.Is(err, apperrors.ErrStreamNotFound), errors.Is(err, apperrors.ErrClientNotFound):
 		return status.Error(codes.NotFound, err.Error())
 	case errors.Is(err, apperrors.ErrInvalidRequest):
 		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, apperrors.ErrForbidden):
+		return status.Error(codes.PermissionDenied, err.Error())
 	default:
 		return status.Error(codes.Internal, err.Error())
 	}
 }
 
+// log — логгер с request_id вызова.
+func (s *VideoStreamServer) log(ctx context.Context) Logger {
+	if id, ok := requestid.FromContext(ctx); ok {
+		return fieldLogger{Logger: s.logger, field: requestid.Field(id)}
+	}
+	return s.logger
+}
+
+// fieldLogger добавляет поле ко всем записям Logger.
+type fieldLogger struct {
+	Logger
+	field zap.Field
+}
+
+func (l fieldLogger) Info(msg string, fields ...zap.Field) {
+	l.Logger.Info(msg, append(fields, l.field)...)
+}
+func (l fieldLogger) Warn(msg string, fields ...zap.Field) {
+	l.Logger.Warn(msg, append(fields, l.field)...)
+}
+func (l fieldLogger) Error(msg string, fields ...zap.Field) {
+	l.Logger.Error(msg, append(fields, l.field)...)
+}
+func (l fieldLogger) Debug(msg string, fields ...zap.Field) {
+	l.Logger.Debug(msg, append(fields, l.field)...)
+}
+
 // StreamSession управляет сессией стрима
 type StreamSession struct {
 	StreamID   string
@@ -90,9 +128,18 @@ func NewVideoStreamServer(svc controller.VideoStreamService, logger Logger) *Vid
 	}
 }
 
-// StreamVideo потоковая передача видео
+// StreamVideo потоковая передача видео. Устройство, аутентифицированное клиентским сертификатом,
+// может не передавать client_id в чанках: он берётся из сертификата; чанк с чужим client_id
+// завершает стрим с PermissionDenied.
 func (s *VideoStreamServer) StreamVideo(stream pb.VideoStreamService_StreamVideoServer) error {
-	s.logger.Info("Starting gRPC video stream")
+	log := s.log(stream.Context())
+	device, _ := auth.FromContext(stream.Context())
+	if device != nil && device.CertSerial == "" {
+		device = nil
+	}
+	log.Info("Starting gRPC video stream")
+	metrics.VideoStreamSessions.Inc()
+	defer metrics.VideoStreamSessions.Dec()
 
 	var session *StreamSession
 	var totalBytes, totalFrames int64
@@ -102,7 +149,7 @@ func (s *VideoStreamServer) StreamVideo(stream pb.VideoStreamService_StreamVideo
 		chunk, err := stream.Recv()
 		if err == io.EOF {
 			if session != nil {
-				s.logger.Info("Stream completed",
+				log.Info("Stream completed",
 					zap.String("stream_id", session.StreamID),
 					zap.Int64("frames", totalFrames),
 					zap.Int64("bytes", totalBytes),
@@ -111,9 +158,22 @@ func (s *VideoStreamServer) StreamVideo(stream pb.VideoStreamService_StreamVideo
 			return nil
 		}
 		if err != nil {
-			s.logger.Error("Stream receive error", zap.Error(err))
+			log.Error("Stream receive error", zap.Error(err))
 			return status.Error(codes.Internal, err.Error())
 		}
+		if device != nil {
+			if chunk.ClientId == "" {
+				chunk.ClientId = device.Subject
+			}
+			if chunk.ClientId != device.Subject {
+				log.Warn("Chunk client_id does not match device certificate",
+					zap.String("stream_id", chunk.StreamId),
+					zap.String("client_id", chunk.ClientId),
+					zap.String("device", device.Subject),
+					zap.String("cert_serial", device.CertSerial))
+				return status.Error(codes.PermissionDenied, "client_id does not match device certificate")
+			}
+		}
 
 		if session == nil {
 			session = &StreamSession{
@@ -126,7 +186,7 @@ func (s *VideoStreamServer) StreamVideo(stream pb.VideoStreamService_StreamVideo
 			s.streams[chunk.StreamId] = session
 			s.mu.Unlock()
 
-			s.logger.Info("New gRPC stream session",
+			log.Info("New gRPC stream session",
 				zap.String("stream_id", chunk.StreamId),
 				zap.String("client_id", chunk.ClientId))
 		}
@@ -141,7 +201,7 @@ func (s *VideoStreamServer) StreamVideo(stream pb.VideoStreamService_StreamVideo
 		totalBytes += int64(len(chunk.Data))
 
 		if totalFrames%100 == 0 {
-			s.logger.Debug("Stream progress",
+			log.Debug("Stream progress",
 				zap.String("stream_id", chunk.StreamId),
 				zap.Int64("frames", totalFrames),
 				zap.Int64("bytes", totalBytes),
@@ -160,7 +220,9 @@ func (s *VideoStreamServer) StreamVideo(stream pb.VideoStreamService_StreamVideo
 			Metadata:  chunk.Metadata,
 		}
 
-		_, _ = s.service.SendFrameInternal(stream.Context(), chunk.StreamId, chunk.ClientId, "gRPC Client", frame)
+		if _, err := s.service.SendFrameInternal(stream.Context(), chunk.StreamId, chunk.ClientId, "gRPC Client", frame); errors.Is(err, apperrors.ErrForbidden) {
+			return mapError(err)
+		}
 
 		ack := &pb.ChunkAck{
 			Status:           "ok",
@@ -170,7 +232,7 @@ func (s *VideoStreamServer) StreamVideo(stream pb.VideoStreamService_StreamVideo
 			ProcessingTimeMs: float32(time.Since(startTime).Seconds() * 1000),
 		}
 		if err := stream.Send(ack); err != nil {
-			s.logger.Error("Failed to send ack", zap.Error(err))
+			log.Error("Failed to send ack", zap.Error(err))
 			return err
 		}
 	}
@@ -178,7 +240,7 @@ func (s *VideoStreamServer) StreamVideo(stream pb.VideoStreamService_StreamVideo
 
 // SendFrame единичный кадр (обратная совместимость)
 func (s *VideoStreamServer) SendFrame(ctx context.Context, req *pb.SendFrameRequest) (*pb.ApiResponse, error) {
-	s.logger.Info("gRPC SendFrame called",
+	s.log(ctx).Info("gRPC SendFrame called",
 		zap.String("stream_id", req.StreamId),
 		zap.String("client_id", req.ClientId))
 	resp, err := s.service.SendFrame(ctx, req)
@@ -199,7 +261,11 @@ func (s *VideoStreamServer) StartStream(ctx context.Context, req *pb.StartStream
 
 // StopStream остановка стрима
 func (s *VideoStreamServer) StopStream(ctx context.Context, req *pb.StopStreamRequest) (*pb.ApiResponse, error) {
-	return s.service.StopStream(ctx, req)
+	resp, err := s.service.StopStream(ctx, req)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return resp, nil
 }
 
 // GetActiveStreams получение активных стримов
@@ -275,6 +341,7 @@ func RunGRPC(port string, videoServer *VideoStreamServer, clientInfoServer *Clie
 	grpcServer := grpc.NewServer(
 		grpc.MaxRecvMsgSize(50*1024*1024), // 50MB для видео
 		grpc.MaxSendMsgSize(10*1024*1024), // 10MB
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
 	)
 	pb.RegisterVideoStreamServiceServer(grpcServer, videoServer)
 	if clientInfoServer != nil {
@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/psds-microservice/api-gateway/internal/apikey"
+	"github.com/psds-microservice/api-gateway/internal/auth"
+	apperrors "github.com/psds-microservice/api-gateway/internal/errors"
+	"github.com/psds-microservice/api-gateway/internal/requestid"
+	"github.com/psds-microservice/api-gateway/pkg/constants"
+	"go.uber.org/zap"
+)
+
+// APIKeyHandler — admin API для API-ключей: выдача, список, ротация, отзыв.
+// Доступ ограничивается политикой RBAC (/api/v1/admin/ — только admin).
+type APIKeyHandler struct {
+	keys   *apikey.Manager
+	logger *zap.Logger
+}
+
+// NewAPIKeyHandler создаёт хендлер admin API ключей.
+func NewAPIKeyHandler(keys *apikey.Manager, logger *zap.Logger) *APIKeyHandler {
+	return &APIKeyHandler{keys: keys, logger: logger}
+}
+
+// Register регистрирует маршруты в mux (шаблоны net/http с методами).
+func (h *APIKeyHandler) Register(mux *http.ServeMux) {
+	base := constants.BasePathAPI + constants.PathAPIKeys
+	mux.HandleFunc("POST "+base, h.Issue)
+	mux.HandleFunc("GET "+base, h.List)
+	mux.HandleFunc("POST "+base+"/{id}/rotate", h.Rotate)
+	mux.HandleFunc("DELETE "+base+"/{id}", h.Revoke)
+}
+
+// issuedKey — ответ на выдачу/ротацию: открытый ключ показывается один раз.
+type issuedKey struct {
+	Key    string      `json:"key"`
+	APIKey *apikey.Key `json:"api_key"`
+}
+
+// Issue — POST /api/v1/admin/api-keys {client_id, name, scopes, ttl_sec}.
+func (h *APIKeyHandler) Issue(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ClientID string   `json:"client_id"`
+		Name     string   `json:"name"`
+		Scopes   []string `json:"scopes"`
+		TTLSec   int64    `json:"ttl_sec"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid request", err.Error())
+		return
+	}
+	plaintext, k, err := h.keys.Issue(r.Context(), apikey.IssueRequest{
+		ClientID: req.ClientID,
+		Name:     req.Name,
+		Scopes:   req.Scopes,
+		TTL:      time.Duration(req.TTLSec) * time.Second,
+	})
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	requestid.Logger(r.Context(), h.logger).Info("API key issued",
+		zap.String("key_id", k.ID),
+		zap.String("client_id", k.ClientID),
+		zap.Strings("scopes", k.Scopes),
+		zap.String("subject", auth.Subject(r.Context())))
+	writeJSON(w, http.StatusCreated, issuedKey{Key: plaintext, APIKey: k})
+}
+
+// List — GET /api/v1/admin/api-keys[?client_id=...].
+func (h *APIKeyHandler) List(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.keys.List(r.Context(), r.URL.Query().Get("client_id"))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"api_keys": keys, "count": len(keys)})
+}
+
+// Rotate — POST /api/v1/admin/api-keys/{id}/rotate [{grace_sec}]: новый ключ с теми же
+// client_id и scopes; старый действует ещё grace_sec (по умолчанию API_KEYS_ROTATION_GRACE_SEC).
+func (h *APIKeyHandler) Rotate(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		GraceSec *int64 `json:"grace_sec"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeJSONError(w, http.StatusBadRequest, "invalid request", err.Error())
+		return
+	}
+	grace := time.Duration(-1)
+	if req.GraceSec != nil {
+		if *req.GraceSec < 0 {
+			writeJSONError(w, http.StatusBadRequest, "invalid request", "grace_sec must be >= 0")
+			return
+		}
+		grace = time.Duration(*req.GraceSec) * time.Second
+	}
+	id := r.PathValue("id")
+	plaintext, k, err := h.keys.Rotate(r.Context(), id, grace)
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	requestid.Logger(r.Context(), h.logger).Info("API key rotated",
+		zap.String("key_id", k.ID),
+		zap.String("rotated_from", id),
+		zap.String("client_id", k.ClientID),
+		zap.String("subject", auth.Subject(r.Context())))
+	writeJSON(w, http.StatusCreated, issuedKey{Key: plaintext, APIKey: k})
+}
+
+// Revoke — DELETE /api/v1/admin/api-keys/{id}.
+func (h *APIKeyHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := h.keys.Revoke(r.Context(), id); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	requestid.Logger(r.Context(), h.logger).Info("API key revoked",
+		zap.String("key_id", id),
+		zap.String("subject", auth.Subject(r.Context())))
+	writeJSON(w, http.StatusOK, map[string]string{"status": "revoked", "id": id})
+}
+
+func (h *APIKeyHandler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, apperrors.ErrInvalidRequest):
+		writeJSONError(w, http.StatusBadRequest, "invalid request", err.Error())
+	case errors.Is(err, apikey.ErrNotFound):
+		writeJSONError(w, http.StatusNotFound, "not found", err.Error())
+	case errors.Is(err, apikey.ErrInactive):
+		writeJSONError(w, http.StatusConflict, "conflict", err.Error())
+	default:
+		requestid.Logger(r.Context(), h.logger).Error("API key request failed", zap.String("path", r.URL.Path), zap.Error(err))
+		writeJSONError(w, http.StatusInternalServerError, "internal error", "api key storage failed")
+	}
+}
+
+// writeJSONError — JSON-ошибка с request_id запроса (заголовок ответа middleware.RequestID).
+func writeJSONError(w http.ResponseWriter, code int, errText, message string) {
+	body := map[string]string{"error": errText, "message": message}
+	if id := w.Header().Get(constants.HeaderRequestID); id != "" {
+		body["request_id"] = id
+	}
+	writeJSON(w, code, body)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
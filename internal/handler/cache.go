@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/psds-microservice/api-gateway/internal/auth"
+	"github.com/psds-microservice/api-gateway/internal/cache"
+	"github.com/psds-microservice/api-gateway/internal/requestid"
+	"github.com/psds-microservice/api-gateway/pkg/constants"
+	"go.uber.org/zap"
+)
+
+// CacheHandler — admin API кэша ответов прокси: сброс записей.
+// Доступ ограничивается политикой RBAC (/api/v1/admin/ — только admin).
+type CacheHandler struct {
+	cache  *cache.Cache
+	logger *zap.Logger
+}
+
+// NewCacheHandler создаёт хендлер admin API кэша.
+func NewCacheHandler(c *cache.Cache, logger *zap.Logger) *CacheHandler {
+	return &CacheHandler{cache: c, logger: logger}
+}
+
+// Register регистрирует маршруты в mux (шаблоны net/http с методами).
+func (h *CacheHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("DELETE "+constants.BasePathAPI+constants.PathCache, h.Purge)
+}
+
+// Purge — DELETE /api/v1/admin/cache[?prefix=/search]: удаляет записи, путь которых начинается
+// с prefix; без prefix — весь кэш.
+func (h *CacheHandler) Purge(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("prefix")
+	n, err := h.cache.Purge(r.Context(), prefix)
+	if err != nil {
+		requestid.Logger(r.Context(), h.logger).Error("Cache purge failed", zap.String("prefix", prefix), zap.Error(err))
+		writeJSONError(w, http.StatusServiceUnavailable, "cache unavailable", "cache purge failed")
+		return
+	}
+	requestid.Logger(r.Context(), h.logger).Info("Cache purged",
+		zap.String("prefix", prefix),
+		zap.Int("purged", n),
+		zap.String("subject", auth.Subject(r.Context())))
+	writeJSON(w, http.StatusOK, map[string]any{"status": "purged", "prefix": prefix, "purged": n})
+}
@@ -1,7 +1,10 @@
 package handler
 
 import (
+	"encoding/json"
 	"net/http"
+
+	"github.com/psds-microservice/api-gateway/internal/health"
 )
 
 // Health — liveness.
@@ -10,8 +13,15 @@ func Health(w http.ResponseWriter, _ *http.Request) {
 	w.Write([]byte("ok"))
 }
 
-// Ready — readiness (при необходимости проверять DB).
-func Ready(w http.ResponseWriter, _ *http.Request) {
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("ready"))
+// Ready — readiness: 200, если обязательные зависимости доступны, иначе 503.
+// Тело — JSON с состоянием каждой зависимости (результат фоновых проверок health.Checker).
+func Ready(checker *health.Checker) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		report := checker.Report()
+		w.Header().Set("Content-Type", "application/json")
+		if !report.Ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(report)
+	}
 }
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/psds-microservice/api-gateway/internal/auth"
+	"github.com/psds-microservice/api-gateway/internal/logging"
+	"github.com/psds-microservice/api-gateway/internal/requestid"
+	"github.com/psds-microservice/api-gateway/pkg/constants"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// LogLevelHandler — admin API уровней логирования: просмотр и изменение без перезапуска.
+// Доступ ограничивается политикой RBAC (/api/v1/admin/ — только admin).
+type LogLevelHandler struct {
+	levels *logging.Levels
+	logger *zap.Logger
+}
+
+// NewLogLevelHandler создаёт хендлер admin API уровней логирования.
+func NewLogLevelHandler(levels *logging.Levels, logger *zap.Logger) *LogLevelHandler {
+	return &LogLevelHandler{levels: levels, logger: logger}
+}
+
+// Register регистрирует маршруты в mux (шаблоны net/http с методами).
+func (h *LogLevelHandler) Register(mux *http.ServeMux) {
+	path := constants.BasePathAPI + constants.PathLogLevel
+	mux.HandleFunc("GET "+path, h.Get)
+	mux.HandleFunc("PUT "+path, h.Set)
+}
+
+// logLevels — текущие уровни: общий и переопределения логгеров по имени.
+type logLevels struct {
+	Level   string            `json:"level"`
+	Loggers map[string]string `json:"loggers"`
+}
+
+// Get — GET /api/v1/admin/log-level.
+func (h *LogLevelHandler) Get(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.current())
+}
+
+// Set — PUT /api/v1/admin/log-level {level[, logger]}: без logger меняется общий уровень,
+// с logger — уровень этого логгера и дочерних; level "" для logger убирает переопределение.
+func (h *LogLevelHandler) Set(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Level  string `json:"level"`
+		Logger string `json:"logger"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid request", err.Error())
+		return
+	}
+	if req.Logger != "" && req.Level == "" {
+		h.levels.ResetNamed(req.Logger)
+		h.logChange(r, req.Logger, "")
+		writeJSON(w, http.StatusOK, h.current())
+		return
+	}
+	lvl, err := zapcore.ParseLevel(req.Level)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "invalid request", err.Error())
+		return
+	}
+	if req.Logger == "" {
+		h.levels.SetLevel(lvl)
+	} else {
+		h.levels.SetNamed(req.Logger, lvl)
+	}
+	h.logChange(r, req.Logger, lvl.String())
+	writeJSON(w, http.StatusOK, h.current())
+}
+
+func (h *LogLevelHandler) current() logLevels {
+	return logLevels{Level: h.levels.Level().String(), Loggers: h.levels.Named()}
+}
+
+// logChange пишет изменение уровнем warn, чтобы оно было видно при любом уровне, кроме error.
+func (h *LogLevelHandler) logChange(r *http.Request, logger, level string) {
+	requestid.Logger(r.Context(), h.logger).Warn("Log level changed",
+		zap.String("target_logger", logger),
+		zap.String("level", level),
+		zap.String("subject", auth.Subject(r.Context())))
+}
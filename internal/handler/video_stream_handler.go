@@ -2,6 +2,7 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -12,6 +13,7 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/psds-microservice/api-gateway/internal/controller"
+	apperrors "github.com/psds-microservice/api-gateway/internal/errors"
 	pb "github.com/psds-microservice/api-gateway/pkg/gen"
 )
 
@@ -258,6 +260,10 @@ func (h *VideoStreamHandler) StopStream(c *gin.Context) {
 		Filename: req.Filename, EndTime: req.EndTime, FileSize: req.FileSize,
 	}
 	response, err := h.service.StopStream(c.Request.Context(), stopReq)
+	if errors.Is(err, apperrors.ErrForbidden) {
+		c.JSON(403, gin.H{"error": "Forbidden", "message": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(500, gin.H{"error": "Internal server error", "message": err.Error()})
 		return
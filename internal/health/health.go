@@ -0,0 +1,153 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Probe — проверяемая зависимость gateway.
+type Probe struct {
+	Name     string
+	Required bool
+	Check    func(ctx context.Context) error
+	// Details — дополнительная информация для /ready (например, состояние экземпляров upstream-а).
+	Details func() any
+}
+
+// Status — результат последней проверки зависимости.
+type Status struct {
+	Status    string    `json:"status"` // up | down
+	Required  bool      `json:"required"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at,omitempty"`
+	LatencyMs int64     `json:"latency_ms"`
+	Details   any       `json:"details,omitempty"`
+}
+
+// Report — сводка для /ready.
+type Report struct {
+	Ready        bool              `json:"ready"`
+	Dependencies map[string]Status `json:"dependencies"`
+}
+
+// Checker периодически проверяет зависимости. Список проверок собирается из источников
+// на каждом круге, поэтому после hot reload проверяются актуальные upstream-ы.
+type Checker struct {
+	interval time.Duration
+	timeout  time.Duration
+	sources  []func() []Probe
+	logger   *zap.Logger
+
+	mu     sync.RWMutex
+	status map[string]Status
+}
+
+// NewChecker создаёт Checker; interval и timeout должны быть больше нуля. sources возвращают пробы для очередного круга проверок.
+func NewChecker(interval, timeout time.Duration, logger *zap.Logger, sources ...func() []Probe) *Checker {
+	return &Checker{
+		interval: interval,
+		timeout:  timeout,
+		sources:  sources,
+		logger:   logger,
+		status:   make(map[string]Status),
+	}
+}
+
+// Static возвращает источник с фиксированным набором проб.
+func Static(probes ...Probe) func() []Probe {
+	return func() []Probe { return probes }
+}
+
+// Run выполняет проверки сразу и далее каждые interval до отмены ctx.
+func (c *Checker) Run(ctx context.Context) {
+	c.CheckNow(ctx)
+	t := time.NewTicker(c.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			c.CheckNow(ctx)
+		}
+	}
+}
+
+// CheckNow параллельно выполняет все пробы и обновляет статусы.
+func (c *Checker) CheckNow(ctx context.Context) {
+	var probes []Probe
+	for _, src := range c.sources {
+		probes = append(probes, src()...)
+	}
+	results := make(map[string]Status, len(probes))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, p := range probes {
+		wg.Add(1)
+		go func(p Probe) {
+			defer wg.Done()
+			st := c.run(ctx, p)
+			mu.Lock()
+			results[p.Name] = st
+			mu.Unlock()
+		}(p)
+	}
+	wg.Wait()
+
+	c.mu.Lock()
+	prev := c.status
+	c.status = results
+	c.mu.Unlock()
+
+	for name, st := range results {
+		if old, ok := prev[name]; ok && old.Status != st.Status {
+			c.logger.Warn("Dependency health changed",
+				zap.String("dependency", name),
+				zap.String("from", old.Status),
+				zap.String("to", st.Status),
+				zap.String("error", st.Error))
+		}
+	}
+}
+
+func (c *Checker) run(ctx context.Context, p Probe) Status {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	start := time.Now()
+	err := p.Check(ctx)
+	st := Status{
+		Status:    "up",
+		Required:  p.Required,
+		CheckedAt: start,
+		LatencyMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		st.Status = "down"
+		st.Error = err.Error()
+	}
+	if p.Details != nil {
+		st.Details = p.Details()
+	}
+	return st
+}
+
+// Report возвращает последние статусы. Gateway не готов, если обязательная зависимость
+// не "up"; до первой проверки gateway не готов.
+func (c *Checker) Report() Report {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	r := Report{Ready: true, Dependencies: make(map[string]Status, len(c.status))}
+	for name, st := range c.status {
+		r.Dependencies[name] = st
+		if st.Required && st.Status != "up" {
+			r.Ready = false
+		}
+	}
+	if len(c.status) == 0 {
+		r.Ready = false
+	}
+	return r
+}
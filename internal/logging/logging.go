@@ -0,0 +1,155 @@
+// Package logging — логгер приложения по конфигурации (LOG_LEVEL, LOG_FORMAT, LOG_LEVELS)
+// с уровнями, которые можно менять во время работы: общим и для отдельных логгеров по имени.
+package logging
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/psds-microservice/api-gateway/internal/config"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// New создаёт логгер: JSON или консольный вывод в stderr, общий уровень и уровни логгеров
+// по имени (logger.Named). Возвращает и Levels для изменения уровней во время работы.
+func New(cfg *config.Config) (*zap.Logger, *Levels, error) {
+	levels, err := NewLevels(cfg.Logging.Level, cfg.Logging.Levels)
+	if err != nil {
+		return nil, nil, err
+	}
+	var enc zapcore.Encoder
+	switch cfg.Logging.Format {
+	case config.LogFormatJSON, "":
+		encCfg := zap.NewProductionEncoderConfig()
+		encCfg.EncodeTime = zapcore.ISO8601TimeEncoder
+		enc = zapcore.NewJSONEncoder(encCfg)
+	case config.LogFormatConsole:
+		encCfg := zap.NewDevelopmentEncoderConfig()
+		enc = zapcore.NewConsoleEncoder(encCfg)
+	default:
+		return nil, nil, fmt.Errorf("LOG_FORMAT: unknown format %q (want %s or %s)",
+			cfg.Logging.Format, config.LogFormatJSON, config.LogFormatConsole)
+	}
+	// Уровень проверяет levelCore; внутреннее ядро пропускает всё.
+	core := zapcore.NewCore(enc, zapcore.Lock(os.Stderr), zapcore.DebugLevel)
+	logger := zap.New(&levelCore{Core: core, levels: levels},
+		zap.AddCaller(),
+		zap.AddStacktrace(zapcore.ErrorLevel),
+		zap.ErrorOutput(zapcore.Lock(os.Stderr)))
+	return logger, levels, nil
+}
+
+// Levels — общий уровень логирования и переопределения для логгеров по имени. Имя "proxy"
+// действует и на дочерние логгеры ("proxy.x"); из нескольких подходящих — самое длинное.
+// Методы безопасны для конкурентного вызова.
+type Levels struct {
+	base zap.AtomicLevel
+
+	mu    sync.RWMutex
+	named map[string]zap.AtomicLevel
+}
+
+// NewLevels создаёт уровни из строк ("debug", "info", "warn", "error").
+func NewLevels(base string, named map[string]string) (*Levels, error) {
+	lvl, err := zapcore.ParseLevel(base)
+	if err != nil {
+		return nil, fmt.Errorf("LOG_LEVEL: %w", err)
+	}
+	l := &Levels{base: zap.NewAtomicLevelAt(lvl), named: make(map[string]zap.AtomicLevel, len(named))}
+	for name, s := range named {
+		lvl, err := zapcore.ParseLevel(s)
+		if err != nil {
+			return nil, fmt.Errorf("LOG_LEVELS %s: %w", name, err)
+		}
+		l.named[name] = zap.NewAtomicLevelAt(lvl)
+	}
+	return l, nil
+}
+
+// Level — общий уровень.
+func (l *Levels) Level() zapcore.Level {
+	return l.base.Level()
+}
+
+// SetLevel меняет общий уровень; переопределения логгеров сохраняются.
+func (l *Levels) SetLevel(lvl zapcore.Level) {
+	l.base.SetLevel(lvl)
+}
+
+// SetNamed задаёт уровень логгера name и его дочерних логгеров.
+func (l *Levels) SetNamed(name string, lvl zapcore.Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if a, ok := l.named[name]; ok {
+		a.SetLevel(lvl)
+		return
+	}
+	l.named[name] = zap.NewAtomicLevelAt(lvl)
+}
+
+// ResetNamed убирает переопределение: логгер name снова пишет с общим уровнем.
+func (l *Levels) ResetNamed(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.named, name)
+}
+
+// Named возвращает текущие переопределения: имя логгера -> уровень.
+func (l *Levels) Named() map[string]string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	out := make(map[string]string, len(l.named))
+	for name, a := range l.named {
+		out[name] = a.Level().String()
+	}
+	return out
+}
+
+// LevelFor — действующий уровень логгера с именем name.
+func (l *Levels) LevelFor(name string) zapcore.Level {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	best, lvl := -1, l.base.Level()
+	for n, a := range l.named {
+		if len(n) > best && (name == n || strings.HasPrefix(name, n+".")) {
+			best, lvl = len(n), a.Level()
+		}
+	}
+	return lvl
+}
+
+// minLevel — самый подробный из действующих уровней (для быстрого отсева в Enabled).
+func (l *Levels) minLevel() zapcore.Level {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	lvl := l.base.Level()
+	for a := range maps.Values(l.named) {
+		lvl = min(lvl, a.Level())
+	}
+	return lvl
+}
+
+// levelCore отсеивает записи по уровню логгера с именем записи (Entry.LoggerName).
+type levelCore struct {
+	zapcore.Core
+	levels *Levels
+}
+
+func (c *levelCore) Enabled(lvl zapcore.Level) bool {
+	return lvl >= c.levels.minLevel()
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), levels: c.levels}
+}
+
+func (c *levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level < c.levels.LevelFor(ent.LoggerName) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
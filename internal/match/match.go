@@ -0,0 +1,134 @@
+// Package match — выбор правила для HTTP-пути или gRPC-метода. Синтаксис путей — как у маршрутов
+// прокси (config.RouteConfig): "/x" совпадает с самим путём и поддеревом, "/x/" — только с поддеревом,
+// "*" — любой один сегмент. Из совпавших правил выбирается наиболее специфичное.
+package match
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/psds-microservice/api-gateway/internal/config"
+)
+
+// HTTP — набор правил для HTTP-путей со значениями V.
+type HTTP[V any] struct {
+	rules []httpRule[V]
+}
+
+type httpRule[V any] struct {
+	value    V
+	segments []string
+	literals int
+	dirOnly  bool
+	methods  []string // nil — любые
+}
+
+// Add добавляет правило. При равной специфичности побеждает добавленное раньше.
+func (m *HTTP[V]) Add(path string, methods []string, v V) {
+	r := httpRule[V]{
+		value:    v,
+		segments: config.SplitPath(path),
+		dirOnly:  strings.HasSuffix(path, "/") && path != "/",
+	}
+	for _, s := range r.segments {
+		if s != "*" {
+			r.literals++
+		}
+	}
+	if len(methods) > 0 {
+		r.methods = slices.Clone(methods)
+	}
+	m.rules = append(m.rules, r)
+}
+
+// Lookup возвращает значение наиболее специфичного правила: больше сегментов, затем больше литеральных.
+func (m *HTTP[V]) Lookup(method, path string) (V, bool) {
+	segs := config.SplitPath(path)
+	trailing := strings.HasSuffix(path, "/")
+	var best *httpRule[V]
+	for i := range m.rules {
+		r := &m.rules[i]
+		if !r.matchPath(segs, trailing) || (r.methods != nil && !slices.Contains(r.methods, method)) {
+			continue
+		}
+		if best == nil || len(r.segments) > len(best.segments) ||
+			(len(r.segments) == len(best.segments) && r.literals > best.literals) {
+			best = r
+		}
+	}
+	if best == nil {
+		var zero V
+		return zero, false
+	}
+	return best.value, true
+}
+
+// Allowed возвращает методы правил, совпавших с path без учёта метода (в порядке добавления), —
+// для заголовка Allow ответа 405. matched = false, если с path не совпало ни одно правило.
+func (m *HTTP[V]) Allowed(path string) (methods []string, matched bool) {
+	segs := config.SplitPath(path)
+	trailing := strings.HasSuffix(path, "/")
+	for i := range m.rules {
+		r := &m.rules[i]
+		if !r.matchPath(segs, trailing) {
+			continue
+		}
+		matched = true
+		for _, meth := range r.methods {
+			if !slices.Contains(methods, meth) {
+				methods = append(methods, meth)
+			}
+		}
+	}
+	return methods, matched
+}
+
+func (r *httpRule[V]) matchPath(segs []string, trailing bool) bool {
+	if len(segs) < len(r.segments) {
+		return false
+	}
+	if r.dirOnly && len(segs) == len(r.segments) && !trailing {
+		return false
+	}
+	for i, s := range r.segments {
+		if s != "*" && s != segs[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// GRPC — набор правил для gRPC-методов: полное имя "/pkg.Service/Method" или префикс сервиса
+// "/pkg.Service/"; побеждает самое длинное совпадение.
+type GRPC[V any] struct {
+	rules []grpcRule[V]
+}
+
+type grpcRule[V any] struct {
+	method string
+	value  V
+}
+
+// Add добавляет правило.
+func (m *GRPC[V]) Add(method string, v V) {
+	m.rules = append(m.rules, grpcRule[V]{method: method, value: v})
+}
+
+// Lookup возвращает значение правила для fullMethod.
+func (m *GRPC[V]) Lookup(fullMethod string) (V, bool) {
+	var best *grpcRule[V]
+	for i := range m.rules {
+		r := &m.rules[i]
+		if fullMethod != r.method && !(strings.HasSuffix(r.method, "/") && strings.HasPrefix(fullMethod, r.method)) {
+			continue
+		}
+		if best == nil || len(r.method) > len(best.method) {
+			best = r
+		}
+	}
+	if best == nil {
+		var zero V
+		return zero, false
+	}
+	return best.value, true
+}
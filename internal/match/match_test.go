@@ -0,0 +1,85 @@
+package match
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestHTTP(t *testing.T) {
+	var m HTTP[string]
+	m.Add("/api/v1/", nil, "api")
+	m.Add("/api/v1/operators", nil, "operators")
+	m.Add("/api/v1/operators/*/availability", nil, "availability")
+	m.Add("/api/v1/operators/available", nil, "available")
+	m.Add("/api/v1/tickets", []string{"GET", "HEAD"}, "tickets read")
+	m.Add("/api/v1/tickets", []string{"POST"}, "tickets write")
+	m.Add("/api/v1/tickets/*", []string{"GET"}, "ticket")
+	tests := []struct {
+		method, path string
+		want         string // "" — нет совпадения
+	}{
+		{"GET", "/api", ""},
+		{"GET", "/api/v1", ""},
+		{"GET", "/api/v1/", "api"},
+		{"GET", "/api/v1/operators", "operators"},
+		{"GET", "/api/v1/operators/", "operators"},
+		{"GET", "/api/v1/operators/7", "operators"},
+		{"GET", "/api/v1/operators/available", "available"},
+		{"GET", "/api/v1/operators/7/availability", "availability"},
+		{"GET", "/api/v1/operators/available/availability", "availability"},
+		{"GET", "/api/v1/operatorsx", "api"},
+		{"POST", "/api/v1/tickets", "tickets write"},
+		{"HEAD", "/api/v1/tickets", "tickets read"},
+		{"GET", "/api/v1/tickets/1", "ticket"},
+		{"POST", "/api/v1/tickets/1", "tickets write"},
+		{"DELETE", "/api/v1/tickets/1", "api"},
+	}
+	for _, tt := range tests {
+		got, ok := m.Lookup(tt.method, tt.path)
+		if ok != (tt.want != "") || got != tt.want {
+			t.Errorf("Lookup(%s, %s) = %q, %v; want %q", tt.method, tt.path, got, ok, tt.want)
+		}
+	}
+}
+
+func TestHTTPAllowed(t *testing.T) {
+	var m HTTP[int]
+	m.Add("/a", []string{"GET", "HEAD"}, 1)
+	m.Add("/a", []string{"POST"}, 2)
+	m.Add("/a/", []string{"GET", "DELETE"}, 3)
+	m.Add("/b", nil, 4)
+	tests := []struct {
+		path    string
+		methods []string
+		matched bool
+	}{
+		{"/a", []string{"GET", "HEAD", "POST"}, true},
+		{"/a/1", []string{"GET", "HEAD", "POST", "DELETE"}, true},
+		{"/b/1", nil, true},
+		{"/c", nil, false},
+	}
+	for _, tt := range tests {
+		methods, matched := m.Allowed(tt.path)
+		if matched != tt.matched || !slices.Equal(methods, tt.methods) {
+			t.Errorf("Allowed(%s) = %v, %v; want %v, %v", tt.path, methods, matched, tt.methods, tt.matched)
+		}
+	}
+}
+
+func TestGRPC(t *testing.T) {
+	var m GRPC[string]
+	m.Add("/pkg.Video/", "service")
+	m.Add("/pkg.Video/SendFrame", "method")
+	tests := []struct{ method, want string }{
+		{"/pkg.Video/SendFrame", "method"},
+		{"/pkg.Video/StreamVideo", "service"},
+		{"/pkg.VideoX/SendFrame", ""},
+		{"/pkg.Video", ""},
+	}
+	for _, tt := range tests {
+		got, ok := m.Lookup(tt.method)
+		if ok != (tt.want != "") || got != tt.want {
+			t.Errorf("Lookup(%s) = %q, %v; want %q", tt.method, got, ok, tt.want)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+// Package metrics — Prometheus-метрики gateway (реестр по умолчанию, отдаются на /metrics).
+package metrics
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "api_gateway"
+
+var (
+	// CircuitBreakerState — состояние breaker-а upstream-а: 0 closed, 1 half-open, 2 open.
+	CircuitBreakerState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "circuit_breaker_state",
+		Help:      "Circuit breaker state per upstream (0 closed, 1 half-open, 2 open).",
+	}, []string{"upstream"})
+
+	// CircuitBreakerTransitions — переходы breaker-а по целевому состоянию.
+	CircuitBreakerTransitions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "circuit_breaker_transitions_total",
+		Help:      "Circuit breaker state transitions per upstream and target state.",
+	}, []string{"upstream", "state"})
+
+	// CircuitBreakerRejected — запросы, отклонённые разомкнутым breaker-ом.
+	CircuitBreakerRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "circuit_breaker_rejected_total",
+		Help:      "Requests rejected by an open circuit breaker per upstream.",
+	}, []string{"upstream"})
+
+	// Retries — повторы запросов к backend-ам: result = retried | budget_exhausted.
+	// target — upstream прокси или gRPC-сервис.
+	Retries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "retries_total",
+		Help:      "Retries of backend requests per target and result (retried, budget_exhausted).",
+	}, []string{"target", "result"})
+
+	// RateLimited — запросы, отклонённые лимитом (429 / RESOURCE_EXHAUSTED), по имени правила.
+	RateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_total",
+		Help:      "Requests rejected by rate limiting per rule.",
+	}, []string{"rule"})
+
+	// RateLimitFallback — решения лимита, принятые локально из-за недоступности Redis.
+	RateLimitFallback = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limit_fallback_total",
+		Help:      "Rate limit decisions made by the local fallback because Redis was unavailable.",
+	})
+
+	// HTTPRequests — HTTP-запросы по методу, шаблону маршрута и статусу ответа.
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests per method, route template and status code.",
+	}, []string{"method", "route", "code"})
+
+	// HTTPRequestDuration — длительность HTTP-запросов (для WebSocket — время жизни соединения).
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency per method, route template and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "code"})
+
+	// ProxyErrors — запросы прокси, на которые upstream не ответил: reason = circuit_open |
+	// timeout | canceled | no_instance | transport.
+	ProxyErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "proxy_errors_total",
+		Help:      "Proxied requests that failed without an upstream response, per upstream and reason.",
+	}, []string{"upstream", "reason"})
+
+	// CacheRequests — GET-запросы маршрутов с кэшем: result = hit | miss | revalidated | bypass | error.
+	CacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "Requests to cached proxy routes per route and result (hit, miss, revalidated, bypass, error).",
+	}, []string{"route", "result"})
+
+	// CoalescedRequests — запросы, получившие ответ одновременного такого же запроса (без своего вызова).
+	CoalescedRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "coalesced_requests_total",
+		Help:      "GET requests served with the response of an identical concurrent request, per route.",
+	}, []string{"route"})
+
+	// GRPCRequests — вызовы gRPC-сервера по методу и коду ответа.
+	GRPCRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_requests_total",
+		Help:      "gRPC server calls per full method and status code.",
+	}, []string{"method", "code"})
+
+	// GRPCRequestDuration — длительность gRPC-вызовов (для стримов — время жизни стрима).
+	GRPCRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_request_duration_seconds",
+		Help:      "gRPC server call latency per full method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+
+	// WebSocketConnections — открытые WebSocket-соединения маршрутов прокси.
+	WebSocketConnections = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "websocket_connections",
+		Help:      "Open proxied WebSocket connections per route.",
+	}, []string{"route"})
+
+	// WebSocketRejected — отклонённые WebSocket-handshake: reason = user_limit | ip_limit | shutdown.
+	WebSocketRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "websocket_rejected_total",
+		Help:      "Rejected WebSocket upgrades per route and reason (user_limit, ip_limit, shutdown).",
+	}, []string{"route", "reason"})
+
+	// WebSocketClosed — закрытые WebSocket-соединения: reason = closed | idle | ping_timeout |
+	// message_too_big | shutdown.
+	WebSocketClosed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "websocket_closed_total",
+		Help:      "Closed WebSocket connections per route and reason (closed, idle, ping_timeout, message_too_big, shutdown).",
+	}, []string{"route", "reason"})
+
+	// TLSCertExpiry — срок действия загруженных сертификатов: cert = server, user-service или имя
+	// upstream-а прокси (клиентский сертификат mTLS).
+	TLSCertExpiry = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "tls_cert_expiry_timestamp_seconds",
+		Help:      "NotAfter of the loaded TLS certificate as a Unix timestamp.",
+	}, []string{"cert"})
+
+	// TLSReloads — перечитывание сертификатов после изменения файлов: result = ok | error.
+	TLSReloads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tls_reloads_total",
+		Help:      "TLS certificate reloads after file changes per certificate and result (ok, error).",
+	}, []string{"cert", "result"})
+
+	// VideoStreamSessions — активные сессии StreamVideo.
+	VideoStreamSessions = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "video_stream_sessions",
+		Help:      "Active StreamVideo sessions.",
+	})
+
+	// VideoFrames / VideoBytes — принятые кадры и байты по стриму (кадры/с и байты/с — rate()).
+	// Пишутся через ObserveFrame; серии стрима удаляются при его остановке (DeleteStream).
+	VideoFrames = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "video_frames_total",
+		Help:      "Video frames ingested per stream.",
+	}, []string{"stream_id"})
+	VideoBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "video_bytes_total",
+		Help:      "Video bytes ingested per stream.",
+	}, []string{"stream_id"})
+
+	// RateLimitBackendDegraded — 1, пока лимиты считаются локально вместо Redis.
+	RateLimitBackendDegraded = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "rate_limit_backend_degraded",
+		Help:      "1 while rate limiting falls back to local buckets because Redis is unavailable.",
+	})
+)
+
+// Ограничение числа серий video_*: stream_id приходит от клиента, а брошенный стрим не останавливается.
+const (
+	maxVideoStreams   = 1000
+	videoStreamIdle   = 10 * time.Minute // стрим без кадров дольше — кандидат на вытеснение
+	videoStreamsOther = "other"          // stream_id кадров сверх maxVideoStreams
+)
+
+// videoStreams — стримы с собственными сериями и время их последнего кадра.
+var videoStreams = struct {
+	mu       sync.Mutex
+	lastSeen map[string]time.Time
+}{lastSeen: make(map[string]time.Time)}
+
+// ObserveFrame учитывает принятый кадр стрима. Собственные серии получают не больше maxVideoStreams
+// стримов: новый стрим вытесняет дольше всех простаивающий (без кадров videoStreamIdle), а если
+// таких нет — учитывается с stream_id="other".
+func ObserveFrame(streamID string, size int) {
+	label := videoStreamLabel(streamID, time.Now())
+	VideoFrames.WithLabelValues(label).Inc()
+	VideoBytes.WithLabelValues(label).Add(float64(size))
+}
+
+func videoStreamLabel(streamID string, now time.Time) string {
+	videoStreams.mu.Lock()
+	defer videoStreams.mu.Unlock()
+	if _, ok := videoStreams.lastSeen[streamID]; ok || len(videoStreams.lastSeen) < maxVideoStreams {
+		videoStreams.lastSeen[streamID] = now
+		return streamID
+	}
+	var oldest string
+	var oldestSeen time.Time
+	for id, seen := range videoStreams.lastSeen {
+		if oldest == "" || seen.Before(oldestSeen) {
+			oldest, oldestSeen = id, seen
+		}
+	}
+	if now.Sub(oldestSeen) < videoStreamIdle {
+		return videoStreamsOther
+	}
+	delete(videoStreams.lastSeen, oldest)
+	VideoFrames.DeleteLabelValues(oldest)
+	VideoBytes.DeleteLabelValues(oldest)
+	videoStreams.lastSeen[streamID] = now
+	return streamID
+}
+
+// DeleteStream удаляет серии остановленного стрима.
+func DeleteStream(streamID string) {
+	videoStreams.mu.Lock()
+	delete(videoStreams.lastSeen, streamID)
+	videoStreams.mu.Unlock()
+	VideoFrames.DeleteLabelValues(streamID)
+	VideoBytes.DeleteLabelValues(streamID)
+}
+
+// repositories — размеры in-memory хранилищ (api_gateway_repository_size), см. SetRepositorySize.
+var repositories = &repositoryCollector{
+	desc: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "repository_size"),
+		"Entries in the gateway's in-memory repositories.", []string{"repository"}, nil),
+	sizes: make(map[string]func() int),
+}
+
+func init() {
+	prometheus.MustRegister(repositories)
+}
+
+// SetRepositorySize регистрирует функцию размера хранилища name (повторный вызов заменяет её).
+func SetRepositorySize(name string, size func() int) {
+	repositories.mu.Lock()
+	defer repositories.mu.Unlock()
+	repositories.sizes[name] = size
+}
+
+type repositoryCollector struct {
+	desc  *prometheus.Desc
+	mu    sync.Mutex
+	sizes map[string]func() int
+}
+
+func (c *repositoryCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *repositoryCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for name, size := range c.sizes {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(size()), name)
+	}
+}
+
+// Handler отдаёт метрики в формате Prometheus.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/psds-microservice/api-gateway/internal/accesslog"
+	"github.com/psds-microservice/api-gateway/internal/reqinfo"
+	"github.com/psds-microservice/api-gateway/internal/requestid"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// AccessLog пишет запись журнала доступа на каждый HTTP-запрос: метод, шаблон маршрута, путь
+// (с маскированием), upstream, статус, размер ответа, длительность, IP клиента, вызывающий
+// и request ID. Успешные запросы к маршрутам из ACCESS_LOG_SAMPLE пишутся выборочно.
+// Должен быть внутри RequestID; IP клиента и вызывающего заполняют ClientIP и Auth (reqinfo).
+func AccessLog(l *accesslog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ctx := r.Context()
+			info := reqinfo.From(ctx)
+			if info == nil {
+				ctx, info = reqinfo.New(ctx)
+			}
+			rec := &responseRecorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r.WithContext(ctx))
+
+			code := rec.Status()
+			lvl := zapcore.InfoLevel
+			switch {
+			case code >= http.StatusInternalServerError:
+				lvl = zapcore.ErrorLevel
+			case code >= http.StatusBadRequest:
+				lvl = zapcore.WarnLevel
+			default:
+				if !l.Sampled(info.Route) {
+					return
+				}
+			}
+			id, _ := requestid.FromContext(ctx)
+			l.Log(lvl, "HTTP request",
+				zap.String("method", r.Method),
+				zap.String("route", info.Route),
+				zap.String("path", l.Path(r.URL)),
+				zap.String("upstream", info.Upstream),
+				zap.Int("status", code),
+				zap.Int64("bytes", rec.Bytes()),
+				zap.Duration("latency", time.Since(start)),
+				zap.String("client_ip", info.ClientIP),
+				zap.String("user_id", info.UserID),
+				requestid.Field(id))
+		})
+	}
+}
+
+// UnaryAccessLog — gRPC-аналог AccessLog для unary-вызовов; bytes — размер ответа в protobuf.
+// Должен быть внутри UnaryRequestID и снаружи UnaryClientIP и UnaryAuth.
+func UnaryAccessLog(l *accesslog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		ctx, ri := reqinfo.New(ctx)
+		resp, err := handler(ctx, req)
+		var size int
+		if m, ok := resp.(proto.Message); ok && err == nil {
+			size = proto.Size(m)
+		}
+		logGRPC(ctx, l, info.FullMethod, ri, start, int64(size), err)
+		return resp, err
+	}
+}
+
+// StreamAccessLog — gRPC-аналог AccessLog для стриминговых вызовов: одна запись по завершении
+// стрима, bytes — суммарный размер отправленных сообщений.
+func StreamAccessLog(l *accesslog.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		ctx, ri := reqinfo.New(ss.Context())
+		cs := &countingStream{ServerStream: ss, ctx: ctx}
+		err := handler(srv, cs)
+		logGRPC(ctx, l, info.FullMethod, ri, start, cs.bytes, err)
+		return err
+	}
+}
+
+func logGRPC(ctx context.Context, l *accesslog.Logger, method string, ri *reqinfo.Info, start time.Time, bytes int64, err error) {
+	code := status.Code(err)
+	lvl := zapcore.InfoLevel
+	switch code {
+	case codes.OK:
+		if !l.Sampled(method) {
+			return
+		}
+	case codes.Unknown, codes.Internal, codes.Unavailable, codes.DataLoss, codes.Unimplemented:
+		lvl = zapcore.ErrorLevel
+	default:
+		lvl = zapcore.WarnLevel
+	}
+	id, _ := requestid.FromContext(ctx)
+	l.Log(lvl, "gRPC call",
+		zap.String("grpc_method", method),
+		zap.String("code", code.String()),
+		zap.Int64("bytes", bytes),
+		zap.Duration("latency", time.Since(start)),
+		zap.String("client_ip", ri.ClientIP),
+		zap.String("user_id", ri.UserID),
+		requestid.Field(id))
+}
+
+// countingStream подменяет контекст стрима (reqinfo) и считает размер отправленных сообщений.
+type countingStream struct {
+	grpc.ServerStream
+	ctx   context.Context
+	bytes int64
+}
+
+func (s *countingStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *countingStream) SendMsg(m any) error {
+	err := s.ServerStream.SendMsg(m)
+	if pm, ok := m.(proto.Message); ok && err == nil {
+		s.bytes += int64(proto.Size(pm))
+	}
+	return err
+}
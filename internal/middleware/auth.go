@@ -0,0 +1,113 @@
+// Package middleware — сквозные обработчики входящих HTTP-запросов и gRPC-вызовов.
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/psds-microservice/api-gateway/internal/apikey"
+	"github.com/psds-microservice/api-gateway/internal/auth"
+	"github.com/psds-microservice/api-gateway/internal/reqinfo"
+	"github.com/psds-microservice/api-gateway/internal/requestid"
+	"github.com/psds-microservice/api-gateway/pkg/constants"
+	"go.uber.org/zap"
+)
+
+// Auth проверяет учётные данные (Bearer-токен, API-ключ в X-API-Key или, для WebSocket-handshake,
+// токен в query access_token) и кладёт identity в контекст запроса. Запросы к publicPaths
+// пропускаются без них (валидные всё равно учитываются); остальные без валидных учётных данных
+// получают 401. keys == nil — API-ключи не принимаются.
+func Auth(v *auth.Verifier, keys *apikey.Manager, publicPaths []string, logger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			public := matchAnyPrefix(r.URL.Path, publicPaths)
+			id, err := authenticateHTTP(v, keys, r)
+			if err != nil && !public {
+				if !isCredentialError(err) {
+					requestid.Logger(r.Context(), logger).Error("Authentication backend failed", zap.String("path", r.URL.Path), zap.Error(err))
+					writeError(w, http.StatusServiceUnavailable, "auth_unavailable", "authentication is temporarily unavailable")
+					return
+				}
+				requestid.Logger(r.Context(), logger).Debug("Unauthenticated request",
+					zap.String("method", r.Method),
+					zap.String("path", r.URL.Path),
+					zap.Error(err))
+				writeUnauthorized(w, err)
+				return
+			}
+			if id != nil {
+				reqinfo.SetUserID(r.Context(), id.Subject)
+				r = r.WithContext(auth.WithIdentity(r.Context(), id))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func authenticateHTTP(v *auth.Verifier, keys *apikey.Manager, r *http.Request) (*auth.Identity, error) {
+	if key := r.Header.Get(constants.HeaderAPIKey); key != "" && keys != nil {
+		return keys.Verify(r.Context(), key)
+	}
+	h := r.Header.Get(constants.HeaderAuthorization)
+	if h == "" {
+		// Браузерный WebSocket не передаёт заголовки: токен — в query handshake.
+		if token := r.URL.Query().Get(constants.QueryAccessToken); token != "" && isWebSocketHandshake(r) {
+			return v.Verify(token)
+		}
+		return nil, auth.ErrNoCredentials
+	}
+	token, ok := auth.BearerToken(h)
+	if !ok {
+		return nil, auth.ErrNoCredentials
+	}
+	return v.Verify(token)
+}
+
+// isWebSocketHandshake — запрос на upgrade до WebSocket.
+func isWebSocketHandshake(r *http.Request) bool {
+	return r.Method == http.MethodGet && strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+// isCredentialError — ошибка в самих учётных данных (401), а не в хранилище ключей.
+func isCredentialError(err error) bool {
+	return errors.Is(err, auth.ErrNoCredentials) || errors.Is(err, auth.ErrInvalidToken) || errors.Is(err, apikey.ErrInvalidKey) ||
+		errors.Is(err, auth.ErrInvalidCert)
+}
+
+func writeUnauthorized(w http.ResponseWriter, err error) {
+	challenge := `Bearer`
+	if errors.Is(err, auth.ErrInvalidToken) {
+		challenge = `Bearer error="invalid_token"`
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	writeError(w, http.StatusUnauthorized, "unauthorized", err.Error())
+}
+
+// writeError отвечает JSON-ошибкой; request_id — из заголовка ответа, выставленного RequestID.
+func writeError(w http.ResponseWriter, code int, errText, message string) {
+	body := map[string]string{"error": errText, "message": message}
+	if id := w.Header().Get(constants.HeaderRequestID); id != "" {
+		body["request_id"] = id
+	}
+	w.Header().Set("Content-Type", constants.ContentTypeJSON)
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
+
+// matchAnyPrefix: "/x/" совпадает с поддеревом, "/x" — с самим путём и поддеревом "/x/...".
+func matchAnyPrefix(path string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasSuffix(p, "/") {
+			if strings.HasPrefix(path, p) {
+				return true
+			}
+			continue
+		}
+		if path == p || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// dotSegments раскрывает экранированные точки, чтобы "%2e%2e" очищалось как "..".
+var dotSegments = strings.NewReplacer("%2e", ".", "%2E", ".")
+
+// CleanPath перенаправляет запросы с неканоническим путём ("//", ".", "..") на очищенный,
+// как это делает http.ServeMux: маршруты прокси, правила RBAC, лимитов, кэша и CORS
+// сопоставляются с тем же путём, который увидит backend. GET и HEAD перенаправляются с 301,
+// остальные методы — с 308 (клиент повторяет метод и тело). Экранирование пути ("%2F")
+// сохраняется; путь, который нельзя очистить без его раскрытия, отклоняется с 400.
+// Должен быть внешним middleware.
+func CleanPath() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p := cleanPath(r.URL.Path)
+			if r.Method == http.MethodConnect || p == r.URL.Path {
+				next.ServeHTTP(w, r)
+				return
+			}
+			escaped := cleanPath(dotSegments.Replace(r.URL.EscapedPath()))
+			if dec, err := url.PathUnescape(escaped); err != nil || dec != p {
+				http.Error(w, "Bad request", http.StatusBadRequest)
+				return
+			}
+			u := *r.URL
+			u.Path, u.RawPath = p, escaped
+			code := http.StatusPermanentRedirect
+			if r.Method == http.MethodGet || r.Method == http.MethodHead {
+				code = http.StatusMovedPermanently
+			}
+			http.Redirect(w, r, u.RequestURI(), code)
+		})
+	}
+}
+
+// cleanPath — path.Clean с ведущим "/" и сохранённым завершающим "/".
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if p[0] != '/' {
+		p = "/" + p
+	}
+	np := path.Clean(p)
+	if p[len(p)-1] == '/' && np != "/" {
+		np += "/"
+	}
+	return np
+}
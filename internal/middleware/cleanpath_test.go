@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCleanPath(t *testing.T) {
+	h := CleanPath()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	tests := []struct {
+		method, target string
+		code           int
+		location       string
+	}{
+		{http.MethodGet, "/api/v1/tickets", http.StatusNoContent, ""},
+		{http.MethodGet, "/api/v1/tickets/", http.StatusNoContent, ""},
+		{http.MethodGet, "/api/v1/x/../../admin?a=1", http.StatusMovedPermanently, "/api/admin?a=1"},
+		{http.MethodHead, "/api//v1/", http.StatusMovedPermanently, "/api/v1/"},
+		{http.MethodGet, "/api/v1/%2e%2e/admin", http.StatusMovedPermanently, "/api/admin"},
+		{http.MethodPost, "/api/v1/./tickets", http.StatusPermanentRedirect, "/api/v1/tickets"},
+		{http.MethodDelete, "/api/v1/tickets/1/..", http.StatusPermanentRedirect, "/api/v1/tickets"},
+		// "%2F" не раскрывается в разделитель
+		{http.MethodGet, "/files/a%2Fb//c", http.StatusMovedPermanently, "/files/a%2Fb/c"},
+		{http.MethodGet, "/files/a%2Fb", http.StatusNoContent, ""},
+		// ".." за "%2F" нельзя очистить, не раскрыв его
+		{http.MethodGet, "/files/a%2F..%2F..%2Fadmin", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, nil))
+		if rec.Code != tt.code {
+			t.Errorf("%s %s: code %d, want %d", tt.method, tt.target, rec.Code, tt.code)
+			continue
+		}
+		if loc := rec.Header().Get("Location"); loc != tt.location {
+			t.Errorf("%s %s: Location %q, want %q", tt.method, tt.target, loc, tt.location)
+		}
+	}
+}
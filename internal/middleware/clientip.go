@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/psds-microservice/api-gateway/internal/clientip"
+	"github.com/psds-microservice/api-gateway/internal/reqinfo"
+	"github.com/psds-microservice/api-gateway/pkg/constants"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// forwardingHeaders — заголовки с адресом клиента, которые ставят прокси перед gateway.
+var forwardingHeaders = []string{constants.HeaderForwarded, constants.HeaderForwardedFor, constants.HeaderRealIP}
+
+// ClientIP определяет IP клиента (clientip.Resolver) и кладёт его в контекст запроса
+// (clientip.FromContext) для лимитов, handler-ов и прокси. Заголовки пересылки от
+// недоверенного источника удаляются, чтобы backend-ы не получили подделанную цепочку.
+// Должен быть внешним middleware.
+func ClientIP(res *clientip.Resolver) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := res.Resolve(r)
+			if !res.TrustsRemote(r.RemoteAddr) {
+				for _, h := range forwardingHeaders {
+					r.Header.Del(h)
+				}
+			}
+			reqinfo.SetClientIP(r.Context(), ip)
+			next.ServeHTTP(w, r.WithContext(clientip.WithIP(r.Context(), ip)))
+		})
+	}
+}
+
+// UnaryClientIP — gRPC-аналог ClientIP: адрес peer-а и метаданные "forwarded",
+// "x-forwarded-for", "x-real-ip" (если peer — доверенный прокси).
+func UnaryClientIP(res *clientip.Resolver) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(grpcClientIP(ctx, res), req)
+	}
+}
+
+// StreamClientIP — gRPC-аналог ClientIP для стриминговых вызовов.
+func StreamClientIP(res *clientip.Resolver) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &contextStream{ServerStream: ss, ctx: grpcClientIP(ss.Context(), res)})
+	}
+}
+
+func grpcClientIP(ctx context.Context, res *clientip.Resolver) context.Context {
+	remote := ""
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		remote = p.Addr.String()
+	}
+	h := http.Header{}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, k := range forwardingHeaders {
+			for _, v := range md.Get(strings.ToLower(k)) {
+				h.Add(k, v)
+			}
+		}
+	}
+	ip := res.ResolveAddr(remote, h)
+	reqinfo.SetClientIP(ctx, ip)
+	return clientip.WithIP(ctx, ip)
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/psds-microservice/api-gateway/internal/corspolicy"
+)
+
+// CORS отвечает на preflight и выставляет заголовки Access-Control-* по политике пути запроса
+// (текущая политика Holder). Пути без политики обслуживаются без заголовков CORS.
+// Должен быть снаружи Auth: preflight приходит без учётных данных.
+func CORS(h *corspolicy.Holder) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c := h.Policy().HTTP(r.URL.Path)
+			if c == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			c.ServeHTTP(w, r, next.ServeHTTP)
+		})
+	}
+}
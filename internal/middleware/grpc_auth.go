@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"context"
+	"crypto/x509"
+	"strings"
+
+	"github.com/psds-microservice/api-gateway/internal/apikey"
+	"github.com/psds-microservice/api-gateway/internal/auth"
+	"github.com/psds-microservice/api-gateway/internal/reqinfo"
+	"github.com/psds-microservice/api-gateway/internal/requestid"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryAuth — gRPC-аналог Auth для unary-вызовов: токен из метаданных "authorization"
+// или API-ключ из "x-api-key". Для методов устройств (devices.Applies; devices nil — режим
+// выключен) identity берётся только из клиентского сертификата соединения.
+func UnaryAuth(v *auth.Verifier, keys *apikey.Manager, devices *auth.Devices, publicMethods []string, logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authenticateGRPC(ctx, v, keys, devices, info.FullMethod, publicMethods, logger)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamAuth — gRPC-аналог Auth для стриминговых вызовов (StreamVideo).
+func StreamAuth(v *auth.Verifier, keys *apikey.Manager, devices *auth.Devices, publicMethods []string, logger *zap.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticateGRPC(ss.Context(), v, keys, devices, info.FullMethod, publicMethods, logger)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func authenticateGRPC(ctx context.Context, v *auth.Verifier, keys *apikey.Manager, devices *auth.Devices, method string, publicMethods []string, logger *zap.Logger) (context.Context, error) {
+	if devices != nil && devices.Applies(method) {
+		id, err := devices.Verify(peerChains(ctx))
+		if err != nil {
+			requestid.Logger(ctx, logger).Warn("Device certificate rejected", zap.String("method", method), zap.Error(err))
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		reqinfo.SetUserID(ctx, id.Subject)
+		return auth.WithIdentity(ctx, id), nil
+	}
+	public := false
+	for _, p := range publicMethods {
+		if strings.HasPrefix(method, p) {
+			public = true
+			break
+		}
+	}
+	id, err := verifyMetadata(ctx, v, keys)
+	if err != nil {
+		if public {
+			return ctx, nil
+		}
+		if !isCredentialError(err) {
+			requestid.Logger(ctx, logger).Error("Authentication backend failed", zap.String("method", method), zap.Error(err))
+			return nil, status.Error(codes.Unavailable, "authentication is temporarily unavailable")
+		}
+		requestid.Logger(ctx, logger).Debug("Unauthenticated gRPC call", zap.String("method", method), zap.Error(err))
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	reqinfo.SetUserID(ctx, id.Subject)
+	return auth.WithIdentity(ctx, id), nil
+}
+
+func verifyMetadata(ctx context.Context, v *auth.Verifier, keys *apikey.Manager) (*auth.Identity, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if key := md.Get("x-api-key"); len(key) > 0 && keys != nil {
+		return keys.Verify(ctx, key[0])
+	}
+	vals := md.Get("authorization")
+	if len(vals) == 0 {
+		return nil, auth.ErrNoCredentials
+	}
+	token, ok := auth.BearerToken(vals[0])
+	if !ok {
+		return nil, auth.ErrNoCredentials
+	}
+	return v.Verify(token)
+}
+
+// peerChains возвращает проверенные цепочки клиентского сертификата соединения (nil — без mTLS).
+func peerChains(ctx context.Context) [][]*x509.Certificate {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return nil
+	}
+	return info.State.VerifiedChains
+}
+
+// contextStream подменяет контекст стрима (с identity).
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/psds-microservice/api-gateway/internal/metrics"
+	"github.com/psds-microservice/api-gateway/internal/reqinfo"
+	"github.com/psds-microservice/api-gateway/internal/tracing"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// routeUnmatched — метка маршрута для запросов, не попавших ни в один маршрут.
+const routeUnmatched = "unmatched"
+
+// knownMethods — методы с собственной меткой; остальные учитываются как "other".
+var knownMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true, http.MethodPut: true,
+	http.MethodPatch: true, http.MethodDelete: true, http.MethodOptions: true,
+}
+
+// Metrics считает HTTP-запросы и их длительность по методу, шаблону маршрута (reqinfo) и статусу
+// и называет серверный спан запроса по маршруту. Должен быть внутри tracing.Handler и снаружи
+// остальных middleware.
+func Metrics() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ctx, info := reqinfo.New(r.Context())
+			rec := &responseRecorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r.WithContext(ctx))
+
+			route := info.Route
+			if route == "" {
+				route = routeUnmatched
+			} else {
+				tracing.SetRoute(ctx, r.Method, route)
+			}
+			method := r.Method
+			if !knownMethods[method] {
+				method = "other"
+			}
+			code := strconv.Itoa(rec.Status())
+			metrics.HTTPRequests.WithLabelValues(method, route, code).Inc()
+			metrics.HTTPRequestDuration.WithLabelValues(method, route, code).Observe(time.Since(start).Seconds())
+		})
+	}
+}
+
+// UnaryMetrics — gRPC-аналог Metrics для unary-вызовов.
+func UnaryMetrics() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		observeGRPC(info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamMetrics — gRPC-аналог Metrics для стриминговых вызовов (длительность — время жизни стрима).
+func StreamMetrics() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		observeGRPC(info.FullMethod, start, err)
+		return err
+	}
+}
+
+func observeGRPC(method string, start time.Time, err error) {
+	metrics.GRPCRequests.WithLabelValues(method, status.Code(err).String()).Inc()
+	metrics.GRPCRequestDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
+
+// responseRecorder запоминает статус и размер ответа. Flush, Hijack и Unwrap передаются
+// исходному ResponseWriter (стриминг grpc-gateway, WebSocket через прокси).
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	if r.status == 0 && code >= http.StatusOK {
+		r.status = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+func (r *responseRecorder) Flush() {
+	_ = http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+func (r *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(r.ResponseWriter).Hijack()
+	if err == nil && r.status == 0 {
+		r.status = http.StatusSwitchingProtocols
+	}
+	return conn, brw, err
+}
+
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Status — статус ответа (200, если handler ничего не записал).
+func (r *responseRecorder) Status() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
+
+// Bytes — размер тела ответа.
+func (r *responseRecorder) Bytes() int64 {
+	return r.bytes
+}
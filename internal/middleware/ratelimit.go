@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/psds-microservice/api-gateway/internal/auth"
+	"github.com/psds-microservice/api-gateway/internal/clientip"
+	"github.com/psds-microservice/api-gateway/internal/metrics"
+	"github.com/psds-microservice/api-gateway/internal/ratelimit"
+	"github.com/psds-microservice/api-gateway/internal/requestid"
+	"github.com/psds-microservice/api-gateway/pkg/constants"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// RateLimit ограничивает запросы по текущим лимитам (после Auth, чтобы учитывать пользователя
+// и API-ключ). Ответ несёт RateLimit-*; превышение — 429 с Retry-After. Ошибка хранилища
+// лимитов не блокирует запрос.
+func RateLimit(l *ratelimit.Limiter, logger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rule := l.Policy().HTTP(r.Method, r.URL.Path)
+			if rule == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			res, err := l.Take(r.Context(), rule, caller(r.Context(), r.RemoteAddr))
+			if err != nil {
+				requestid.Logger(r.Context(), logger).Warn("Rate limit check failed, request allowed", zap.String("path", r.URL.Path), zap.Error(err))
+				next.ServeHTTP(w, r)
+				return
+			}
+			h := w.Header()
+			h.Set(constants.HeaderRateLimitLimit, strconv.Itoa(res.Limit))
+			h.Set(constants.HeaderRateLimitRemaining, strconv.Itoa(res.Remaining))
+			h.Set(constants.HeaderRateLimitReset, ceilSeconds(res.Reset))
+			if !res.Allowed {
+				metrics.RateLimited.WithLabelValues(rule.Name).Inc()
+				h.Set(constants.HeaderRetryAfter, ceilSeconds(res.RetryAfter))
+				writeError(w, http.StatusTooManyRequests, "rate limit exceeded", "too many requests")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// UnaryRateLimit — gRPC-аналог RateLimit для unary-вызовов: превышение — RESOURCE_EXHAUSTED,
+// лимиты — в метаданных заголовка ответа (имена RateLimit-* в нижнем регистре).
+func UnaryRateLimit(l *ratelimit.Limiter, logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, err := rateLimitGRPC(ctx, l, info.FullMethod, logger)
+		if md != nil {
+			_ = grpc.SetHeader(ctx, md)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamRateLimit — gRPC-аналог RateLimit для стриминговых вызовов: лимит применяется
+// к открытию стрима, а не к сообщениям в нём.
+func StreamRateLimit(l *ratelimit.Limiter, logger *zap.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		md, err := rateLimitGRPC(ss.Context(), l, info.FullMethod, logger)
+		if md != nil {
+			_ = ss.SetHeader(md)
+		}
+		if err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+func rateLimitGRPC(ctx context.Context, l *ratelimit.Limiter, method string, logger *zap.Logger) (metadata.MD, error) {
+	rule := l.Policy().GRPC(method)
+	if rule == nil {
+		return nil, nil
+	}
+	remote := ""
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		remote = p.Addr.String()
+	}
+	res, err := l.Take(ctx, rule, caller(ctx, remote))
+	if err != nil {
+		requestid.Logger(ctx, logger).Warn("Rate limit check failed, call allowed", zap.String("grpc_method", method), zap.Error(err))
+		return nil, nil
+	}
+	md := metadata.Pairs(
+		strings.ToLower(constants.HeaderRateLimitLimit), strconv.Itoa(res.Limit),
+		strings.ToLower(constants.HeaderRateLimitRemaining), strconv.Itoa(res.Remaining),
+		strings.ToLower(constants.HeaderRateLimitReset), ceilSeconds(res.Reset),
+	)
+	if !res.Allowed {
+		metrics.RateLimited.WithLabelValues(rule.Name).Inc()
+		md.Set(strings.ToLower(constants.HeaderRetryAfter), ceilSeconds(res.RetryAfter))
+		return md, status.Error(codes.ResourceExhausted, "rate limit exceeded")
+	}
+	return md, nil
+}
+
+// caller — кто делает запрос: IP из контекста (ClientIP), без него — адрес соединения remote.
+func caller(ctx context.Context, remote string) ratelimit.Caller {
+	ip, ok := clientip.FromContext(ctx)
+	if !ok {
+		ip = hostOnly(remote)
+	}
+	c := ratelimit.Caller{IP: ip}
+	if id, ok := auth.FromContext(ctx); ok {
+		c.Subject, c.KeyID = id.Subject, id.KeyID
+	}
+	return c
+}
+
+// ceilSeconds — целые секунды с округлением вверх (не меньше 1 для ненулевой длительности).
+func ceilSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
+
+func hostOnly(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil && host != "" {
+		return host
+	}
+	return addr
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/psds-microservice/api-gateway/internal/auth"
+	"github.com/psds-microservice/api-gateway/internal/rbac"
+	"github.com/psds-microservice/api-gateway/internal/requestid"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Authorize проверяет доступ по текущей политике RBAC (после Auth): без identity — 401,
+// без нужной роли/разрешения — 403. Отказы логируются с identity вызывающего.
+func Authorize(authz *rbac.Authorizer, logger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, _ := auth.FromContext(r.Context())
+			d := authz.Policy().HTTP(r.Method, r.URL.Path, id)
+			if !d.Allowed {
+				logDenied(requestid.Logger(r.Context(), logger), id, d, zap.String("method", r.Method), zap.String("path", r.URL.Path))
+				if id == nil {
+					writeUnauthorized(w, auth.ErrNoCredentials)
+					return
+				}
+				writeError(w, http.StatusForbidden, "forbidden", "access denied")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// UnaryAuthorize — gRPC-аналог Authorize для unary-вызовов.
+func UnaryAuthorize(authz *rbac.Authorizer, logger *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := authorizeGRPC(ctx, authz, info.FullMethod, logger); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamAuthorize — gRPC-аналог Authorize для стриминговых вызовов.
+func StreamAuthorize(authz *rbac.Authorizer, logger *zap.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := authorizeGRPC(ss.Context(), authz, info.FullMethod, logger); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+func authorizeGRPC(ctx context.Context, authz *rbac.Authorizer, method string, logger *zap.Logger) error {
+	id, _ := auth.FromContext(ctx)
+	d := authz.Policy().GRPC(method, id)
+	if d.Allowed {
+		return nil
+	}
+	logDenied(requestid.Logger(ctx, logger), id, d, zap.String("grpc_method", method))
+	if id == nil {
+		return status.Error(codes.Unauthenticated, auth.ErrNoCredentials.Error())
+	}
+	return status.Error(codes.PermissionDenied, "access denied")
+}
+
+func logDenied(logger *zap.Logger, id *auth.Identity, d rbac.Decision, fields ...zap.Field) {
+	subject, roles := "", []string(nil)
+	if id != nil {
+		subject, roles = id.Subject, id.Roles
+	}
+	logger.Warn("Access denied", append(fields,
+		zap.String("subject", subject),
+		zap.Strings("roles", roles),
+		zap.String("rule", d.Rule),
+		zap.Strings("required_roles", d.Roles),
+		zap.Strings("required_permissions", d.Permissions))...)
+}
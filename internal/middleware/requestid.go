@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/psds-microservice/api-gateway/internal/requestid"
+	"github.com/psds-microservice/api-gateway/pkg/constants"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// RequestID принимает X-Request-ID клиента (или генерирует новый), кладёт его в контекст
+// (requestid.FromContext) и в заголовок запроса для backend-ов, возвращает в ответе.
+// Должен быть внешним middleware, чтобы идентификатор был и в ответах других middleware.
+func RequestID() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := requestid.Accept(r.Header.Get(constants.HeaderRequestID))
+			r.Header.Set(constants.HeaderRequestID, id)
+			w.Header().Set(constants.HeaderRequestID, id)
+			next.ServeHTTP(w, r.WithContext(requestid.WithID(r.Context(), id)))
+		})
+	}
+}
+
+// UnaryRequestID — gRPC-аналог RequestID: идентификатор из метаданных "x-request-id",
+// возвращается в метаданных заголовка ответа и в деталях ошибки (google.rpc.RequestInfo).
+func UnaryRequestID() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		id := grpcRequestID(ctx)
+		_ = grpc.SetHeader(ctx, metadata.Pairs(requestid.MetadataKey, id))
+		resp, err := handler(requestid.WithID(ctx, id), req)
+		return resp, requestid.Status(err, id)
+	}
+}
+
+// StreamRequestID — gRPC-аналог RequestID для стриминговых вызовов.
+func StreamRequestID() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		id := grpcRequestID(ss.Context())
+		_ = ss.SetHeader(metadata.Pairs(requestid.MetadataKey, id))
+		err := handler(srv, &contextStream{ServerStream: ss, ctx: requestid.WithID(ss.Context(), id)})
+		return requestid.Status(err, id)
+	}
+}
+
+func grpcRequestID(ctx context.Context) string {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(requestid.MetadataKey); len(v) > 0 {
+			id = v[0]
+		}
+	}
+	return requestid.Accept(id)
+}
@@ -0,0 +1,133 @@
+package proxy
+
+import (
+	"hash/crc32"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/psds-microservice/api-gateway/internal/config"
+)
+
+// balancer выбирает экземпляр upstream-а для запроса.
+type balancer interface {
+	pick(r *http.Request, instances []*Instance) *Instance
+}
+
+func newBalancer(uc config.UpstreamConfig, instances []*Instance) balancer {
+	switch uc.Policy {
+	case config.LBWeighted:
+		return &weightedBalancer{current: make(map[*Instance]int, len(instances))}
+	case config.LBLeastRequests:
+		return &leastRequestsBalancer{}
+	case config.LBConsistentHash:
+		kind, key, _ := strings.Cut(uc.HashOn, ":")
+		return newHashBalancer(kind, key, instances)
+	default:
+		return &roundRobinBalancer{}
+	}
+}
+
+// roundRobinBalancer — по кругу, без учёта весов.
+type roundRobinBalancer struct {
+	next atomic.Uint64
+}
+
+func (b *roundRobinBalancer) pick(_ *http.Request, instances []*Instance) *Instance {
+	n := b.next.Add(1) - 1
+	return instances[n%uint64(len(instances))]
+}
+
+// weightedBalancer — smooth weighted round-robin (как в nginx): экземпляры чередуются
+// пропорционально весам без серий подряд.
+type weightedBalancer struct {
+	mu      sync.Mutex
+	current map[*Instance]int
+}
+
+func (b *weightedBalancer) pick(_ *http.Request, instances []*Instance) *Instance {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	total := 0
+	var best *Instance
+	for _, inst := range instances {
+		b.current[inst] += inst.Weight
+		total += inst.Weight
+		if best == nil || b.current[inst] > b.current[best] {
+			best = inst
+		}
+	}
+	b.current[best] -= total
+	return best
+}
+
+// leastRequestsBalancer — экземпляр с наименьшим числом активных запросов; при равенстве — по кругу.
+type leastRequestsBalancer struct {
+	next atomic.Uint64
+}
+
+func (b *leastRequestsBalancer) pick(_ *http.Request, instances []*Instance) *Instance {
+	start := int((b.next.Add(1) - 1) % uint64(len(instances)))
+	var best *Instance
+	for i := range instances {
+		inst := instances[(start+i)%len(instances)]
+		if best == nil || inst.inflight.Load() < best.inflight.Load() {
+			best = inst
+		}
+	}
+	return best
+}
+
+// hashBalancer — consistent hash по значению заголовка или cookie (кольцо с виртуальными узлами).
+// Недоступные экземпляры пропускаются по кольцу; запросы без ключа распределяются по кругу.
+type hashBalancer struct {
+	kind, key string
+	ring      []ringNode
+	fallback  roundRobinBalancer
+}
+
+type ringNode struct {
+	hash uint32
+	inst *Instance
+}
+
+const hashReplicas = 100
+
+func newHashBalancer(kind, key string, instances []*Instance) *hashBalancer {
+	b := &hashBalancer{kind: kind, key: key}
+	for _, inst := range instances {
+		for i := 0; i < hashReplicas*inst.Weight; i++ {
+			h := crc32.ChecksumIEEE([]byte(inst.URL.String() + "#" + strconv.Itoa(i)))
+			b.ring = append(b.ring, ringNode{hash: h, inst: inst})
+		}
+	}
+	sort.Slice(b.ring, func(i, j int) bool { return b.ring[i].hash < b.ring[j].hash })
+	return b
+}
+
+func (b *hashBalancer) pick(r *http.Request, instances []*Instance) *Instance {
+	var v string
+	if b.kind == "cookie" {
+		if c, err := r.Cookie(b.key); err == nil {
+			v = c.Value
+		}
+	} else {
+		v = r.Header.Get(b.key)
+	}
+	if v == "" || len(b.ring) == 0 {
+		return b.fallback.pick(r, instances)
+	}
+	h := crc32.ChecksumIEEE([]byte(v))
+	start := sort.Search(len(b.ring), func(i int) bool { return b.ring[i].hash >= h })
+	for n := 0; n < len(b.ring); n++ {
+		node := b.ring[(start+n)%len(b.ring)]
+		if slices.Contains(instances, node.inst) {
+			return node.inst
+		}
+	}
+	return b.fallback.pick(r, instances)
+}
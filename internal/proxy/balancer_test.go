@@ -0,0 +1,117 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/psds-microservice/api-gateway/internal/config"
+)
+
+// testInstances возвращает экземпляры http://i<N>:8080 с весами weights.
+func testInstances(weights ...int) []*Instance {
+	instances := make([]*Instance, len(weights))
+	for i, w := range weights {
+		instances[i] = &Instance{URL: &url.URL{Scheme: "http", Host: fmt.Sprintf("i%d:8080", i)}, Weight: w}
+	}
+	return instances
+}
+
+// picks возвращает последовательность n выборов в виде индексов экземпляров.
+func picks(b balancer, r *http.Request, instances []*Instance, n int) string {
+	var sb strings.Builder
+	for range n {
+		inst := b.pick(r, instances)
+		for i := range instances {
+			if instances[i] == inst {
+				fmt.Fprint(&sb, i)
+			}
+		}
+	}
+	return sb.String()
+}
+
+func TestBalancerRoundRobin(t *testing.T) {
+	instances := testInstances(1, 5, 1)
+	b := newBalancer(config.UpstreamConfig{Policy: config.LBRoundRobin}, instances)
+	if got := picks(b, httptest.NewRequest(http.MethodGet, "/", nil), instances, 6); got != "012012" {
+		t.Fatalf("picks %s, want 012012 (weights ignored)", got)
+	}
+}
+
+func TestBalancerWeighted(t *testing.T) {
+	instances := testInstances(5, 1, 1)
+	b := newBalancer(config.UpstreamConfig{Policy: config.LBWeighted}, instances)
+	// smooth weighted round-robin: доли по весам, тяжёлый экземпляр не идёт сериями
+	got := picks(b, httptest.NewRequest(http.MethodGet, "/", nil), instances, 14)
+	if want := "00102000010200"; got != want {
+		t.Fatalf("picks %s, want %s", got, want)
+	}
+}
+
+func TestBalancerLeastRequests(t *testing.T) {
+	instances := testInstances(1, 1, 1)
+	instances[0].inflight.Store(3)
+	instances[1].inflight.Store(1)
+	instances[2].inflight.Store(2)
+	b := newBalancer(config.UpstreamConfig{Policy: config.LBLeastRequests}, instances)
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if got := picks(b, r, instances, 3); got != "111" {
+		t.Fatalf("picks %s, want 111", got)
+	}
+	// при равенстве — по кругу
+	instances[0].inflight.Store(1)
+	instances[2].inflight.Store(1)
+	seen := picks(b, r, instances, 3)
+	for i := range instances {
+		if !strings.Contains(seen, fmt.Sprint(i)) {
+			t.Fatalf("picks %s on tie, want every instance", seen)
+		}
+	}
+}
+
+func TestBalancerConsistentHash(t *testing.T) {
+	instances := testInstances(1, 1, 1, 1)
+	uc := config.UpstreamConfig{Policy: config.LBConsistentHash, HashOn: "header:X-Session-ID"}
+	b := newBalancer(uc, instances)
+	request := func(key string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("X-Session-ID", key)
+		return r
+	}
+	const keys = 200
+	before := make([]*Instance, keys)
+	used := make(map[*Instance]bool)
+	for k := range keys {
+		before[k] = b.pick(request(fmt.Sprint("session-", k)), instances)
+		if again := b.pick(request(fmt.Sprint("session-", k)), instances); again != before[k] {
+			t.Fatalf("key %d: %s then %s", k, before[k].URL.Host, again.URL.Host)
+		}
+		used[before[k]] = true
+	}
+	if len(used) != len(instances) {
+		t.Fatalf("%d of %d instances used", len(used), len(instances))
+	}
+
+	// без одного экземпляра переезжают только его ключи
+	removed := instances[1]
+	rest := []*Instance{instances[0], instances[2], instances[3]}
+	b3 := newBalancer(uc, rest)
+	for k := range keys {
+		got := b3.pick(request(fmt.Sprint("session-", k)), rest)
+		if before[k] != removed && got != before[k] {
+			t.Fatalf("key %d moved from %s to %s", k, before[k].URL.Host, got.URL.Host)
+		}
+	}
+
+	// то же кольцо в новом балансировщике (перезагрузка конфигурации)
+	b2 := newBalancer(uc, instances)
+	for k := range keys {
+		if got := b2.pick(request(fmt.Sprint("session-", k)), instances); got != before[k] {
+			t.Fatalf("key %d: %s after rebuild, want %s", k, got.URL.Host, before[k].URL.Host)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package proxy
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/psds-microservice/api-gateway/internal/config"
+	"github.com/psds-microservice/api-gateway/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// Состояния circuit breaker-а (значения совпадают с метрикой circuit_breaker_state).
+const (
+	stateClosed breakerState = iota
+	stateHalfOpen
+	stateOpen
+)
+
+type breakerState int
+
+func (s breakerState) String() string {
+	switch s {
+	case stateHalfOpen:
+		return "half-open"
+	case stateOpen:
+		return "open"
+	}
+	return "closed"
+}
+
+// Исход запроса для breaker-а.
+const (
+	outcomeSuccess outcome = iota
+	outcomeFailure
+	// outcomeIgnored — запрос отменён клиентом: о состоянии upstream-а он ничего не говорит.
+	outcomeIgnored
+)
+
+type outcome int
+
+// circuitOpenError — breaker upstream-а разомкнут, запрос не отправлялся.
+type circuitOpenError struct {
+	upstream   string
+	retryAfter time.Duration
+}
+
+func (e *circuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker open for upstream %s", e.upstream)
+}
+
+// breaker — circuit breaker upstream-а. Считает ошибки подряд и долю ошибок в окне
+// фиксированной длины; в half-open пропускает ограниченное число пробных запросов.
+type breaker struct {
+	upstream string
+	cfg      config.CircuitBreakerConfig
+	logger   *zap.Logger
+	now      func() time.Time
+
+	mu          sync.Mutex
+	state       breakerState
+	gen         uint64 // меняется при каждом переходе: результаты запросов прошлого состояния отбрасываются
+	openedAt    time.Time
+	consecutive int
+	windowStart time.Time
+	requests    int
+	failures    int
+	probes      int // пробные запросы в полёте (half-open)
+	probeOK     int
+}
+
+// newBreaker возвращает breaker или nil, если он выключен.
+func newBreaker(upstream string, cfg *config.CircuitBreakerConfig, logger *zap.Logger) *breaker {
+	if cfg == nil || !cfg.Enabled() {
+		return nil
+	}
+	b := &breaker{upstream: upstream, cfg: *cfg, logger: logger, now: time.Now}
+	b.windowStart = b.now()
+	metrics.CircuitBreakerState.WithLabelValues(upstream).Set(float64(stateClosed))
+	return b
+}
+
+// allow решает, пропустить ли запрос. done сообщает исход и обязателен к вызову.
+func (b *breaker) allow() (done func(outcome), err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == stateOpen {
+		wait := b.openFor() - b.now().Sub(b.openedAt)
+		if wait > 0 {
+			metrics.CircuitBreakerRejected.WithLabelValues(b.upstream).Inc()
+			return nil, &circuitOpenError{upstream: b.upstream, retryAfter: wait}
+		}
+		b.transition(stateHalfOpen)
+	}
+	if b.state == stateHalfOpen {
+		if b.probes >= max(b.cfg.HalfOpenRequests, 1) {
+			metrics.CircuitBreakerRejected.WithLabelValues(b.upstream).Inc()
+			return nil, &circuitOpenError{upstream: b.upstream, retryAfter: time.Second}
+		}
+		b.probes++
+	}
+	gen := b.gen
+	return func(o outcome) { b.record(gen, o) }, nil
+}
+
+func (b *breaker) record(gen uint64, o outcome) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if gen != b.gen {
+		return
+	}
+	switch b.state {
+	case stateHalfOpen:
+		b.probes--
+		switch o {
+		case outcomeFailure:
+			b.transition(stateOpen)
+		case outcomeSuccess:
+			b.probeOK++
+			if b.probeOK >= max(b.cfg.HalfOpenRequests, 1) {
+				b.transition(stateClosed)
+			}
+		}
+	case stateClosed:
+		if o == outcomeIgnored {
+			return
+		}
+		now := b.now()
+		if b.cfg.WindowSec > 0 && now.Sub(b.windowStart) >= time.Duration(b.cfg.WindowSec)*time.Second {
+			b.windowStart, b.requests, b.failures = now, 0, 0
+		}
+		b.requests++
+		if o == outcomeSuccess {
+			b.consecutive = 0
+			return
+		}
+		b.failures++
+		b.consecutive++
+		if b.cfg.Failures > 0 && b.consecutive >= b.cfg.Failures {
+			b.transition(stateOpen)
+			return
+		}
+		if b.cfg.ErrorRatePct > 0 && b.requests >= b.cfg.MinRequests && b.failures*100 >= b.cfg.ErrorRatePct*b.requests {
+			b.transition(stateOpen)
+		}
+	}
+}
+
+// transition переводит breaker в новое состояние; вызывается под mu.
+func (b *breaker) transition(to breakerState) {
+	from := b.state
+	b.state = to
+	b.gen++
+	b.probes, b.probeOK = 0, 0
+	fields := []zap.Field{
+		zap.String("upstream", b.upstream),
+		zap.String("from", from.String()),
+		zap.String("to", to.String()),
+	}
+	switch to {
+	case stateOpen:
+		b.openedAt = b.now()
+		fields = append(fields, zap.Int("consecutive_failures", b.consecutive),
+			zap.Int("window_failures", b.failures), zap.Int("window_requests", b.requests))
+		b.logger.Warn("Circuit breaker opened", fields...)
+	case stateClosed:
+		b.consecutive, b.requests, b.failures = 0, 0, 0
+		b.windowStart = b.now()
+		b.logger.Info("Circuit breaker closed", fields...)
+	default:
+		b.logger.Info("Circuit breaker half-open", fields...)
+	}
+	metrics.CircuitBreakerState.WithLabelValues(b.upstream).Set(float64(to))
+	metrics.CircuitBreakerTransitions.WithLabelValues(b.upstream, to.String()).Inc()
+}
+
+func (b *breaker) openFor() time.Duration {
+	return time.Duration(max(b.cfg.OpenSec, 1)) * time.Second
+}
+
+// current возвращает текущее состояние ("closed", "half-open", "open").
+func (b *breaker) current() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state.String()
+}
@@ -0,0 +1,159 @@
+package proxy
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/psds-microservice/api-gateway/internal/config"
+	"go.uber.org/zap"
+)
+
+// testBreaker возвращает breaker с управляемыми часами.
+func testBreaker(t *testing.T, cfg config.CircuitBreakerConfig) (*breaker, *time.Time) {
+	t.Helper()
+	b := newBreaker("test", &cfg, zap.NewNop())
+	if b == nil {
+		t.Fatal("breaker disabled")
+	}
+	now := time.Unix(1700000000, 0)
+	b.now = func() time.Time { return now }
+	b.windowStart = now
+	return b, &now
+}
+
+// call пропускает через breaker один запрос с исходом o; false — запрос отклонён.
+func call(t *testing.T, b *breaker, o outcome) bool {
+	t.Helper()
+	done, err := b.allow()
+	if err != nil {
+		var open *circuitOpenError
+		if !errors.As(err, &open) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return false
+	}
+	done(o)
+	return true
+}
+
+func TestBreakerConsecutiveFailures(t *testing.T) {
+	b, now := testBreaker(t, config.CircuitBreakerConfig{Failures: 3, OpenSec: 10, HalfOpenRequests: 1})
+	call(t, b, outcomeFailure)
+	call(t, b, outcomeFailure)
+	call(t, b, outcomeSuccess) // успех сбрасывает серию
+	call(t, b, outcomeFailure)
+	call(t, b, outcomeFailure)
+	call(t, b, outcomeIgnored) // отменённые клиентом не считаются
+	if got := b.current(); got != "closed" {
+		t.Fatalf("state %q after interrupted series, want closed", got)
+	}
+	call(t, b, outcomeFailure)
+	if got := b.current(); got != "open" {
+		t.Fatalf("state %q after 3 failures in a row, want open", got)
+	}
+	if call(t, b, outcomeSuccess) {
+		t.Fatal("request allowed while open")
+	}
+	*now = now.Add(10 * time.Second)
+	if !call(t, b, outcomeSuccess) {
+		t.Fatal("probe rejected after open_sec")
+	}
+	if got := b.current(); got != "closed" {
+		t.Fatalf("state %q after successful probe, want closed", got)
+	}
+}
+
+func TestBreakerErrorRate(t *testing.T) {
+	b, now := testBreaker(t, config.CircuitBreakerConfig{ErrorRatePct: 50, MinRequests: 4, WindowSec: 10, OpenSec: 5})
+	// 2 из 3: доля выше порога, но запросов меньше min_requests
+	call(t, b, outcomeFailure)
+	call(t, b, outcomeSuccess)
+	call(t, b, outcomeFailure)
+	if got := b.current(); got != "closed" {
+		t.Fatalf("state %q below min_requests, want closed", got)
+	}
+	// новое окно: прошлые ошибки не учитываются
+	*now = now.Add(10 * time.Second)
+	call(t, b, outcomeFailure)
+	call(t, b, outcomeSuccess)
+	call(t, b, outcomeSuccess)
+	call(t, b, outcomeSuccess)
+	if got := b.current(); got != "closed" {
+		t.Fatalf("state %q at 25%% errors, want closed", got)
+	}
+	call(t, b, outcomeFailure)
+	call(t, b, outcomeFailure)
+	if got := b.current(); got != "open" {
+		t.Fatalf("state %q at 50%% errors, want open", got)
+	}
+}
+
+func TestBreakerHalfOpenProbes(t *testing.T) {
+	b, now := testBreaker(t, config.CircuitBreakerConfig{Failures: 1, OpenSec: 1, HalfOpenRequests: 2})
+	call(t, b, outcomeFailure)
+	*now = now.Add(time.Second)
+
+	// в half-open пропускается не больше half_open_requests запросов одновременно
+	done1, err := b.allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	done2, err := b.allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.allow(); err == nil {
+		t.Fatal("third probe allowed, want at most 2")
+	}
+	if got := b.current(); got != "half-open" {
+		t.Fatalf("state %q, want half-open", got)
+	}
+	done1(outcomeSuccess)
+	if got := b.current(); got != "half-open" {
+		t.Fatalf("state %q after 1 of 2 probes, want half-open", got)
+	}
+	done2(outcomeSuccess)
+	if got := b.current(); got != "closed" {
+		t.Fatalf("state %q after 2 successful probes, want closed", got)
+	}
+
+	// неудачная проба снова размыкает
+	call(t, b, outcomeFailure)
+	*now = now.Add(time.Second)
+	call(t, b, outcomeFailure)
+	if got := b.current(); got != "open" {
+		t.Fatalf("state %q after failed probe, want open", got)
+	}
+}
+
+func TestBreakerStaleResult(t *testing.T) {
+	b, now := testBreaker(t, config.CircuitBreakerConfig{Failures: 1, OpenSec: 1, HalfOpenRequests: 1})
+	// запрос начат в closed и завершится после размыкания
+	slow, err := b.allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	call(t, b, outcomeFailure)
+	*now = now.Add(time.Second)
+	probe, err := b.allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// результат прошлого состояния не влияет ни на пробу, ни на её слот
+	slow(outcomeSuccess)
+	if got := b.current(); got != "half-open" {
+		t.Fatalf("state %q after stale success, want half-open", got)
+	}
+	if _, err := b.allow(); err == nil {
+		t.Fatal("stale result released the probe slot")
+	}
+	probe(outcomeSuccess)
+	if got := b.current(); got != "closed" {
+		t.Fatalf("state %q after probe, want closed", got)
+	}
+	slow(outcomeFailure)
+	if got := b.current(); got != "closed" {
+		t.Fatalf("state %q after stale failure, want closed", got)
+	}
+}
@@ -0,0 +1,111 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/psds-microservice/api-gateway/internal/health"
+)
+
+// unhealthyThreshold — число подряд неудачных проверок, после которого экземпляр помечается down.
+const unhealthyThreshold = 2
+
+// InstanceStatus — состояние экземпляра upstream-а для /ready.
+type InstanceStatus struct {
+	URL      string `json:"url"`
+	Status   string `json:"status"`
+	Inflight int64  `json:"inflight"`
+}
+
+// HealthProbes возвращает пробы для upstream-ов текущей таблицы (по одной на upstream с health_path).
+// Проба проверяет все экземпляры и помечает их up/down; upstream down, если down все экземпляры.
+func (rt *Router) HealthProbes() []health.Probe {
+	table := rt.Table()
+	probes := make([]health.Probe, 0, len(table.upstreams))
+	for _, up := range table.upstreams {
+		if up.healthPath == "" {
+			continue
+		}
+		up := up
+		client := &http.Client{Transport: up.transport}
+		probes = append(probes, health.Probe{
+			Name:     up.Name,
+			Required: up.required,
+			Check:    func(ctx context.Context) error { return up.probe(ctx, client) },
+			Details:  up.healthDetails,
+		})
+	}
+	return probes
+}
+
+func (u *Upstream) probe(ctx context.Context, client *http.Client) error {
+	errs := make([]error, len(u.instances))
+	var wg sync.WaitGroup
+	for i, inst := range u.instances {
+		wg.Add(1)
+		go func(i int, inst *Instance) {
+			defer wg.Done()
+			err := probeInstance(ctx, client, inst, u.healthPath)
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", inst.URL.Host, err)
+				if inst.fails.Add(1) >= unhealthyThreshold {
+					inst.down.Store(true)
+				}
+				return
+			}
+			inst.fails.Store(0)
+			inst.down.Store(false)
+		}(i, inst)
+	}
+	wg.Wait()
+	for _, inst := range u.instances {
+		if inst.Healthy() {
+			return nil
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func probeInstance(ctx context.Context, client *http.Client, inst *Instance, path string) error {
+	u := *inst.URL
+	u.Path = singleJoiningSlash(u.Path, path)
+	u.RawPath = ""
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("health status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// UpstreamStatus — состояние upstream-а для /ready.
+type UpstreamStatus struct {
+	Circuit   string           `json:"circuit,omitempty"`
+	Instances []InstanceStatus `json:"instances"`
+}
+
+func (u *Upstream) healthDetails() any {
+	return UpstreamStatus{Circuit: u.CircuitState(), Instances: u.instanceStatuses()}
+}
+
+func (u *Upstream) instanceStatuses() []InstanceStatus {
+	out := make([]InstanceStatus, 0, len(u.instances))
+	for _, inst := range u.instances {
+		st := "up"
+		if !inst.Healthy() {
+			st = "down"
+		}
+		out = append(out, InstanceStatus{URL: inst.URL.String(), Status: st, Inflight: inst.Inflight()})
+	}
+	return out
+}
@@ -0,0 +1,73 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+)
+
+func TestUpstreamHealth(t *testing.T) {
+	var failing atomic.Bool
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() || r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ok.Close()
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer bad.Close()
+
+	up := &Upstream{Name: "test", healthPath: "/health", balancer: &roundRobinBalancer{}}
+	for _, s := range []*httptest.Server{ok, bad} {
+		u, _ := url.Parse(s.URL)
+		up.instances = append(up.instances, &Instance{URL: u, Weight: 1})
+	}
+	good, down := up.instances[0], up.instances[1]
+	client := ok.Client()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	// одна неудачная проверка ещё не выводит экземпляр из балансировки
+	if err := up.probe(context.Background(), client); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if !down.Healthy() {
+		t.Fatal("instance down after a single failed check")
+	}
+	up.probe(context.Background(), client)
+	if down.Healthy() {
+		t.Fatalf("instance up after %d failed checks", unhealthyThreshold)
+	}
+	for range 4 {
+		if got := up.pick(r); got != good {
+			t.Fatalf("picked %s, want only the healthy instance", got.URL.Host)
+		}
+	}
+
+	// все экземпляры down: upstream недоступен, но запросы идут по всем (fail-open)
+	failing.Store(true)
+	up.probe(context.Background(), client)
+	if err := up.probe(context.Background(), client); err == nil {
+		t.Fatal("probe succeeded with every instance down")
+	}
+	seen := make(map[*Instance]bool)
+	for range 4 {
+		seen[up.pick(r)] = true
+	}
+	if !seen[good] || !seen[down] {
+		t.Fatal("fail-open pick does not use every instance")
+	}
+
+	// успешная проверка сразу возвращает экземпляр
+	failing.Store(false)
+	if err := up.probe(context.Background(), client); err != nil {
+		t.Fatalf("probe after recovery: %v", err)
+	}
+	if !good.Healthy() {
+		t.Fatal("instance still down after a successful check")
+	}
+}
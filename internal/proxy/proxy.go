@@ -0,0 +1,155 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/http/httputil"
+	"strconv"
+	"strings"
+
+	"github.com/psds-microservice/api-gateway/internal/auth"
+	"github.com/psds-microservice/api-gateway/internal/clientip"
+	"github.com/psds-microservice/api-gateway/internal/config"
+	"github.com/psds-microservice/api-gateway/internal/metrics"
+	"github.com/psds-microservice/api-gateway/internal/requestid"
+	"github.com/psds-microservice/api-gateway/internal/tracing"
+	"github.com/psds-microservice/api-gateway/pkg/constants"
+	"go.uber.org/zap"
+)
+
+// quietCancelWriter suppresses repeated "context canceled" proxy errors to avoid log flood when many clients time out.
+// quietCancelWriter подавляет повторяющиеся ошибки прокси "context canceled", чтобы не засорять лог при массовых таймаутах.
+type quietCancelWriter struct{ w io.Writer }
+
+func (q quietCancelWriter) Write(p []byte) (n int, err error) {
+	if bytes.Contains(p, []byte("context canceled")) {
+		return len(p), nil
+	}
+	return q.w.Write(p)
+}
+
+// newReverseProxy returns a ReverseProxy for one route: rewrites the matched prefix, sets per-route
+// headers and logs proxy errors except "context canceled" (reduces log spam). The target instance
+// is chosen per request by upstreamTransport; retryTransport repeats failed attempts per route policy.
+func newReverseProxy(up *Upstream, rc config.RouteConfig, prefixLen int, transport http.RoundTripper, logger *zap.Logger) *httputil.ReverseProxy {
+	p := &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			if rc.Rewrite != "" {
+				req.URL.Path = rewritePath(req.URL.Path, rc.Rewrite, prefixLen)
+				req.URL.RawPath = ""
+			}
+			// scheme/host экземпляра подставляет upstreamTransport.
+			req.URL.Scheme = "http"
+			req.URL.Host = up.Name
+			if _, ok := req.Header["User-Agent"]; !ok {
+				// explicitly disable User-Agent so it's not set to default value
+				req.Header.Set("User-Agent", "")
+			}
+			for k, v := range rc.Options.Headers {
+				req.Header.Set(k, v)
+			}
+			// identity передаётся backend-ам только от gateway, не от клиента; API-ключ — учётные данные
+			// самого gateway и backend-ам не пересылается.
+			req.Header.Del(constants.HeaderAPIKey)
+			req.Header.Del(constants.HeaderAuthSubject)
+			req.Header.Del(constants.HeaderAuthRoles)
+			if id, ok := auth.FromContext(req.Context()); ok {
+				req.Header.Set(constants.HeaderAuthSubject, id.Subject)
+				req.Header.Set(constants.HeaderAuthRoles, strings.Join(id.Roles, ","))
+			}
+			// IP клиента с учётом доверенных прокси; X-Forwarded-For дополняет сам ReverseProxy.
+			if ip, ok := clientip.FromContext(req.Context()); ok {
+				req.Header.Set(constants.HeaderRealIP, ip)
+			}
+			if id, ok := requestid.FromContext(req.Context()); ok {
+				req.Header.Set(constants.HeaderRequestID, id)
+			}
+		},
+		// X-Request-ID ответа уже выставлен gateway; эхо backend-а не должно его дублировать.
+		ModifyResponse: func(resp *http.Response) error {
+			if _, ok := requestid.FromContext(resp.Request.Context()); ok {
+				resp.Header.Del(constants.HeaderRequestID)
+			}
+			return nil
+		},
+		Transport: &retryTransport{
+			up:     up,
+			policy: newRetryPolicy(rc.Options.Retry),
+			next:   &upstreamTransport{up: up, base: tracing.Transport(transport, up.Name)},
+		},
+	}
+	// ErrorLog — прочие ошибки ReverseProxy (копирование тела, протокол), тоже через zap.
+	errLog, _ := zap.NewStdLogAt(logger, zap.WarnLevel)
+	p.ErrorLog = log.New(quietCancelWriter{w: errLog.Writer()}, "", 0)
+	p.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		if hw, ok := w.(*wsHijacker); ok && hw.conn != nil {
+			// Ошибка копирования upgraded-соединения — обычное его завершение одной из сторон.
+			requestid.Logger(r.Context(), logger).Debug("WebSocket copy ended",
+				zap.String("upstream", up.Name), zap.Error(err))
+			return
+		}
+		metrics.ProxyErrors.WithLabelValues(up.Name, errorReason(err)).Inc()
+		var open *circuitOpenError
+		if errors.As(err, &open) {
+			writeCircuitOpen(w, open)
+			return
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			writeJSONError(w, http.StatusGatewayTimeout, "gateway timeout",
+				"upstream "+up.Name+" did not respond within the route timeout", up.Name)
+			return
+		}
+		if !errors.Is(err, context.Canceled) {
+			requestid.Logger(r.Context(), logger).Warn("Proxy error",
+				zap.String("upstream", up.Name),
+				zap.String("path", r.URL.Path),
+				zap.Error(err))
+		}
+		writeJSONError(w, http.StatusBadGateway, "bad gateway", "upstream "+up.Name+" is unavailable", up.Name)
+	}
+	return p
+}
+
+// errorReason — причина ошибки прокси для метрики api_gateway_proxy_errors_total.
+func errorReason(err error) string {
+	var open *circuitOpenError
+	switch {
+	case errors.As(err, &open):
+		return "circuit_open"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, errNoInstance):
+		return "no_instance"
+	default:
+		return "transport"
+	}
+}
+
+// writeCircuitOpen отвечает 503 без обращения к upstream-у, пока breaker разомкнут.
+func writeCircuitOpen(w http.ResponseWriter, e *circuitOpenError) {
+	w.Header().Set(constants.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(e.retryAfter.Seconds()))))
+	writeJSONError(w, http.StatusServiceUnavailable, "service unavailable", e.Error(), e.upstream)
+}
+
+// writeJSONError отвечает JSON-ошибкой gateway; request_id — из заголовка ответа (middleware.RequestID).
+func writeJSONError(w http.ResponseWriter, code int, errText, message, upstream string) {
+	body := map[string]string{
+		"error":    errText,
+		"message":  message,
+		"upstream": upstream,
+	}
+	if id := w.Header().Get(constants.HeaderRequestID); id != "" {
+		body["request_id"] = id
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
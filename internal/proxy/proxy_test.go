@@ -0,0 +1,40 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/psds-microservice/api-gateway/internal/config"
+	"github.com/psds-microservice/api-gateway/pkg/constants"
+)
+
+func TestProxyBadGateway(t *testing.T) {
+	// адрес без слушателя: соединение отклоняется
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+	rt := newTestRouterTo(t, "http://"+addr, []config.RouteConfig{{Path: "/api/"}})
+
+	rec := httptest.NewRecorder()
+	rec.Header().Set(constants.HeaderRequestID, "req-1")
+	rt.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/x", nil))
+	if rec.Code != http.StatusBadGateway {
+		t.Fatalf("code %d, want 502", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("Content-Type %q, want application/json", ct)
+	}
+	var body map[string]string
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body["error"] != "bad gateway" || body["upstream"] != "backend" || body["request_id"] != "req-1" {
+		t.Fatalf("body %v", body)
+	}
+}
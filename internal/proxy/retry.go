@@ -0,0 +1,158 @@
+package proxy
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/psds-microservice/api-gateway/internal/config"
+	"github.com/psds-microservice/api-gateway/internal/metrics"
+	"github.com/psds-microservice/api-gateway/internal/retry"
+)
+
+// maxRetryBody — максимальный размер тела запроса, который буферизуется для повтора.
+// Запросы с большим телом или без Content-Length не повторяются.
+const maxRetryBody = 1 << 20
+
+// retryPolicy — скомпилированная config.RetryConfig маршрута.
+type retryPolicy struct {
+	attempts int
+	methods  map[string]bool
+	statuses map[int]bool
+	errors   map[string]bool
+	base     time.Duration
+	maxDelay time.Duration
+}
+
+// newRetryPolicy возвращает nil, если повторы для маршрута выключены.
+func newRetryPolicy(rc *config.RetryConfig) *retryPolicy {
+	if !rc.Enabled() {
+		return nil
+	}
+	p := &retryPolicy{
+		attempts: rc.Attempts,
+		methods:  make(map[string]bool, len(rc.Methods)),
+		statuses: make(map[int]bool, len(rc.Statuses)),
+		errors:   make(map[string]bool, len(rc.Errors)),
+		base:     time.Duration(rc.BaseDelayMs) * time.Millisecond,
+		maxDelay: time.Duration(rc.MaxDelayMs) * time.Millisecond,
+	}
+	for _, m := range rc.Methods {
+		p.methods[m] = true
+	}
+	for _, st := range rc.Statuses {
+		p.statuses[st] = true
+	}
+	for _, e := range rc.Errors {
+		p.errors[e] = true
+	}
+	return p
+}
+
+// retryable — можно ли повторить запрос после такого результата попытки.
+func (p *retryPolicy) retryable(req *http.Request, resp *http.Response, err error) bool {
+	if err == nil {
+		return p.statuses[resp.StatusCode]
+	}
+	if req.Context().Err() != nil {
+		return false
+	}
+	var open *circuitOpenError
+	if errors.As(err, &open) || errors.Is(err, errNoInstance) {
+		return false
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return p.errors[config.RetryOnConnect]
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return p.errors[config.RetryOnTimeout]
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return p.errors[config.RetryOnReset]
+	}
+	return false
+}
+
+// retryTransport повторяет запросы маршрута по retryPolicy в пределах бюджета upstream-а.
+// Каждая попытка заново проходит breaker и выбор экземпляра (upstreamTransport).
+type retryTransport struct {
+	up     *Upstream
+	policy *retryPolicy // nil = без повторов
+	next   http.RoundTripper
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	end := t.up.retryBudget.Begin()
+	defer end()
+	if t.policy == nil || !t.policy.methods[req.Method] || !rewindable(req) {
+		return t.next.RoundTrip(req)
+	}
+	var release func() // слот бюджета держится до завершения повторной попытки
+	for attempt := 1; ; attempt++ {
+		resp, err := t.next.RoundTrip(req)
+		if release != nil {
+			release()
+		}
+		if attempt > t.policy.attempts || !t.policy.retryable(req, resp, err) {
+			return resp, err
+		}
+		var ok bool
+		if release, ok = t.up.retryBudget.TryRetry(); !ok {
+			metrics.Retries.WithLabelValues(t.up.Name, "budget_exhausted").Inc()
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		if err := t.prepare(req, attempt); err != nil {
+			release()
+			return nil, err
+		}
+		metrics.Retries.WithLabelValues(t.up.Name, "retried").Inc()
+	}
+}
+
+// prepare ждёт backoff и восстанавливает тело запроса для следующей попытки.
+func (t *retryTransport) prepare(req *http.Request, attempt int) error {
+	if err := retry.Sleep(req.Context(), retry.Backoff(attempt, t.policy.base, t.policy.maxDelay)); err != nil {
+		return err
+	}
+	if req.GetBody == nil {
+		return nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return err
+	}
+	req.Body = body
+	return nil
+}
+
+// rewindable готовит тело запроса к повторной отправке: небольшое тело с известной длиной
+// буферизуется. false — запрос нельзя повторить.
+func rewindable(req *http.Request) bool {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody != nil {
+		return true
+	}
+	if req.ContentLength <= 0 || req.ContentLength > maxRetryBody {
+		return false
+	}
+	data, err := io.ReadAll(io.LimitReader(req.Body, maxRetryBody+1))
+	req.Body.Close()
+	if err != nil || int64(len(data)) != req.ContentLength {
+		// Тело уже прочитано: отправляем что есть, без повторов.
+		req.Body = io.NopCloser(bytes.NewReader(data))
+		return false
+	}
+	req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(data)), nil }
+	req.Body, _ = req.GetBody()
+	return true
+}
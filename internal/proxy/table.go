@@ -0,0 +1,181 @@
+package proxy
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/psds-microservice/api-gateway/internal/cache"
+	"github.com/psds-microservice/api-gateway/internal/coalesce"
+	"github.com/psds-microservice/api-gateway/internal/config"
+	"github.com/psds-microservice/api-gateway/internal/match"
+	"github.com/psds-microservice/api-gateway/internal/reqinfo"
+	"go.uber.org/zap"
+)
+
+// Table — скомпилированная таблица маршрутов прокси (неизменяемая после NewTable).
+// У каждой таблицы свой Transport (и свои у upstream-ов с tls): после замены таблицы (hot reload)
+// старые соединения дорабатывают свои запросы, а простаивающие закрываются через Close.
+type Table struct {
+	routes    []*route
+	index     match.HTTP[*route]
+	upstreams map[string]*Upstream
+	transport *http.Transport
+}
+
+type route struct {
+	cfg     config.RouteConfig
+	handler http.Handler
+
+	timeout     time.Duration // 0 = без таймаута
+	idleTimeout time.Duration // для upgraded-соединений; 0 = без таймаута
+}
+
+// NewTable строит прокси для маршрутов gw. Маршруты к upstream-ам без URL пропускаются.
+// gw должен быть провалидирован (config.LoadGateway). Маршруты с options.cache кэшируются в c
+// (nil — кэш не используется), с options.coalesce — объединяют одинаковые GET-запросы,
+// с options.websocket — учитываются в sockets.
+func NewTable(gw *config.Gateway, c *cache.Cache, sockets *WebSockets, logger *zap.Logger) (*Table, error) {
+	t := &Table{
+		upstreams: make(map[string]*Upstream),
+		transport: http.DefaultTransport.(*http.Transport).Clone(),
+	}
+	for _, uc := range gw.Upstreams {
+		if !uc.Enabled() {
+			continue
+		}
+		up, err := newUpstream(uc, t.transport, logger)
+		if err != nil {
+			return nil, err
+		}
+		t.upstreams[uc.Name] = up
+		targets := make([]string, 0, len(up.instances))
+		for _, inst := range up.instances {
+			targets = append(targets, inst.URL.String())
+		}
+		logger.Info("Proxy upstream",
+			zap.String("upstream", uc.Name),
+			zap.String("policy", uc.Policy),
+			zap.Strings("targets", targets))
+	}
+	for _, rc := range gw.Routes {
+		up := t.upstreams[rc.Upstream]
+		if up == nil {
+			logger.Debug("Proxy route skipped: upstream not configured",
+				zap.String("path", rc.Path), zap.String("upstream", rc.Upstream))
+			continue
+		}
+		rt := &route{
+			cfg:         rc,
+			timeout:     time.Duration(rc.Options.TimeoutMs) * time.Millisecond,
+			idleTimeout: time.Duration(rc.Options.IdleTimeoutSec) * time.Second,
+		}
+		rt.handler = newReverseProxy(up, rc, len(config.SplitPath(rc.Path)), up.transport, logger)
+		if rc.Options.WebSocket != nil && sockets != nil {
+			rt.handler = sockets.handler(rc, rt.idleTimeout, rt.handler)
+			rt.idleTimeout = 0 // простой считает WebSocket-режим по кадрам данных
+		}
+		if rc.Options.Cache != nil && c != nil {
+			rt.handler = c.Handler(rc.Path, rc.Options.Cache, rt.handler)
+		}
+		// Снаружи кэша: попадания отдаются сразу, объединяются только промахи.
+		if rc.Options.Coalesce {
+			rt.handler = coalesce.New().Handler(rt.handler)
+		}
+		t.routes = append(t.routes, rt)
+		t.index.Add(rc.Path, rc.Methods, rt)
+		logger.Info("Proxy route",
+			zap.String("path", rc.Path),
+			zap.Strings("methods", rc.Methods),
+			zap.String("upstream", up.Name))
+	}
+	return t, nil
+}
+
+// Close закрывает простаивающие соединения таблицы. Активные запросы (в т.ч. WebSocket) не прерываются.
+func (t *Table) Close() {
+	t.transport.CloseIdleConnections()
+	for _, up := range t.upstreams {
+		up.transport.CloseIdleConnections()
+	}
+}
+
+// Upstreams возвращает подключённые upstream-ы таблицы.
+func (t *Table) Upstreams() map[string]*Upstream {
+	return t.upstreams
+}
+
+// Routes возвращает конфигурацию активных маршрутов (для /api/v1/status).
+func (t *Table) Routes() []config.RouteConfig {
+	out := make([]config.RouteConfig, 0, len(t.routes))
+	for _, rt := range t.routes {
+		out = append(out, rt.cfg)
+	}
+	return out
+}
+
+// rewritePath заменяет первые prefixLen сегментов пути на rewrite, сохраняя хвост и завершающий "/".
+func rewritePath(path, rewrite string, prefixLen int) string {
+	segs := config.SplitPath(path)
+	out := strings.TrimSuffix(rewrite, "/")
+	if prefixLen < len(segs) {
+		out += "/" + strings.Join(segs[prefixLen:], "/")
+	}
+	if strings.HasSuffix(path, "/") && !strings.HasSuffix(out, "/") {
+		out += "/"
+	}
+	if out == "" {
+		out = "/"
+	}
+	return out
+}
+
+// Router — http.Handler: запросы, совпавшие с маршрутом таблицы, уходят в прокси, остальные — в next.
+// Таблица заменяется атомарно (Swap); запрос, уже получивший таблицу, обслуживается ею до конца.
+type Router struct {
+	table atomic.Pointer[Table]
+	next  http.Handler
+}
+
+// NewRouter создаёт Router поверх таблицы маршрутов.
+func NewRouter(table *Table, next http.Handler) *Router {
+	rt := &Router{next: next}
+	rt.table.Store(table)
+	return rt
+}
+
+// Table возвращает текущую таблицу маршрутов.
+func (rt *Router) Table() *Table {
+	return rt.table.Load()
+}
+
+// Swap атомарно подменяет таблицу маршрутов и возвращает предыдущую.
+func (rt *Router) Swap(t *Table) *Table {
+	return rt.table.Swap(t)
+}
+
+// Route возвращает путь маршрута, который обслужит запрос; ok = false — запрос уйдёт в next
+// (или получит 405).
+func (rt *Router) Route(r *http.Request) (path string, ok bool) {
+	if best, ok := rt.table.Load().index.Lookup(r.Method, r.URL.Path); ok {
+		return best.cfg.Path, true
+	}
+	return "", false
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	table := rt.table.Load()
+	if best, ok := table.index.Lookup(r.Method, r.URL.Path); ok {
+		reqinfo.SetRoute(r.Context(), best.cfg.Path)
+		reqinfo.SetUpstream(r.Context(), best.cfg.Upstream)
+		best.serve(w, r)
+		return
+	}
+	if allow, ok := table.index.Allowed(r.URL.Path); ok {
+		w.Header().Set("Allow", strings.Join(allow, ", "))
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	rt.next.ServeHTTP(w, r)
+}
@@ -0,0 +1,137 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/psds-microservice/api-gateway/internal/config"
+	"go.uber.org/zap"
+)
+
+// newTestRouter поднимает backend, отвечающий "<маршрут> <путь>", и Router с маршрутами routes к нему.
+// Маршрут помечается заголовком X-Test-Route (options.headers) со значением своего path.
+func newTestRouter(t *testing.T, routes []config.RouteConfig) *Router {
+	t.Helper()
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s", r.Header.Get("X-Test-Route"), r.URL.Path)
+	}))
+	t.Cleanup(backend.Close)
+	for i := range routes {
+		routes[i].Options.Headers = map[string]string{"X-Test-Route": routes[i].Path}
+	}
+	return newTestRouterTo(t, backend.URL, routes)
+}
+
+// newTestRouterTo возвращает Router с маршрутами routes к upstream-у "backend" по адресу url.
+func newTestRouterTo(t *testing.T, url string, routes []config.RouteConfig) *Router {
+	t.Helper()
+	for i := range routes {
+		routes[i].Upstream = "backend"
+	}
+	data, err := json.Marshal(config.Gateway{
+		Upstreams: []config.UpstreamConfig{{Name: "backend", URL: url}},
+		Routes:    routes,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.Load()
+	cfg.GatewayFile = filepath.Join(t.TempDir(), "gateway.json")
+	if err := os.WriteFile(cfg.GatewayFile, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	gw, err := config.LoadGateway(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	table, err := NewTable(gw, nil, nil, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(table.Close)
+	return NewRouter(table, http.NotFoundHandler())
+}
+
+func TestRouterMatch(t *testing.T) {
+	rt := newTestRouter(t, []config.RouteConfig{
+		{Path: "/api/v1/operators"},
+		{Path: "/api/v1/operators/available"},
+		{Path: "/api/v1/operators/*/availability"},
+		{Path: "/session/"},
+		{Path: "/api/v1/tickets", Methods: []string{http.MethodGet, http.MethodHead}},
+		{Path: "/api/v1/tickets", Methods: []string{http.MethodPost}},
+		{Path: "/search", Rewrite: "/"},
+		{Path: "/svc/*/", Rewrite: "/internal/"},
+	})
+	tests := []struct {
+		method, path string
+		code         int
+		body         string // "<маршрут> <путь у backend-а>"
+		allow        string
+	}{
+		// наиболее специфичный: больше сегментов, затем больше литеральных
+		{http.MethodGet, "/api/v1/operators", http.StatusOK, "/api/v1/operators /api/v1/operators", ""},
+		{http.MethodGet, "/api/v1/operators/42", http.StatusOK, "/api/v1/operators /api/v1/operators/42", ""},
+		{http.MethodGet, "/api/v1/operators/available", http.StatusOK, "/api/v1/operators/available /api/v1/operators/available", ""},
+		{http.MethodGet, "/api/v1/operators/42/availability", http.StatusOK, "/api/v1/operators/*/availability /api/v1/operators/42/availability", ""},
+		{http.MethodGet, "/api/v1/operators/available/availability", http.StatusOK, "/api/v1/operators/*/availability /api/v1/operators/available/availability", ""},
+		{http.MethodGet, "/api/v1/operatorsx", http.StatusNotFound, "", ""},
+		// "/x/" — только пути внутри, "/x" — сам путь и поддерево
+		{http.MethodGet, "/session", http.StatusNotFound, "", ""},
+		{http.MethodGet, "/session/", http.StatusOK, "/session/ /session/", ""},
+		{http.MethodGet, "/session/abc", http.StatusOK, "/session/ /session/abc", ""},
+		// методы: маршрут выбирается по методу, иначе 405 с Allow всех совпавших по пути
+		{http.MethodGet, "/api/v1/tickets/7", http.StatusOK, "/api/v1/tickets /api/v1/tickets/7", ""},
+		{http.MethodPost, "/api/v1/tickets", http.StatusOK, "/api/v1/tickets /api/v1/tickets", ""},
+		{http.MethodDelete, "/api/v1/tickets/7", http.StatusMethodNotAllowed, "", "GET, HEAD, POST"},
+		// rewrite
+		{http.MethodGet, "/search", http.StatusOK, "/search /", ""},
+		{http.MethodGet, "/search/q/", http.StatusOK, "/search /q/", ""},
+		{http.MethodGet, "/svc/a/b/", http.StatusOK, "/svc/*/ /internal/b/", ""},
+		{http.MethodGet, "/unknown", http.StatusNotFound, "", ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		rt.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+		if rec.Code != tt.code {
+			t.Errorf("%s %s: code %d, want %d", tt.method, tt.path, rec.Code, tt.code)
+			continue
+		}
+		if tt.body != "" {
+			if body, _ := io.ReadAll(rec.Body); string(body) != tt.body {
+				t.Errorf("%s %s: got %q, want %q", tt.method, tt.path, body, tt.body)
+			}
+		}
+		if allow := rec.Header().Get("Allow"); allow != tt.allow {
+			t.Errorf("%s %s: Allow %q, want %q", tt.method, tt.path, allow, tt.allow)
+		}
+	}
+}
+
+func TestRewritePath(t *testing.T) {
+	tests := []struct {
+		path, rewrite string
+		prefixLen     int
+		want          string
+	}{
+		{"/search", "/", 1, "/"},
+		{"/search/", "/", 1, "/"},
+		{"/search/q", "/", 1, "/q"},
+		{"/search/q/", "/", 1, "/q/"},
+		{"/api/v1/x/1", "/v2/x", 3, "/v2/x/1"},
+		{"/api/v1/x/", "/v2/x/", 3, "/v2/x/"},
+		{"/api/v1/x", "/v2/x/", 3, "/v2/x"},
+		{"/a/b/c/", "/z", 1, "/z/b/c/"},
+	}
+	for _, tt := range tests {
+		if got := rewritePath(tt.path, tt.rewrite, tt.prefixLen); got != tt.want {
+			t.Errorf("rewritePath(%q, %q, %d) = %q, want %q", tt.path, tt.rewrite, tt.prefixLen, got, tt.want)
+		}
+	}
+}
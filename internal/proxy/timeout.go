@@ -0,0 +1,123 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/psds-microservice/api-gateway/pkg/constants"
+)
+
+// writeGrace — запас к дедлайну записи, чтобы после таймаута успеть отдать клиенту 504.
+const writeGrace = time.Second
+
+// serve обслуживает запрос маршрута с его таймаутами. Обычный запрос ограничен timeout
+// (или меньшим X-Request-Timeout-Ms клиента); upgraded-соединение живёт, пока не простаивает
+// дольше idleTimeout.
+func (rt *route) serve(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	if isUpgrade(r) {
+		// Общие дедлайны сервера к WebSocket не применяются.
+		_ = rc.SetReadDeadline(time.Time{})
+		_ = rc.SetWriteDeadline(time.Time{})
+		if rt.idleTimeout > 0 {
+			w = &idleTimeoutWriter{ResponseWriter: w, idle: rt.idleTimeout}
+		}
+		rt.handler.ServeHTTP(w, r)
+		return
+	}
+
+	timeout := rt.timeout
+	if d, ok := clientTimeout(r); ok && (timeout <= 0 || d < timeout) {
+		timeout = d
+	}
+	if timeout <= 0 {
+		rt.handler.ServeHTTP(w, r)
+		return
+	}
+	deadline := time.Now().Add(timeout)
+	ctx, cancel := context.WithDeadline(r.Context(), deadline)
+	defer cancel()
+	_ = rc.SetReadDeadline(deadline)
+	_ = rc.SetWriteDeadline(deadline.Add(writeGrace))
+	// Дедлайн записи остаётся на keep-alive соединении — снимаем его для следующего запроса.
+	defer rc.SetWriteDeadline(time.Time{})
+	rt.handler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// clientTimeout — таймаут, запрошенный клиентом в X-Request-Timeout-Ms.
+func clientTimeout(r *http.Request) (time.Duration, bool) {
+	v := r.Header.Get(constants.HeaderRequestTimeout)
+	if v == "" {
+		return 0, false
+	}
+	ms, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || ms <= 0 {
+		return 0, false
+	}
+	return time.Duration(ms) * time.Millisecond, true
+}
+
+// setDeadlineHeader передаёт upstream-у оставшееся до дедлайна запроса время.
+func setDeadlineHeader(req *http.Request) {
+	dl, ok := req.Context().Deadline()
+	if !ok {
+		req.Header.Del(constants.HeaderRequestTimeout)
+		return
+	}
+	ms := max(time.Until(dl).Milliseconds(), 1)
+	req.Header.Set(constants.HeaderRequestTimeout, strconv.FormatInt(ms, 10))
+}
+
+func isUpgrade(r *http.Request) bool {
+	if r.Header.Get("Upgrade") == "" {
+		return false
+	}
+	for _, v := range r.Header.Values("Connection") {
+		for _, tok := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(tok), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// idleTimeoutWriter отдаёт ReverseProxy соединение, которое закрывается по таймауту простоя:
+// каждый Read/Write продлевает дедлайн, поэтому активность в любую сторону держит его открытым.
+type idleTimeoutWriter struct {
+	http.ResponseWriter
+	idle time.Duration
+}
+
+func (w *idleTimeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	_ = conn.SetDeadline(time.Now().Add(w.idle))
+	return &idleConn{Conn: conn, idle: w.idle}, brw, nil
+}
+
+func (w *idleTimeoutWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+type idleConn struct {
+	net.Conn
+	idle time.Duration
+}
+
+func (c *idleConn) Read(p []byte) (int, error) {
+	_ = c.Conn.SetDeadline(time.Now().Add(c.idle))
+	return c.Conn.Read(p)
+}
+
+func (c *idleConn) Write(p []byte) (int, error) {
+	_ = c.Conn.SetDeadline(time.Now().Add(c.idle))
+	return c.Conn.Write(p)
+}
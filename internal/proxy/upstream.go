@@ -0,0 +1,254 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+
+	"github.com/psds-microservice/api-gateway/internal/certs"
+	"github.com/psds-microservice/api-gateway/internal/config"
+	"github.com/psds-microservice/api-gateway/internal/retry"
+	"go.uber.org/zap"
+)
+
+// errNoInstance — у upstream-а нет доступных экземпляров.
+var errNoInstance = errors.New("no upstream instance available")
+
+// Upstream — набор экземпляров backend-а с политикой балансировки.
+type Upstream struct {
+	Name       string
+	healthPath string
+	required   bool
+	instances  []*Instance
+	balancer   balancer
+	breaker    *breaker // nil = circuit breaker выключен
+	// retryBudget — бюджет повторов, общий для всех маршрутов upstream-а (в пределах реплики).
+	retryBudget *retry.LocalBudget
+	// transport — соединения с экземплярами (свой при tls upstream-а, иначе общий для таблицы).
+	transport *http.Transport
+}
+
+// Instance — экземпляр upstream-а.
+type Instance struct {
+	URL      *url.URL
+	Weight   int
+	inflight atomic.Int64
+	down     atomic.Bool
+	fails    atomic.Int32
+}
+
+// Inflight возвращает число активных запросов к экземпляру.
+func (i *Instance) Inflight() int64 {
+	return i.inflight.Load()
+}
+
+// Healthy — экземпляр не помечен активной проверкой как недоступный.
+func (i *Instance) Healthy() bool {
+	return !i.down.Load()
+}
+
+// newUpstream создаёт upstream поверх transport; при uc.TLS соединения идут через его клон
+// с CA и клиентским сертификатом upstream-а.
+func newUpstream(uc config.UpstreamConfig, transport *http.Transport, logger *zap.Logger) (*Upstream, error) {
+	up := &Upstream{
+		Name:       uc.Name,
+		healthPath: uc.HealthPath,
+		required:   uc.Required,
+		breaker:    newBreaker(uc.Name, uc.CircuitBreaker, logger),
+		transport:  transport,
+	}
+	if uc.TLS != nil {
+		store, err := certs.New(uc.Name, *uc.TLS, logger)
+		if err != nil {
+			return nil, fmt.Errorf("upstream %s: %w", uc.Name, err)
+		}
+		up.transport = transport.Clone()
+		up.transport.DialTLSContext = store.DialTLS(up.transport)
+	}
+	if rb := uc.RetryBudget; rb != nil {
+		up.retryBudget = retry.NewLocalBudget(rb.Percent, rb.MinConcurrent)
+	} else {
+		up.retryBudget = retry.NewLocalBudget(0, 0)
+	}
+	for _, t := range uc.Targets {
+		u, err := url.Parse(t.URL)
+		if err != nil {
+			return nil, fmt.Errorf("upstream %s: %w", uc.Name, err)
+		}
+		up.instances = append(up.instances, &Instance{URL: u, Weight: max(t.Weight, 1)})
+	}
+	up.balancer = newBalancer(uc, up.instances)
+	return up, nil
+}
+
+// CircuitState возвращает состояние circuit breaker-а ("" — breaker выключен).
+func (u *Upstream) CircuitState() string {
+	if u.breaker == nil {
+		return ""
+	}
+	return u.breaker.current()
+}
+
+// Instances возвращает экземпляры upstream-а.
+func (u *Upstream) Instances() []*Instance {
+	return u.instances
+}
+
+// pick выбирает экземпляр среди здоровых. Если здоровых нет, выбор идёт среди всех
+// (fail-open: активная проверка могла ошибиться, а отказ прокси гарантирован).
+func (u *Upstream) pick(r *http.Request) *Instance {
+	if len(u.instances) == 0 {
+		return nil
+	}
+	candidates := u.instances
+	healthy := 0
+	for _, inst := range u.instances {
+		if inst.Healthy() {
+			healthy++
+		}
+	}
+	if healthy > 0 && healthy < len(u.instances) {
+		candidates = make([]*Instance, 0, healthy)
+		for _, inst := range u.instances {
+			if inst.Healthy() {
+				candidates = append(candidates, inst)
+			}
+		}
+	}
+	return u.balancer.pick(r, candidates)
+}
+
+// upstreamTransport выбирает экземпляр upstream-а для каждого запроса, подставляет его
+// scheme/host/базовый путь и считает активные запросы (до закрытия тела ответа).
+// Запросы проходят через circuit breaker upstream-а.
+type upstreamTransport struct {
+	up   *Upstream
+	base http.RoundTripper
+}
+
+func (t *upstreamTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.up.breaker == nil {
+		return t.roundTrip(req)
+	}
+	done, err := t.up.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.roundTrip(req)
+	done(classify(req, resp, err))
+	return resp, err
+}
+
+// classify определяет исход запроса для breaker-а: ошибки транспорта и 502/503/504 — отказ
+// upstream-а; прочие ответы (в т.ч. 500 — ошибка приложения) — успех; отмена клиентом не учитывается.
+func classify(req *http.Request, resp *http.Response, err error) outcome {
+	if err != nil {
+		if errors.Is(req.Context().Err(), context.Canceled) {
+			return outcomeIgnored
+		}
+		return outcomeFailure
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return outcomeFailure
+	}
+	return outcomeSuccess
+}
+
+func (t *upstreamTransport) roundTrip(req *http.Request) (*http.Response, error) {
+	inst := t.up.pick(req)
+	if inst == nil {
+		return nil, fmt.Errorf("%s: %w", t.up.Name, errNoInstance)
+	}
+	out := new(http.Request)
+	*out = *req
+	u := *req.URL
+	u.Scheme = inst.URL.Scheme
+	u.Host = inst.URL.Host
+	u.Path, u.RawPath = joinURLPath(inst.URL, req.URL)
+	if inst.URL.RawQuery != "" {
+		if u.RawQuery == "" {
+			u.RawQuery = inst.URL.RawQuery
+		} else {
+			u.RawQuery = inst.URL.RawQuery + "&" + u.RawQuery
+		}
+	}
+	out.URL = &u
+	setDeadlineHeader(out)
+
+	inst.inflight.Add(1)
+	resp, err := t.base.RoundTrip(out)
+	if err != nil {
+		inst.inflight.Add(-1)
+		return nil, err
+	}
+	done := func() { inst.inflight.Add(-1) }
+	if rwc, ok := resp.Body.(io.ReadWriteCloser); ok {
+		// 101 Switching Protocols: ReverseProxy требует записываемое тело.
+		resp.Body = &trackedRWC{ReadWriteCloser: rwc, done: done}
+	} else {
+		resp.Body = &trackedBody{ReadCloser: resp.Body, done: done}
+	}
+	return resp, nil
+}
+
+type trackedBody struct {
+	io.ReadCloser
+	done   func()
+	closed atomic.Bool
+}
+
+func (b *trackedBody) Close() error {
+	if b.closed.CompareAndSwap(false, true) {
+		b.done()
+	}
+	return b.ReadCloser.Close()
+}
+
+type trackedRWC struct {
+	io.ReadWriteCloser
+	done   func()
+	closed atomic.Bool
+}
+
+func (b *trackedRWC) Close() error {
+	if b.closed.CompareAndSwap(false, true) {
+		b.done()
+	}
+	return b.ReadWriteCloser.Close()
+}
+
+// joinURLPath склеивает базовый путь экземпляра и путь запроса (как httputil.NewSingleHostReverseProxy).
+func joinURLPath(a, b *url.URL) (path, rawpath string) {
+	if a.RawPath == "" && b.RawPath == "" {
+		return singleJoiningSlash(a.Path, b.Path), ""
+	}
+	apath := a.EscapedPath()
+	bpath := b.EscapedPath()
+	aslash := strings.HasSuffix(apath, "/")
+	bslash := strings.HasPrefix(bpath, "/")
+	switch {
+	case aslash && bslash:
+		return a.Path + b.Path[1:], apath + bpath[1:]
+	case !aslash && !bslash:
+		return a.Path + "/" + b.Path, apath + "/" + bpath
+	}
+	return a.Path + b.Path, apath + bpath
+}
+
+func singleJoiningSlash(a, b string) string {
+	aslash := strings.HasSuffix(a, "/")
+	bslash := strings.HasPrefix(b, "/")
+	switch {
+	case aslash && bslash:
+		return a + b[1:]
+	case !aslash && !bslash:
+		return a + "/" + b
+	}
+	return a + b
+}
@@ -0,0 +1,450 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"maps"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/psds-microservice/api-gateway/internal/auth"
+	"github.com/psds-microservice/api-gateway/internal/clientip"
+	"github.com/psds-microservice/api-gateway/internal/config"
+	"github.com/psds-microservice/api-gateway/internal/metrics"
+	"github.com/psds-microservice/api-gateway/internal/requestid"
+	"github.com/psds-microservice/api-gateway/pkg/constants"
+	"go.uber.org/zap"
+)
+
+// Причины отказа в handshake и закрытия соединения (метки метрик websocket_*).
+const (
+	wsRejectUserLimit = "user_limit"
+	wsRejectIPLimit   = "ip_limit"
+	wsRejectShutdown  = "shutdown"
+
+	wsClosed            = "closed"
+	wsClosedIdle        = "idle"
+	wsClosedPingTimeout = "ping_timeout"
+	wsClosedTooBig      = "message_too_big"
+	wsClosedShutdown    = "shutdown"
+)
+
+const (
+	// wsCloseGrace — сколько после кадра Close ждать ответного, прежде чем разорвать соединение.
+	wsCloseGrace = time.Second
+	// wsControlWriteTimeout — дедлайн записи ping: клиент, не читающий сокет, не держит watch.
+	wsControlWriteTimeout = 5 * time.Second
+)
+
+// WebSockets — соединения маршрутов в режиме options.websocket: лимиты одновременных соединений
+// на subject и IP клиента, учёт открытых соединений и их закрытие при остановке gateway.
+// Один экземпляр обслуживает все таблицы маршрутов: соединения переживают перезагрузку.
+type WebSockets struct {
+	logger *zap.Logger
+
+	mu      sync.Mutex
+	perUser map[string]int // маршрут + subject -> соединений
+	perIP   map[string]int // маршрут + IP -> соединений
+	conns   map[*wsConn]struct{}
+	closing bool
+	active  sync.WaitGroup // handshake и соединения, ещё не освободившие лимиты
+}
+
+// NewWebSockets создаёт пустой учёт соединений.
+func NewWebSockets(logger *zap.Logger) *WebSockets {
+	return &WebSockets{
+		logger:  logger,
+		perUser: make(map[string]int),
+		perIP:   make(map[string]int),
+		conns:   make(map[*wsConn]struct{}),
+	}
+}
+
+// Len — число открытых соединений.
+func (ws *WebSockets) Len() int {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return len(ws.conns)
+}
+
+// Shutdown отправляет открытым соединениям Close 1001 и ждёт завершения их обработки (не дольше
+// ctx). Новые handshake после вызова получают 503.
+func (ws *WebSockets) Shutdown(ctx context.Context) {
+	ws.mu.Lock()
+	ws.closing = true
+	conns := slices.Collect(maps.Keys(ws.conns))
+	ws.mu.Unlock()
+	if len(conns) > 0 {
+		ws.logger.Info("Closing WebSocket connections", zap.Int("count", len(conns)))
+	}
+	for _, c := range conns {
+		go c.closeWith(wsCloseGoingAway, "server shutdown", wsClosedShutdown)
+	}
+	done := make(chan struct{})
+	go func() {
+		ws.active.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		ws.logger.Warn("WebSocket connections did not close in time", zap.Int("count", ws.Len()))
+	}
+}
+
+// handler — WebSocket-режим маршрута rc поверх next (ReverseProxy): лимиты на handshake,
+// токен из query — в Authorization, слежение за кадрами upgraded-соединения. Простой idle
+// отсчитывается по кадрам данных, а не по ping/pong. Прочие запросы передаются next как есть.
+func (ws *WebSockets) handler(rc config.RouteConfig, idle time.Duration, next http.Handler) http.Handler {
+	route, cfg := rc.Path, rc.Options.WebSocket
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isUpgrade(r) || !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		user := auth.Subject(r.Context())
+		ip, ok := clientip.FromContext(r.Context())
+		if !ok {
+			ip, _, _ = net.SplitHostPort(r.RemoteAddr)
+		}
+		if reason := ws.acquire(route, user, ip, cfg); reason != "" {
+			metrics.WebSocketRejected.WithLabelValues(route, reason).Inc()
+			requestid.Logger(r.Context(), ws.logger).Debug("WebSocket rejected",
+				zap.String("route", route),
+				zap.String("reason", reason),
+				zap.String("user_id", user),
+				zap.String("client_ip", ip))
+			if reason == wsRejectShutdown {
+				writeJSONError(w, http.StatusServiceUnavailable, "service unavailable", "gateway is shutting down", rc.Upstream)
+				return
+			}
+			writeJSONError(w, http.StatusTooManyRequests, "too many connections",
+				"too many concurrent WebSocket connections", rc.Upstream)
+			return
+		}
+		defer ws.release(route, user, ip)
+
+		hw := &wsHijacker{ResponseWriter: w, ws: ws, route: route, cfg: cfg, idle: idle}
+		next.ServeHTTP(hw, withQueryToken(r))
+		if c := hw.conn; c != nil {
+			_ = c.Close()
+			ws.untrack(c)
+			reason := c.closeReason()
+			metrics.WebSocketConnections.WithLabelValues(route).Dec()
+			metrics.WebSocketClosed.WithLabelValues(route, reason).Inc()
+			requestid.Logger(r.Context(), ws.logger).Debug("WebSocket closed",
+				zap.String("route", route),
+				zap.String("reason", reason),
+				zap.String("user_id", user),
+				zap.String("client_ip", ip),
+				zap.Duration("duration", time.Since(c.opened)))
+		}
+	})
+}
+
+// acquire занимает место в лимитах маршрута; "" — занято, иначе причина отказа.
+func (ws *WebSockets) acquire(route, user, ip string, cfg *config.WebSocketConfig) string {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if ws.closing {
+		return wsRejectShutdown
+	}
+	uk, ik := route+"\x00"+user, route+"\x00"+ip
+	if user != "" && cfg.MaxConnsPerUser > 0 && ws.perUser[uk] >= cfg.MaxConnsPerUser {
+		return wsRejectUserLimit
+	}
+	if ip != "" && cfg.MaxConnsPerIP > 0 && ws.perIP[ik] >= cfg.MaxConnsPerIP {
+		return wsRejectIPLimit
+	}
+	if user != "" {
+		ws.perUser[uk]++
+	}
+	if ip != "" {
+		ws.perIP[ik]++
+	}
+	ws.active.Add(1)
+	return ""
+}
+
+func (ws *WebSockets) release(route, user, ip string) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if user != "" {
+		decrement(ws.perUser, route+"\x00"+user)
+	}
+	if ip != "" {
+		decrement(ws.perIP, route+"\x00"+ip)
+	}
+	ws.active.Done()
+}
+
+func decrement(m map[string]int, key string) {
+	if m[key]--; m[key] <= 0 {
+		delete(m, key)
+	}
+}
+
+// track учитывает upgraded-соединение; во время остановки оно сразу закрывается.
+func (ws *WebSockets) track(route string, c *wsConn) {
+	ws.mu.Lock()
+	ws.conns[c] = struct{}{}
+	closing := ws.closing
+	ws.mu.Unlock()
+	metrics.WebSocketConnections.WithLabelValues(route).Inc()
+	if closing {
+		go c.closeWith(wsCloseGoingAway, "server shutdown", wsClosedShutdown)
+	}
+	go c.watch()
+}
+
+func (ws *WebSockets) untrack(c *wsConn) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	delete(ws.conns, c)
+}
+
+// withQueryToken переносит токен из query handshake (constants.QueryAccessToken) в Authorization:
+// backend получает его как от обычного клиента, а токен не попадает в журналы upstream-а.
+func withQueryToken(r *http.Request) *http.Request {
+	q := r.URL.Query()
+	token := q.Get(constants.QueryAccessToken)
+	if token == "" {
+		return r
+	}
+	r = r.Clone(r.Context())
+	q.Del(constants.QueryAccessToken)
+	r.URL.RawQuery = q.Encode()
+	if r.Header.Get(constants.HeaderAuthorization) == "" {
+		r.Header.Set(constants.HeaderAuthorization, "Bearer "+token)
+	}
+	return r
+}
+
+// wsHijacker отдаёт ReverseProxy соединение клиента, обёрнутое в wsConn.
+type wsHijacker struct {
+	http.ResponseWriter
+	ws    *WebSockets
+	route string
+	cfg   *config.WebSocketConfig
+	idle  time.Duration
+
+	conn *wsConn
+}
+
+func (h *wsHijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(h.ResponseWriter).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	h.conn = newWSConn(conn, h.cfg, h.idle)
+	h.ws.track(h.route, h.conn)
+	return h.conn, brw, nil
+}
+
+func (h *wsHijacker) Unwrap() http.ResponseWriter {
+	return h.ResponseWriter
+}
+
+// wsConn — соединение клиента WebSocket-маршрута. Следит за кадрами в обе стороны (границы
+// кадров, размер сообщений клиента, активность), отправляет клиенту ping и закрывает
+// соединение по таймаутам. Read вызывает только горутина ReverseProxy, копирующая к upstream-у.
+type wsConn struct {
+	net.Conn
+	opened      time.Time
+	idle        time.Duration
+	ping        time.Duration
+	pongTimeout time.Duration
+	maxMessage  uint64
+
+	in    wsFrameParser
+	inMsg uint64 // длина текущего сообщения клиента (с фрагментами)
+
+	wmu       sync.Mutex // запись клиенту: данные upstream-а и собственные кадры gateway
+	out       wsFrameParser
+	pending   []byte // кадр Close, ждущий границы кадра в потоке к клиенту
+	closeSent bool
+
+	ready      atomic.Bool  // ответ 101 отправлен: ReverseProxy начал копирование
+	lastActive atomic.Int64 // последний кадр данных в любую сторону (UnixNano)
+	pingSent   atomic.Int64 // ping, на который клиент ещё ничего не прислал (UnixNano; 0 — нет)
+	reason     atomic.Pointer[string]
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+func newWSConn(conn net.Conn, cfg *config.WebSocketConfig, idle time.Duration) *wsConn {
+	c := &wsConn{
+		Conn:        conn,
+		opened:      time.Now(),
+		idle:        idle,
+		ping:        time.Duration(cfg.PingIntervalSec) * time.Second,
+		pongTimeout: time.Duration(cfg.PongTimeoutSec) * time.Second,
+		maxMessage:  uint64(cfg.MaxMessageBytes),
+		done:        make(chan struct{}),
+	}
+	c.lastActive.Store(c.opened.UnixNano())
+	return c
+}
+
+func (c *wsConn) Read(p []byte) (int, error) {
+	c.ready.Store(true)
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		if ferr := c.in.feed(p[:n], c.inbound); ferr != nil {
+			c.closeWith(wsCloseMessageTooBig, "message too big", wsClosedTooBig)
+			return 0, ferr
+		}
+	}
+	return n, err
+}
+
+func (c *wsConn) inbound(f wsFrame) error {
+	// Любой кадр клиента — ответ на ping.
+	c.pingSent.Store(0)
+	if !f.isData() {
+		return nil
+	}
+	if f.opcode != wsOpContinuation {
+		c.inMsg = 0
+	}
+	c.inMsg += f.length
+	if c.maxMessage > 0 && c.inMsg > c.maxMessage {
+		return errWSMessageTooBig
+	}
+	c.lastActive.Store(time.Now().UnixNano())
+	return nil
+}
+
+func (c *wsConn) Write(p []byte) (int, error) {
+	c.ready.Store(true)
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closeSent && c.pending == nil {
+		// После кадра Close данные клиенту не отправляются.
+		return 0, net.ErrClosed
+	}
+	n, err := c.Conn.Write(p)
+	_ = c.out.feed(p[:n], c.outbound)
+	if err == nil && c.pending != nil && c.out.atBoundary() {
+		_, err = c.Conn.Write(c.pending)
+		c.pending = nil
+	}
+	return n, err
+}
+
+func (c *wsConn) outbound(f wsFrame) error {
+	if f.isData() {
+		c.lastActive.Store(time.Now().UnixNano())
+	}
+	return nil
+}
+
+func (c *wsConn) Close() error {
+	err := net.ErrClosed
+	c.closeOnce.Do(func() {
+		close(c.done)
+		err = c.Conn.Close()
+	})
+	return err
+}
+
+// closeWith отправляет клиенту кадр Close (на ближайшей границе кадра) и разрывает соединение,
+// если за wsCloseGrace его не закрыл ReverseProxy.
+func (c *wsConn) closeWith(code uint16, text, reason string) {
+	c.setReason(reason)
+	if !c.ready.Load() {
+		_ = c.Close()
+		return
+	}
+	// Дедлайн освобождает запись, заблокированную клиентом, который не читает сокет.
+	_ = c.Conn.SetWriteDeadline(time.Now().Add(wsCloseGrace))
+	c.wmu.Lock()
+	_ = c.Conn.SetWriteDeadline(time.Now().Add(wsCloseGrace))
+	if !c.closeSent {
+		c.closeSent = true
+		if f := wsCloseFrame(code, text); c.out.atBoundary() {
+			_, _ = c.Conn.Write(f)
+		} else {
+			c.pending = f
+		}
+	}
+	c.wmu.Unlock()
+	time.AfterFunc(wsCloseGrace, func() { _ = c.Close() })
+}
+
+// watch отправляет ping и закрывает соединение по таймаутам простоя и ответа на ping.
+func (c *wsConn) watch() {
+	tick := time.Duration(0)
+	for _, d := range []time.Duration{c.ping, c.pongTimeout, c.idle} {
+		if d > 0 && (tick == 0 || d < tick) {
+			tick = d
+		}
+	}
+	if tick == 0 {
+		return
+	}
+	t := time.NewTicker(tick)
+	defer t.Stop()
+	// Ping и его ответ отсчитываются от тактов тикера: полтакта запаса, потому что интервалы
+	// тикера бывают чуть короче периода.
+	lastPing := c.opened
+	for {
+		select {
+		case <-c.done:
+			return
+		case now := <-t.C:
+			if c.idle > 0 && now.Sub(time.Unix(0, c.lastActive.Load())) >= c.idle {
+				c.closeWith(wsCloseNormal, "idle timeout", wsClosedIdle)
+				return
+			}
+			if sent := c.pingSent.Load(); sent != 0 {
+				if c.pongTimeout > 0 && now.Add(tick/2).Sub(time.Unix(0, sent)) >= c.pongTimeout {
+					// Клиент не отвечает — кадр Close он тоже не прочитает.
+					c.setReason(wsClosedPingTimeout)
+					_ = c.Close()
+					return
+				}
+				continue
+			}
+			if c.ping > 0 && now.Add(tick/2).Sub(lastPing) >= c.ping && c.sendPing(now) {
+				lastPing = now
+			}
+		}
+	}
+}
+
+// sendPing отправляет ping, если поток к клиенту на границе кадра и никто не пишет.
+func (c *wsConn) sendPing(now time.Time) bool {
+	if !c.ready.Load() || !c.wmu.TryLock() {
+		return false
+	}
+	defer c.wmu.Unlock()
+	if c.closeSent || !c.out.atBoundary() {
+		return false
+	}
+	_ = c.Conn.SetWriteDeadline(now.Add(wsControlWriteTimeout))
+	_, err := c.Conn.Write(wsControlFrame(wsOpPing, nil))
+	_ = c.Conn.SetWriteDeadline(time.Time{})
+	if err != nil {
+		return false
+	}
+	c.pingSent.Store(now.UnixNano())
+	return true
+}
+
+func (c *wsConn) setReason(reason string) {
+	c.reason.CompareAndSwap(nil, &reason)
+}
+
+func (c *wsConn) closeReason() string {
+	if r := c.reason.Load(); r != nil {
+		return *r
+	}
+	return wsClosed
+}
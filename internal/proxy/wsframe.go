@@ -0,0 +1,111 @@
+package proxy
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Коды операций кадров WebSocket (RFC 6455, 5.2).
+const (
+	wsOpContinuation = 0x0
+	wsOpText         = 0x1
+	wsOpBinary       = 0x2
+	wsOpClose        = 0x8
+	wsOpPing         = 0x9
+)
+
+// Коды закрытия соединения (RFC 6455, 7.4.1).
+const (
+	wsCloseNormal        = 1000
+	wsCloseGoingAway     = 1001
+	wsCloseMessageTooBig = 1009
+)
+
+// wsMaxCloseReason — наибольшая причина закрытия: 125 байт управляющего кадра минус код.
+const wsMaxCloseReason = 123
+
+var errWSMessageTooBig = errors.New("websocket message too big")
+
+// wsFrame — заголовок кадра.
+type wsFrame struct {
+	opcode byte
+	length uint64
+}
+
+func (f wsFrame) isData() bool {
+	return f.opcode == wsOpContinuation || f.opcode == wsOpText || f.opcode == wsOpBinary
+}
+
+// wsFrameParser следит за границами кадров в потоке байтов одного направления: разбирает
+// заголовки и пропускает полезную нагрузку, не копируя её.
+type wsFrameParser struct {
+	hdr  [14]byte // 2 байта + расширенная длина (до 8) + маска (4)
+	n    int      // собрано байтов заголовка
+	left uint64   // осталось байтов полезной нагрузки текущего кадра
+}
+
+// feed разбирает очередную порцию потока и вызывает onFrame для каждого полного заголовка;
+// ошибка onFrame прерывает разбор.
+func (p *wsFrameParser) feed(b []byte, onFrame func(wsFrame) error) error {
+	for len(b) > 0 {
+		if p.left > 0 {
+			k := min(uint64(len(b)), p.left)
+			p.left -= k
+			b = b[k:]
+			continue
+		}
+		p.hdr[p.n] = b[0]
+		p.n++
+		b = b[1:]
+		if p.n < 2 {
+			continue
+		}
+		need := 2
+		switch p.hdr[1] & 0x7f {
+		case 126:
+			need += 2
+		case 127:
+			need += 8
+		}
+		if p.hdr[1]&0x80 != 0 {
+			need += 4
+		}
+		if p.n < need {
+			continue
+		}
+		f := wsFrame{opcode: p.hdr[0] & 0x0f}
+		switch l := p.hdr[1] & 0x7f; l {
+		case 126:
+			f.length = uint64(binary.BigEndian.Uint16(p.hdr[2:4]))
+		case 127:
+			f.length = binary.BigEndian.Uint64(p.hdr[2:10])
+		default:
+			f.length = uint64(l)
+		}
+		p.n = 0
+		p.left = f.length
+		if err := onFrame(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// atBoundary — поток остановился между кадрами: сюда можно вставить свой управляющий кадр.
+func (p *wsFrameParser) atBoundary() bool {
+	return p.n == 0 && p.left == 0
+}
+
+// wsControlFrame — управляющий кадр сервера (без маски).
+func wsControlFrame(opcode byte, payload []byte) []byte {
+	return append([]byte{0x80 | opcode, byte(len(payload))}, payload...)
+}
+
+// wsCloseFrame — кадр Close с кодом и причиной.
+func wsCloseFrame(code uint16, reason string) []byte {
+	if len(reason) > wsMaxCloseReason {
+		reason = reason[:wsMaxCloseReason]
+	}
+	payload := binary.BigEndian.AppendUint16(nil, code)
+	return wsControlFrame(wsOpClose, append(payload, reason...))
+}
@@ -0,0 +1,50 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval — как часто Local удаляет наполнившиеся вёдра (полное ведро равно отсутствующему).
+const sweepInterval = time.Minute
+
+// Local — вёдра в памяти процесса (лимит на один экземпляр gateway).
+type Local struct {
+	mu        sync.Mutex
+	buckets   map[string]*localBucket
+	lastSweep time.Time
+}
+
+type localBucket struct {
+	bucket
+	full time.Time // когда ведро наполнится без новых запросов
+}
+
+// NewLocal создаёт Local.
+func NewLocal() *Local {
+	return &Local{buckets: make(map[string]*localBucket), lastSweep: time.Now()}
+}
+
+// Take берёт токен из ведра key.
+func (l *Local) Take(_ context.Context, key string, rule *Rule) (Result, error) {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.lastSweep) >= sweepInterval {
+		for k, b := range l.buckets {
+			if !now.Before(b.full) {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastSweep = now
+	}
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &localBucket{bucket: bucket{tokens: float64(rule.Burst), updated: now}}
+		l.buckets[key] = b
+	}
+	res := b.take(now, rule)
+	b.full = now.Add(res.Reset)
+	return res, nil
+}
@@ -0,0 +1,192 @@
+// Package ratelimit — лимиты запросов по алгоритму token bucket: правила из config.RateLimit
+// для HTTP-путей и gRPC-методов, ключи по IP, пользователю, API-ключу или маршруту.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/psds-microservice/api-gateway/internal/config"
+	"github.com/psds-microservice/api-gateway/internal/match"
+)
+
+// Rule — скомпилированное правило: Rate токенов в секунду, ёмкость ведра Burst.
+type Rule struct {
+	// Name — имя правила (часть ключа ведра, метки метрик и логи).
+	Name  string
+	Rate  float64
+	Burst int
+	Key   string // config.RateLimitBy*
+}
+
+// Result — результат взятия токена.
+type Result struct {
+	Allowed   bool
+	Limit     int // ёмкость ведра
+	Remaining int // целых токенов после запроса
+	// RetryAfter — через сколько появится токен (для отклонённого запроса).
+	RetryAfter time.Duration
+	// Reset — через сколько ведро наполнится полностью.
+	Reset time.Duration
+}
+
+// Backend хранит вёдра токенов.
+type Backend interface {
+	// Take берёт токен из ведра key по правилу rule.
+	Take(ctx context.Context, key string, rule *Rule) (Result, error)
+}
+
+// Caller — атрибуты вызывающего, из которых строится ключ ведра.
+type Caller struct {
+	IP      string
+	Subject string // "" — анонимный вызов
+	KeyID   string // id API-ключа; "" — не API-ключ
+}
+
+// Policy — скомпилированная config.RateLimit (неизменяемая).
+type Policy struct {
+	def  *Rule
+	http match.HTTP[*Rule] // nil-значение — правило disabled (без лимита)
+	grpc match.GRPC[*Rule]
+}
+
+// New компилирует лимиты: правила cfg.Rules, затем options.rate_limit маршрутов прокси.
+// cfg должен быть провалидирован (config.LoadGateway).
+func New(cfg *config.RateLimit, routes []config.RouteConfig) *Policy {
+	p := &Policy{}
+	if cfg == nil {
+		return p
+	}
+	p.def = compile("default", cfg.Default)
+	for _, r := range cfg.Rules {
+		if r.GRPC != "" {
+			p.grpc.Add(r.GRPC, compile(r.GRPC, &r))
+			continue
+		}
+		p.http.Add(r.Path, r.Methods, compile(ruleName(r.Methods, r.Path), &r))
+	}
+	for _, rc := range routes {
+		if rc.Options.RateLimit != nil {
+			p.http.Add(rc.Path, rc.Methods, compile("route "+ruleName(rc.Methods, rc.Path), rc.Options.RateLimit))
+		}
+	}
+	return p
+}
+
+func ruleName(methods []string, path string) string {
+	if len(methods) == 0 {
+		return path
+	}
+	return strings.Join(methods, ",") + " " + path
+}
+
+func compile(name string, r *config.RateLimitRule) *Rule {
+	if r == nil || r.Disabled {
+		return nil
+	}
+	return &Rule{Name: name, Rate: r.RPS, Burst: r.Burst, Key: r.Key}
+}
+
+// HTTP возвращает правило для запроса; nil — запрос не ограничивается.
+func (p *Policy) HTTP(method, path string) *Rule {
+	if r, ok := p.http.Lookup(method, path); ok {
+		return r
+	}
+	return p.def
+}
+
+// GRPC возвращает правило для gRPC-метода; nil — вызов не ограничивается.
+func (p *Policy) GRPC(fullMethod string) *Rule {
+	if r, ok := p.grpc.Lookup(fullMethod); ok {
+		return r
+	}
+	return p.def
+}
+
+// BucketKey — ключ ведра вызывающего по правилу.
+func (r *Rule) BucketKey(c Caller) string {
+	key := r.Key
+	if key == config.RateLimitByAPIKey {
+		if c.KeyID != "" {
+			return r.Name + "|key:" + c.KeyID
+		}
+		key = config.RateLimitByUser
+	}
+	switch key {
+	case config.RateLimitByRoute:
+		return r.Name + "|route"
+	case config.RateLimitByUser:
+		if c.Subject != "" {
+			return r.Name + "|user:" + c.Subject
+		}
+	}
+	return r.Name + "|ip:" + c.IP
+}
+
+// Limiter применяет текущие лимиты; при hot reload политика подменяется атомарно,
+// вёдра сохраняются.
+type Limiter struct {
+	policy  atomic.Pointer[Policy]
+	backend Backend
+}
+
+// NewLimiter создаёт Limiter с начальной политикой.
+func NewLimiter(p *Policy, b Backend) *Limiter {
+	l := &Limiter{backend: b}
+	l.policy.Store(p)
+	return l
+}
+
+// Swap подменяет политику.
+func (l *Limiter) Swap(p *Policy) {
+	l.policy.Store(p)
+}
+
+// Policy возвращает текущую политику.
+func (l *Limiter) Policy() *Policy {
+	return l.policy.Load()
+}
+
+// Take берёт токен по правилу rule для вызывающего c.
+func (l *Limiter) Take(ctx context.Context, rule *Rule, c Caller) (Result, error) {
+	res, err := l.backend.Take(ctx, rule.BucketKey(c), rule)
+	if err != nil {
+		return Result{}, fmt.Errorf("rate limit %q: %w", rule.Name, err)
+	}
+	return res, nil
+}
+
+// bucket — состояние ведра: tokens на момент updated.
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// take пополняет ведро за прошедшее время и берёт из него токен.
+func (b *bucket) take(now time.Time, rule *Rule) Result {
+	b.tokens = math.Min(float64(rule.Burst), b.tokens+now.Sub(b.updated).Seconds()*rule.Rate)
+	b.updated = now
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return result(allowed, b.tokens, rule)
+}
+
+// result строит Result по остатку токенов после запроса.
+func result(allowed bool, tokens float64, rule *Rule) Result {
+	res := Result{Allowed: allowed, Limit: rule.Burst, Remaining: int(tokens)}
+	if !allowed {
+		res.RetryAfter = seconds((1 - tokens) / rule.Rate)
+	}
+	res.Reset = seconds((float64(rule.Burst) - tokens) / rule.Rate)
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,128 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/psds-microservice/api-gateway/internal/config"
+)
+
+func TestBucketTake(t *testing.T) {
+	rule := &Rule{Name: "test", Rate: 2, Burst: 3}
+	now := time.Unix(1700000000, 0)
+	b := bucket{tokens: float64(rule.Burst), updated: now}
+
+	// burst подряд, затем отказ до пополнения
+	for i, want := range []int{2, 1, 0} {
+		res := b.take(now, rule)
+		if !res.Allowed || res.Remaining != want || res.Limit != 3 {
+			t.Fatalf("take %d: %+v, want allowed with %d remaining", i, res, want)
+		}
+	}
+	res := b.take(now, rule)
+	if res.Allowed {
+		t.Fatal("allowed over burst")
+	}
+	if res.RetryAfter != 500*time.Millisecond {
+		t.Fatalf("RetryAfter %v, want 500ms (1 token at 2 rps)", res.RetryAfter)
+	}
+	if res.Reset != 1500*time.Millisecond {
+		t.Fatalf("Reset %v, want 1.5s (3 tokens at 2 rps)", res.Reset)
+	}
+
+	// за 0.5 с пополняется один токен
+	now = now.Add(500 * time.Millisecond)
+	if res := b.take(now, rule); !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("after refill: %+v", res)
+	}
+	if res := b.take(now, rule); res.Allowed {
+		t.Fatal("allowed without refill")
+	}
+
+	// ведро не наполняется выше burst
+	now = now.Add(time.Hour)
+	if res := b.take(now, rule); !res.Allowed || res.Remaining != 2 {
+		t.Fatalf("after idle: %+v, want 2 remaining", res)
+	}
+}
+
+func TestLocalBuckets(t *testing.T) {
+	l := NewLocal()
+	rule := &Rule{Name: "test", Rate: 0.001, Burst: 1}
+	ctx := context.Background()
+	if res, _ := l.Take(ctx, "a", rule); !res.Allowed {
+		t.Fatal("first request to a rejected")
+	}
+	if res, _ := l.Take(ctx, "a", rule); res.Allowed {
+		t.Fatal("second request to a allowed")
+	}
+	if res, _ := l.Take(ctx, "b", rule); !res.Allowed {
+		t.Fatal("buckets are not separated by key")
+	}
+}
+
+func TestBucketKey(t *testing.T) {
+	anon := Caller{IP: "10.0.0.1"}
+	user := Caller{IP: "10.0.0.1", Subject: "u1"}
+	withKey := Caller{IP: "10.0.0.1", Subject: "u1", KeyID: "k1"}
+	tests := []struct {
+		key    string
+		caller Caller
+		want   string
+	}{
+		{config.RateLimitByIP, user, "r|ip:10.0.0.1"},
+		{config.RateLimitByUser, user, "r|user:u1"},
+		{config.RateLimitByUser, anon, "r|ip:10.0.0.1"},
+		{config.RateLimitByAPIKey, withKey, "r|key:k1"},
+		{config.RateLimitByAPIKey, user, "r|user:u1"},
+		{config.RateLimitByAPIKey, anon, "r|ip:10.0.0.1"},
+		{config.RateLimitByRoute, user, "r|route"},
+	}
+	for _, tt := range tests {
+		rule := &Rule{Name: "r", Key: tt.key}
+		if got := rule.BucketKey(tt.caller); got != tt.want {
+			t.Errorf("key %s, caller %+v: %q, want %q", tt.key, tt.caller, got, tt.want)
+		}
+	}
+}
+
+func TestPolicyRules(t *testing.T) {
+	p := New(&config.RateLimit{
+		Default: &config.RateLimitRule{RPS: 10, Burst: 10, Key: config.RateLimitByIP},
+		Rules: []config.RateLimitRule{
+			{Path: "/api/v1/", RPS: 5, Burst: 5},
+			{Path: "/api/v1/video/frame", Methods: []string{"POST"}, RPS: 100, Burst: 100},
+			{Path: "/api/v1/status", Disabled: true},
+			{GRPC: "/pkg.Video/", RPS: 1, Burst: 1},
+		},
+	}, []config.RouteConfig{
+		{Path: "/search", Options: config.RouteOptions{RateLimit: &config.RateLimitRule{RPS: 2, Burst: 4}}},
+	})
+	tests := []struct {
+		method, path string
+		want         string // имя правила; "" — без лимита
+	}{
+		{"GET", "/other", "default"},
+		{"GET", "/api/v1/tickets", "/api/v1/"},
+		{"POST", "/api/v1/video/frame", "POST /api/v1/video/frame"},
+		{"GET", "/api/v1/video/frame", "/api/v1/"},
+		{"GET", "/api/v1/status", ""},
+		{"GET", "/search/q", "route /search"},
+	}
+	for _, tt := range tests {
+		got := ""
+		if r := p.HTTP(tt.method, tt.path); r != nil {
+			got = r.Name
+		}
+		if got != tt.want {
+			t.Errorf("%s %s: rule %q, want %q", tt.method, tt.path, got, tt.want)
+		}
+	}
+	if r := p.GRPC("/pkg.Video/SendFrame"); r == nil || r.Name != "/pkg.Video/" {
+		t.Errorf("grpc rule %v, want /pkg.Video/", r)
+	}
+	if r := p.GRPC("/pkg.Other/Call"); r == nil || r.Name != "default" {
+		t.Errorf("grpc rule %v, want default", r)
+	}
+}
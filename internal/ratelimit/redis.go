@@ -0,0 +1,114 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/psds-microservice/api-gateway/internal/metrics"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// redisKeyPrefix — префикс ключей вёдер в Redis.
+const redisKeyPrefix = "api_gateway:ratelimit:"
+
+// takeScript — атомарное взятие токена из ведра-хэша {tokens, ts}. Время берётся из Redis (TIME),
+// поэтому часы реплик gateway не влияют на пополнение. TTL — время до полного ведра:
+// полное ведро равно отсутствующему ключу. Возвращает {allowed, tokens}.
+var takeScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) + tonumber(t[2]) / 1000000
+local b = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(b[1])
+local ts = tonumber(b[2])
+if tokens == nil or ts == nil then
+  tokens = burst
+  ts = now
+end
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil((burst - tokens) / rate * 1000) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+// Redis — вёдра в Redis, общие для всех реплик gateway. Если Redis недоступен, решения
+// принимает локальный Backend (лимит на реплику) в течение retryAfter, затем Redis пробуется снова.
+type Redis struct {
+	client     *redis.Client
+	fallback   Backend
+	retryAfter time.Duration
+	logger     *zap.Logger
+
+	mu        sync.Mutex
+	downUntil time.Time // до этого момента Redis не опрашивается
+}
+
+// NewRedis создаёт Backend поверх client. fallback используется при недоступности Redis.
+func NewRedis(client *redis.Client, fallback Backend, retryAfter time.Duration, logger *zap.Logger) *Redis {
+	metrics.RateLimitBackendDegraded.Set(0)
+	return &Redis{client: client, fallback: fallback, retryAfter: retryAfter, logger: logger}
+}
+
+// Take берёт токен в Redis, при ошибке Redis — в локальном ведре.
+func (r *Redis) Take(ctx context.Context, key string, rule *Rule) (Result, error) {
+	if r.degraded() {
+		return r.local(ctx, key, rule)
+	}
+	vals, err := takeScript.Run(ctx, r.client, []string{redisKeyPrefix + key},
+		strconv.FormatFloat(rule.Rate, 'f', -1, 64), rule.Burst).Slice()
+	if err != nil {
+		if ctx.Err() != nil {
+			return Result{}, ctx.Err()
+		}
+		r.markDown(err)
+		return r.local(ctx, key, rule)
+	}
+	r.markUp()
+	allowed, _ := vals[0].(int64)
+	tokensStr, _ := vals[1].(string)
+	tokens, err := strconv.ParseFloat(tokensStr, 64)
+	if err != nil {
+		return Result{}, err
+	}
+	return result(allowed == 1, tokens, rule), nil
+}
+
+func (r *Redis) local(ctx context.Context, key string, rule *Rule) (Result, error) {
+	metrics.RateLimitFallback.Inc()
+	return r.fallback.Take(ctx, key, rule)
+}
+
+func (r *Redis) degraded() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return time.Now().Before(r.downUntil)
+}
+
+func (r *Redis) markDown(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.downUntil.IsZero() {
+		r.logger.Warn("Rate limit Redis unavailable, falling back to local limits", zap.Error(err))
+		metrics.RateLimitBackendDegraded.Set(1)
+	}
+	r.downUntil = time.Now().Add(r.retryAfter)
+}
+
+func (r *Redis) markUp() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.downUntil.IsZero() {
+		r.logger.Info("Rate limit Redis recovered")
+		metrics.RateLimitBackendDegraded.Set(0)
+		r.downUntil = time.Time{}
+	}
+}
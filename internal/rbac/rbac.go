@@ -0,0 +1,119 @@
+// Package rbac — проверка доступа по ролям и разрешениям (config.RBAC) для HTTP-путей и gRPC-методов.
+package rbac
+
+import (
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/psds-microservice/api-gateway/internal/auth"
+	"github.com/psds-microservice/api-gateway/internal/config"
+	"github.com/psds-microservice/api-gateway/internal/match"
+)
+
+// Decision — результат проверки доступа.
+type Decision struct {
+	Allowed bool
+	// Rule — описание сработавшего правила (для логов); "" — правило не найдено, применён default.
+	Rule        string
+	Roles       []string
+	Permissions []string
+}
+
+// Policy — скомпилированная config.RBAC (неизменяемая).
+type Policy struct {
+	allowDefault bool
+	permissions  map[string][]string
+	http         match.HTTP[config.PolicyConfig]
+	grpc         match.GRPC[config.PolicyConfig]
+}
+
+// New компилирует политику. cfg должен быть провалидирован (config.LoadGateway).
+func New(cfg *config.RBAC) *Policy {
+	p := &Policy{allowDefault: true, permissions: map[string][]string{}}
+	if cfg == nil {
+		return p
+	}
+	p.allowDefault = cfg.Default != config.RBACDeny
+	p.permissions = cfg.RolePermissions
+	for _, pc := range cfg.Policies {
+		if pc.GRPC != "" {
+			p.grpc.Add(pc.GRPC, pc)
+			continue
+		}
+		p.http.Add(pc.Path, pc.Methods, pc)
+	}
+	return p
+}
+
+// HTTP проверяет доступ к HTTP-пути. id == nil — анонимный вызов.
+func (p *Policy) HTTP(method, path string, id *auth.Identity) Decision {
+	pc, ok := p.http.Lookup(method, path)
+	if !ok {
+		return Decision{Allowed: p.allowDefault}
+	}
+	return p.decide(pc, strings.Join(pc.Methods, ",")+" "+pc.Path, id)
+}
+
+// GRPC проверяет доступ к gRPC-методу (полное имя "/pkg.Service/Method").
+func (p *Policy) GRPC(fullMethod string, id *auth.Identity) Decision {
+	pc, ok := p.grpc.Lookup(fullMethod)
+	if !ok {
+		return Decision{Allowed: p.allowDefault}
+	}
+	return p.decide(pc, pc.GRPC, id)
+}
+
+func (p *Policy) decide(pc config.PolicyConfig, rule string, id *auth.Identity) Decision {
+	d := Decision{Rule: strings.TrimSpace(rule), Roles: pc.Roles, Permissions: pc.Permissions}
+	if id == nil {
+		return d
+	}
+	if len(pc.Roles) > 0 && !slices.ContainsFunc(pc.Roles, id.HasRole) {
+		return d
+	}
+	for _, perm := range pc.Permissions {
+		if !p.granted(id, perm) {
+			return d
+		}
+	}
+	d.Allowed = true
+	return d
+}
+
+// granted — разрешение есть хотя бы у одной роли вызывающего и входит в его scopes (если заданы).
+func (p *Policy) granted(id *auth.Identity, perm string) bool {
+	if id.Scopes != nil && !slices.Contains(id.Scopes, "*") && !slices.Contains(id.Scopes, perm) {
+		return false
+	}
+	for _, role := range id.Roles {
+		for _, have := range p.permissions[role] {
+			if have == "*" || have == perm {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Authorizer хранит текущую политику; при hot reload политика подменяется атомарно.
+type Authorizer struct {
+	policy atomic.Pointer[Policy]
+}
+
+// NewAuthorizer создаёт Authorizer с начальной политикой.
+func NewAuthorizer(p *Policy) *Authorizer {
+	a := &Authorizer{}
+	a.policy.Store(p)
+	return a
+}
+
+// Policy возвращает текущую политику.
+func (a *Authorizer) Policy() *Policy {
+	return a.policy.Load()
+}
+
+// Swap подменяет политику.
+func (a *Authorizer) Swap(p *Policy) {
+	a.policy.Store(p)
+}
@@ -0,0 +1,61 @@
+// Package reqinfo — сведения о запросе, которые узнают внутренние обработчики (шаблон
+// маршрута, upstream прокси, IP клиента, вызывающий), а используют внешние middleware: метрики, трассировка, access log.
+package reqinfo
+
+import "context"
+
+// Info — сведения о запросе. Заполняются в горутине обработчика запроса и читаются
+// middleware после его завершения.
+type Info struct {
+	// Route — шаблон маршрута ("/api/v1/video/stats/{client_id}", путь маршрута прокси);
+	// "" — маршрут не найден.
+	Route string
+	// Upstream — upstream прокси, которому передан запрос; "" — ответил сам gateway.
+	Upstream string
+	// ClientIP — IP клиента, определённый middleware.ClientIP.
+	ClientIP string
+	// UserID — subject аутентифицированного вызывающего; "" — анонимный запрос.
+	UserID string
+}
+
+type infoKey struct{}
+
+// New возвращает контекст с пустыми сведениями о запросе.
+func New(ctx context.Context) (context.Context, *Info) {
+	info := &Info{}
+	return context.WithValue(ctx, infoKey{}, info), info
+}
+
+// From возвращает сведения о запросе или nil, если контекст создан не New.
+func From(ctx context.Context) *Info {
+	info, _ := ctx.Value(infoKey{}).(*Info)
+	return info
+}
+
+// SetRoute запоминает шаблон маршрута запроса.
+func SetRoute(ctx context.Context, route string) {
+	if info := From(ctx); info != nil {
+		info.Route = route
+	}
+}
+
+// SetUpstream запоминает upstream прокси, обслуживающий запрос.
+func SetUpstream(ctx context.Context, upstream string) {
+	if info := From(ctx); info != nil {
+		info.Upstream = upstream
+	}
+}
+
+// SetClientIP запоминает IP клиента.
+func SetClientIP(ctx context.Context, ip string) {
+	if info := From(ctx); info != nil {
+		info.ClientIP = ip
+	}
+}
+
+// SetUserID запоминает subject аутентифицированного вызывающего.
+func SetUserID(ctx context.Context, userID string) {
+	if info := From(ctx); info != nil {
+		info.UserID = userID
+	}
+}
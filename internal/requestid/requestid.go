@@ -0,0 +1,99 @@
+// Package requestid — идентификатор запроса (X-Request-ID) для сквозной корреляции логов
+// gateway, backend-ов прокси и user-service.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+
+	"github.com/psds-microservice/api-gateway/pkg/constants"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// MetadataKey — ключ идентификатора в gRPC-метаданных.
+var MetadataKey = strings.ToLower(constants.HeaderRequestID)
+
+// maxLen — максимальная длина принимаемого от клиента идентификатора.
+const maxLen = 128
+
+// New генерирует новый идентификатор (128 случайных бит в hex).
+func New() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Accept возвращает идентификатор клиента, если он допустим (до 128 печатных символов
+// без пробелов и разделителей), иначе — новый.
+func Accept(id string) string {
+	if valid(id) {
+		return id
+	}
+	return New()
+}
+
+func valid(id string) bool {
+	if id == "" || len(id) > maxLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if c <= ' ' || c > '~' || c == '"' || c == ',' || c == ';' || c == '\\' {
+			return false
+		}
+	}
+	return true
+}
+
+type idKey struct{}
+
+// WithID возвращает контекст с идентификатором запроса.
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, idKey{}, id)
+}
+
+// FromContext возвращает идентификатор, положенный middleware.RequestID.
+func FromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(idKey{}).(string)
+	return id, ok && id != ""
+}
+
+// Logger возвращает логгер с полем request_id запроса ctx (или сам logger, если его нет).
+func Logger(ctx context.Context, logger *zap.Logger) *zap.Logger {
+	if id, ok := FromContext(ctx); ok {
+		return logger.With(Field(id))
+	}
+	return logger
+}
+
+// Field — поле лога с идентификатором запроса.
+func Field(id string) zap.Field {
+	return zap.String("request_id", id)
+}
+
+// Status добавляет идентификатор запроса в детали gRPC-ошибки (google.rpc.RequestInfo),
+// чтобы его видели и gRPC-клиенты, и клиенты HTTP (тело ошибки grpc-gateway).
+func Status(err error, id string) error {
+	if err == nil || id == "" {
+		return err
+	}
+	st := status.Convert(err)
+	if st.Code() == codes.OK {
+		return err
+	}
+	for _, d := range st.Details() {
+		if _, ok := d.(*errdetails.RequestInfo); ok {
+			return err
+		}
+	}
+	withID, derr := st.WithDetails(&errdetails.RequestInfo{RequestId: id})
+	if derr != nil {
+		return err
+	}
+	return withID.Err()
+}
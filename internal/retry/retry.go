@@ -0,0 +1,75 @@
+// Package retry — общие примитивы повторов: backoff с jitter и бюджет повторов.
+// Используется прокси (proxy) и gRPC-клиентом user-service (grpc_client).
+package retry
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+)
+
+// Backoff возвращает задержку перед повтором attempt (с 1): случайное значение
+// из [0, min(maxDelay, base*2^(attempt-1))] (full jitter).
+func Backoff(attempt int, base, maxDelay time.Duration) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+	d := base
+	for i := 1; i < attempt && (maxDelay <= 0 || d < maxDelay); i++ {
+		d *= 2
+	}
+	if maxDelay > 0 && d > maxDelay {
+		d = maxDelay
+	}
+	return rand.N(d + 1)
+}
+
+// Sleep ждёт d или отмены ctx.
+func Sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// LocalBudget ограничивает одновременные повторы долей от активных запросов: повтор разрешён,
+// пока активных повторов меньше max(minConcurrent, percent% активных запросов).
+// Так при отказе backend-а повторы не умножают нагрузку на него. Счётчики — в памяти процесса:
+// бюджет действует на каждой реплике gateway отдельно, а доля повторов от её собственных
+// запросов сохраняется и для всего кластера.
+type LocalBudget struct {
+	percent       int64
+	minConcurrent int64
+	requests      atomic.Int64
+	retries       atomic.Int64
+}
+
+// NewLocalBudget создаёт бюджет. percent <= 0 и minConcurrent <= 0 — повторы запрещены.
+func NewLocalBudget(percent, minConcurrent int) *LocalBudget {
+	return &LocalBudget{percent: int64(percent), minConcurrent: int64(minConcurrent)}
+}
+
+// Begin учитывает активный запрос; возвращённую функцию нужно вызвать по завершении.
+func (b *LocalBudget) Begin() (end func()) {
+	b.requests.Add(1)
+	return func() { b.requests.Add(-1) }
+}
+
+// TryRetry резервирует повтор. ok = false — бюджет исчерпан; иначе release вызывается
+// по завершении повтора.
+func (b *LocalBudget) TryRetry() (release func(), ok bool) {
+	limit := max(b.minConcurrent, b.requests.Load()*b.percent/100)
+	if b.retries.Add(1) > limit {
+		b.retries.Add(-1)
+		return nil, false
+	}
+	return func() { b.retries.Add(-1) }, true
+}
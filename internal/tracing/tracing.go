@@ -0,0 +1,122 @@
+// Package tracing — трассировка OpenTelemetry: провайдер с экспортёром из конфигурации,
+// пропагация W3C trace context и обёртки для HTTP-сервера и исходящих запросов прокси.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/psds-microservice/api-gateway/internal/config"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// untracedPaths — служебные пути без спанов (опрашиваются балансировщиками и Prometheus).
+var untracedPaths = map[string]bool{"/health": true, "/ready": true, "/metrics": true}
+
+// Init настраивает глобальные TracerProvider и propagator (W3C traceparent + baggage) по
+// cfg.Tracing. Возвращаемая функция отправляет накопленные спаны и закрывает экспортёр.
+// При TracingExporterNone спаны не создаются, но входящий traceparent передаётся дальше.
+func Init(ctx context.Context, cfg *config.Config, logger *zap.Logger) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		logger.Warn("OpenTelemetry error", zap.Error(err))
+	}))
+
+	var (
+		exp     sdktrace.SpanExporter
+		closeFn func() error
+		err     error
+	)
+	switch cfg.Tracing.Exporter {
+	case config.TracingExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case config.TracingExporterOTLP:
+		opts := []otlptracegrpc.Option{}
+		if cfg.Tracing.OTLPEndpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(cfg.Tracing.OTLPEndpoint))
+		}
+		if cfg.Tracing.OTLPInsecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		exp, err = otlptracegrpc.New(ctx, opts...)
+	case config.TracingExporterStdout:
+		exp, err = stdouttrace.New()
+	case config.TracingExporterFile:
+		var f *os.File
+		f, err = os.OpenFile(cfg.Tracing.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("tracing file: %w", err)
+		}
+		closeFn = f.Close
+		exp, err = stdouttrace.New(stdouttrace.WithWriter(f))
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", cfg.Tracing.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("tracing exporter %s: %w", cfg.Tracing.Exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(),
+		resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(cfg.Tracing.ServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("tracing resource: %w", err)
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.Tracing.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+	logger.Info("Tracing enabled",
+		zap.String("exporter", cfg.Tracing.Exporter),
+		zap.Float64("sample_ratio", cfg.Tracing.SampleRatio))
+
+	return func(ctx context.Context) error {
+		err := tp.Shutdown(ctx)
+		if closeFn != nil {
+			err = errors.Join(err, closeFn())
+		}
+		return err
+	}, nil
+}
+
+// Handler создаёт серверный спан для каждого входящего HTTP-запроса (кроме служебных путей).
+// Имя спана — метод; шаблон маршрута добавляет SetRoute после обработки запроса.
+func Handler(next http.Handler) http.Handler {
+	return otelhttp.NewHandler(next, "http",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string { return r.Method }),
+		otelhttp.WithFilter(func(r *http.Request) bool { return !untracedPaths[r.URL.Path] }),
+	)
+}
+
+// SetRoute уточняет серверный спан запроса шаблоном маршрута: имя "<метод> <маршрут>", http.route.
+func SetRoute(ctx context.Context, method, route string) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+	span.SetName(method + " " + route)
+	span.SetAttributes(semconv.HTTPRoute(route))
+}
+
+// Transport создаёт клиентский спан на каждую попытку запроса к upstream-у и передаёт
+// ему traceparent.
+func Transport(base http.RoundTripper, upstream string) http.RoundTripper {
+	return otelhttp.NewTransport(base,
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string { return r.Method + " " + upstream }),
+		otelhttp.WithSpanOptions(trace.WithAttributes(attribute.String("peer.service", upstream))),
+	)
+}
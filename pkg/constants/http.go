@@ -13,6 +13,36 @@ const (
 const (
 	HeaderAuthorization = "Authorization"
 	HeaderContentType   = "Content-Type"
+	// HeaderRequestTimeout — оставшееся время на обработку запроса в миллисекундах
+	// (gateway передаёт его backend-ам и учитывает во входящих запросах).
+	HeaderRequestTimeout = "X-Request-Timeout-Ms"
+	// HeaderAuthSubject / HeaderAuthRoles — проверенная gateway identity, передаваемая backend-ам
+	// (одноимённые заголовки клиента отбрасываются).
+	HeaderAuthSubject = "X-Auth-Subject"
+	HeaderAuthRoles   = "X-Auth-Roles"
+	// HeaderRequestID — идентификатор запроса: принимается от клиента или генерируется,
+	// возвращается в ответе и передаётся backend-ам (в gRPC — метаданные "x-request-id").
+	HeaderRequestID = "X-Request-ID"
+	// HeaderAPIKey — API-ключ устройства / машинного клиента (в gRPC — метаданные "x-api-key").
+	HeaderAPIKey = "X-API-Key"
+	// Лимит запросов (draft-ietf-httpapi-ratelimit-headers) и Retry-After при 429.
+	HeaderRateLimitLimit     = "RateLimit-Limit"
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	HeaderRateLimitReset     = "RateLimit-Reset"
+	HeaderRetryAfter         = "Retry-After"
+	// HeaderCache — результат кэша ответов маршрута: HIT, MISS, REVALIDATED или BYPASS.
+	HeaderCache = "X-Cache"
+	// Адрес клиента от прокси перед gateway (учитываются только от TRUSTED_PROXIES);
+	// backend-ам gateway передаёт определённый им IP в X-Real-IP.
+	HeaderForwarded    = "Forwarded"
+	HeaderForwardedFor = "X-Forwarded-For"
+	HeaderRealIP       = "X-Real-IP"
+)
+
+// Параметры query
+const (
+	// QueryAccessToken — JWT в query WebSocket-handshake (браузер не может передать Authorization).
+	QueryAccessToken = "access_token"
 )
 
 const (
@@ -33,6 +33,14 @@ const (
 	PathClientsActive       = "/clients/active"
 )
 
+// Admin API (относительно BasePathAPI)
+const (
+	PathAdmin    = "/admin/"
+	PathAPIKeys  = "/admin/api-keys"
+	PathLogLevel = "/admin/log-level"
+	PathCache    = "/admin/cache"
+)
+
 // Swagger
 const (
 	PathSwagger = "/swagger"